@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
@@ -26,6 +27,11 @@ const (
 
 // @host      localhost:8080
 // @BasePath  /api/v1
+
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Admin token as "Bearer <token>"
 func main() {
 	config, err := config.Load()
 	if err != nil {
@@ -34,7 +40,24 @@ func main() {
 
 	log := setupLogger(config.Env)
 
-	app := app.New(*config, log)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(*config, log, os.Args[2:]))
+	}
+
+	flag.BoolVar(&config.AutoMigrate, "auto-migrate", config.AutoMigrate, "apply pending schema migrations before start")
+	flag.Parse()
+
+	app, err := app.New(*config, log)
+	if err != nil {
+		log.Error("Failed to init app", sl.Err(err))
+		os.Exit(1)
+	}
+
+	app.CallbackDispatcher.Start()
+	app.SubscriptionDispatcher.Start()
+	app.TaskLogStore.Start()
+	app.TrashJanitor.Start()
+	app.OutboxRelay.Start()
 
 	go func() {
 		err := app.HttpAdapter.Start()
@@ -52,6 +75,16 @@ func main() {
 	if err := app.HttpAdapter.Shutdown(context.Background()); err != nil {
 		log.Error("Failed to shut down the server", sl.Err(err))
 	}
+	// a purge in progress publishes its events on the way out, so the janitor stops while the dispatchers still run
+	app.TrashJanitor.Stop()
+	// the relay publishes what the server and the janitor left in the outbox, the events still reach the dispatchers
+	app.OutboxRelay.Stop()
+	app.CallbackDispatcher.Stop()
+	app.SubscriptionDispatcher.Stop()
+	app.TaskLogStore.Stop()
+	if err := app.Close(); err != nil {
+		log.Error("Failed to close the storage", sl.Err(err))
+	}
 
 	log.Info("Gracefully stopped")
 }
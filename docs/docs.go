@@ -15,45 +15,91 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/tasks": {
-            "post": {
-                "description": "Creates a new task with the specified title and description",
-                "consumes": [
-                    "application/json"
+        "/admin/backup": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Streams a consistent copy of the task database while the service keeps serving requests.\nOnly storages with online backup support it (bbolt), requires the admin token",
                 "produces": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "tags": [
-                    "tasks"
+                    "admin"
                 ],
-                "summary": "Create a new task",
-                "parameters": [
-                    {
-                        "description": "Task title and description",
-                        "name": "task",
-                        "in": "body",
-                        "required": true,
+                "summary": "Back up task storage",
+                "responses": {
+                    "200": {
+                        "description": "database file",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.createTaskRequest"
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to back up storage",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "storage does not support online backup",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     }
+                }
+            }
+        },
+        "/admin/cache": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Returns hit and miss counters of the read-through cache in front of the task storage, requires the admin token",
+                "produces": [
+                    "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get task cache stats",
                 "responses": {
-                    "201": {
-                        "description": "task created successfully with id {task_id}",
+                    "200": {
+                        "description": "cache stats",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.createTaskResponse"
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.CacheStats"
                         }
                     },
-                    "400": {
-                        "description": "invalid request body",
+                    "401": {
+                        "description": "invalid admin token",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     },
-                    "500": {
-                        "description": "failed to create task",
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "task cache is disabled",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
@@ -61,50 +107,74 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}": {
-            "delete": {
-                "description": "Deletes a task with the specified ID",
-                "consumes": [
-                    "application/json"
+        "/admin/export": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Streams every stored task as NDJSON ordered by creation time, tasks in the trash included. Results kept in the result store\nare exported as references, the store has to be copied separately. Requires the admin token",
                 "produces": [
-                    "application/json"
+                    "application/x-ndjson"
                 ],
                 "tags": [
-                    "tasks"
+                    "admin"
                 ],
-                "summary": "Delete task by ID",
+                "summary": "Export tasks",
                 "parameters": [
                     {
+                        "enum": [
+                            "in_progress",
+                            "completed",
+                            "failed",
+                            "cancelled"
+                        ],
                         "type": "string",
-                        "format": "uuid",
-                        "description": "Task ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Only tasks with the status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks created at or after the RFC3339 time",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks created before the RFC3339 time",
+                        "name": "created_to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "task deleted successfully",
+                        "description": "a task per line",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.deleteTaskResponse"
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.Task"
                         }
                     },
                     "400": {
-                        "description": "invalid task ID",
+                        "description": "invalid query",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "task not found",
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "failed to delete task",
+                        "description": "failed to export tasks",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
@@ -112,50 +182,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}/result": {
+        "/admin/history/check": {
             "get": {
-                "description": "Returns the result of task execution",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Rebuilds every task with a history from its events and compares the result with the stored task.\nTasks whose history is invalid or does not match the stored status, progress, attempt, version or trash state are reported, requires the admin token",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tasks"
-                ],
-                "summary": "Get task result by ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Task ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Check task history",
                 "responses": {
                     "200": {
-                        "description": "task result: {task_result}",
+                        "description": "check report",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getTaskResultResponse"
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.checkTaskHistoryResponse"
                         }
                     },
-                    "400": {
-                        "description": "invalid task ID",
+                    "401": {
+                        "description": "invalid admin token",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "task not found",
+                    "403": {
+                        "description": "admin endpoints are disabled",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "failed to get task result",
+                        "description": "failed to check task history",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
@@ -163,138 +225,2430 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}/state": {
-            "get": {
-                "description": "Returns the current state (status, work duration in nanoseconds) of the task and its creation time",
+        "/admin/import": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Stores tasks sent as NDJSON in the export format, tasks keep their ids, versions and timestamps and publish no events.\nInvalid lines are rejected and the import goes on. mode decides what happens to a task whose id is already stored:\nskip keeps the stored task, overwrite replaces it, fail (default) stops the import with 409, tasks imported before stay stored.\nRequires the admin token",
                 "consumes": [
-                    "application/json"
+                    "application/x-ndjson"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tasks"
+                    "admin"
                 ],
-                "summary": "Get task state by ID",
+                "summary": "Import tasks",
                 "parameters": [
                     {
+                        "enum": [
+                            "skip",
+                            "overwrite",
+                            "fail"
+                        ],
                         "type": "string",
-                        "format": "uuid",
-                        "description": "Task ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": "fail",
+                        "description": "What to do with conflicting ids",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "description": "A task per line",
+                        "name": "tasks",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "task state: {state}, created at: {created_at}",
+                        "description": "import report",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getTaskStateResponse"
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.importTasksResponse"
                         }
                     },
                     "400": {
-                        "description": "invalid task ID",
+                        "description": "invalid mode or a line is too long",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "task not found",
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     },
+                    "409": {
+                        "description": "import stopped at a conflicting id",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.importTasksResponse"
+                        }
+                    },
                     "500": {
-                        "description": "failed to get task state",
+                        "description": "failed to import tasks",
                         "schema": {
                             "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_Util787_task-manager_internal_domain.TaskState": {
-            "type": "object",
-            "properties": {
-                "status": {
-                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskStatus"
-                },
-                "work_duration": {
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/time.Duration"
-                        }
-                    ],
-                    "example": 10
-                }
-            }
-        },
-        "github_com_Util787_task-manager_internal_domain.TaskStatus": {
-            "type": "string",
-            "enum": [
-                "failed",
-                "in_progress",
-                "completed"
-            ],
-            "x-enum-varnames": [
-                "StatusFailed",
-                "StatusInProgress",
-                "StatusCompleted"
-            ]
-        },
-        "internal_adapters_http-adapter_handlers.createTaskRequest": {
-            "type": "object",
-            "required": [
-                "title"
-            ],
-            "properties": {
-                "description": {
-                    "type": "string"
-                },
-                "title": {
-                    "type": "string"
-                }
-            }
-        },
-        "internal_adapters_http-adapter_handlers.createTaskResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "task created successfully with id 6bcd175e-cba9-4ba6-b6ef-f3ac37864118"
-                }
-            }
-        },
-        "internal_adapters_http-adapter_handlers.deleteTaskResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "task deleted successfully"
-                }
-            }
         },
-        "internal_adapters_http-adapter_handlers.errorResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
+        "/admin/results/sweep": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Removes blobs of the result store that no stored task references, tasks in the trash included, and blobs put within the last hour are kept. Blobs are left behind by finishes whose task update failed, by retries and by purges. Requires the admin token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Sweep result blobs",
+                "responses": {
+                    "200": {
+                        "description": "sweep report",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.ResultSweep"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to sweep result blobs",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
                 }
             }
         },
-        "internal_adapters_http-adapter_handlers.getTaskResultResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "task result: completed"
+        "/events/ws": {
+            "get": {
+                "description": "Upgrades to WebSocket and sends lifecycle events of all tasks as {\"type\":\"event\",\"event\":{...}} messages.\nClient messages are filters {\"statuses\":[...],\"types\":[...],\"labels\":[...]} that replace the current one and are acknowledged with {\"type\":\"filter\"},\ninvalid ones are answered with {\"type\":\"error\"}. A slow client either gets {\"type\":\"dropped\",\"dropped\":n} before the next event or is disconnected with code 1013",
+                "tags": [
+                    "events"
+                ],
+                "summary": "Stream events of all tasks over WebSocket",
+                "responses": {
+                    "101": {
+                        "description": "switching protocols",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.wsMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "not a websocket handshake",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
-        "internal_adapters_http-adapter_handlers.getTaskStateResponse": {
+        "/tasks": {
+            "get": {
+                "description": "Returns a page of tasks that match the filters, tasks in the trash are not listed. Comma separated lists match tasks with any of the values,\ntime ranges include their from and exclude their to. Pass next_cursor of a page as cursor with the same query to get the next page",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "List tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "in_progress,failed",
+                        "description": "Comma separated statuses",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "report",
+                        "description": "Comma separated task types",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "billing,nightly",
+                        "description": "Comma separated labels, tasks with at least one of them match",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks created at or after the RFC3339 time",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks created before the RFC3339 time",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks updated at or after the RFC3339 time",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks updated before the RFC3339 time",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "-created_at",
+                            "updated_at",
+                            "-updated_at"
+                        ],
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "Order of tasks, a leading minus lists the latest first",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, up to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "page of tasks",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskPage"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to list tasks",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a new task with the specified title and description, if callback url is set the final task is posted there once the task is finished",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Create a new task",
+                "parameters": [
+                    {
+                        "description": "Task title, description and optional type, labels and callback url",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.createTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "task created successfully with id {task_id}",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.createTaskResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to create task",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}": {
+            "delete": {
+                "description": "Moves the task with the specified ID to the trash, it is hidden from reads and purged for good once the trash grace period is over.\nWith hard=true the task is deleted for good right away, which requires the admin token. With If-Match the task is deleted only if its ETag still matches",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Delete task by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Delete the task for good instead of moving it to the trash, admin only",
+                        "name": "hard",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the task version the change is based on, * matches any version",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "task moved to trash or deleted for good",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.deleteTaskResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID, hard or If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to delete task",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/deliveries": {
+            "get": {
+                "description": "Returns the latest attempts to deliver the finished task to its callback url made by this node, see DELIVERY_LOG_MAX_ATTEMPTS and DELIVERY_LOG_RETENTION",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get callback deliveries of the task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "delivery attempts",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getTaskDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get task deliveries",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/events": {
+            "get": {
+                "description": "Server-Sent Events stream of the task. The first event is snapshot with the current task, then every transition and progress update follows,\nthe stream is closed after the terminal event. Idle stream sends keep-alive comments. Every event data is a task event JSON",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Stream task events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event stream",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to watch task",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/finish": {
+            "post": {
+                "description": "Moves the task to a terminal status (completed, failed or cancelled) and stores its JSON result, the result is checked against the schema of the task type if there is one, the final task is posted to its callback url if it is set.\nWith If-Match the task is finished only if its ETag still matches",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Finish task by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the task version the change is based on, * matches any version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Terminal status, JSON result and its media type (application/json by default)",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.finishTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "task finished successfully",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.finishTaskResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID, If-Match or request body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "task is already finished",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to finish task",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/history": {
+            "get": {
+                "description": "Returns the append-only history of the task oldest first: created, started, progress, completed, failed, cancelled, deleted and restored events.\nEvery event records who caused it (the X-Actor header of the request, system for changes made by the service) and the task version right after it.\nHistory is stored with the task in the same write as the change, imported tasks have none",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "task history",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getTaskHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get task history",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/logs": {
+            "get": {
+                "description": "Returns stored log lines of the task. since is either the seq of the last seen line or RFC3339 time, only later lines are returned.\nWith follow=true lines are streamed as NDJSON while the task is running, the stream ends when the task is finished",
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task log lines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Seq of the last seen line or RFC3339 time",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Stream new lines until the task is finished",
+                        "name": "follow",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "log lines, with follow=true every line is a separate JSON object",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getTaskLogsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID or query",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get task logs",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Stores output lines of the task executor, lines are tagged with the task ID and the attempt (current attempt of the task by default).\nOnly the latest lines of every task are kept",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Append task log lines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Attempt and log lines",
+                        "name": "logs",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.appendTaskLogsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "lines appended",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.appendTaskLogsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID or request body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to append task logs",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/progress": {
+            "post": {
+                "description": "Stores the progress percent of the running task, watchers of the task get it as a progress event.\nWith If-Match the progress is stored only if the task ETag still matches",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Report task progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the task version the change is based on, * matches any version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Progress percent from 0 to 100",
+                        "name": "progress",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.reportTaskProgressRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "task state",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.reportTaskProgressResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID, If-Match or request body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "task is already finished",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to report task progress",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/result": {
+            "get": {
+                "description": "Returns the result of task execution as text, string results are unquoted and other JSON values are inlined as is. Use /v2/tasks/{id}/result for the structured result.\nResults kept in the result store are not inlined and have to be downloaded from /tasks/{id}/result/content",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task result by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "task result: {task_result}",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getTaskResultResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get task result",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/result/content": {
+            "get": {
+                "description": "Streams the raw result of the finished task with its content type, supports Range and conditional requests",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Download task result",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Byte range, e.g. bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "task result",
+                        "schema": {
+                            "type": "file"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "206": {
+                        "description": "requested part of task result",
+                        "schema": {
+                            "type": "file"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "task is not finished",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "416": {
+                        "description": "requested range not satisfiable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get task result",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/retry": {
+            "post": {
+                "description": "Starts the next attempt of the failed or cancelled task, the result of the last attempt is dropped and watchers get a retried event.\nWith If-Match the task is retried only if its ETag still matches",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Retry failed task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the task version the change is based on, * matches any version",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "retried task",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.retryTaskResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID or If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "task is not failed or cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to retry task",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/state": {
+            "get": {
+                "description": "Returns the current state (status, work duration in nanoseconds) of the task and its creation time.\nWith wait the request blocks until the task reaches one of the until statuses (terminal statuses by default) or the wait expires, and returns the current state either way",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task state by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "30s",
+                        "description": "How long to wait, Go duration up to 1m",
+                        "name": "wait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "completed,failed",
+                        "description": "Comma separated statuses to wait for",
+                        "name": "until",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "task state: {state}, created at: {created_at}",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getTaskStateResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID, wait or until",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get task state",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/trash": {
+            "get": {
+                "description": "Returns tasks in the trash, earliest deleted first. They are purged for good once the trash grace period is over",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "List deleted tasks",
+                "responses": {
+                    "200": {
+                        "description": "deleted tasks",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.listTrashResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to list deleted tasks",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/trash/{id}/restore": {
+            "post": {
+                "description": "Takes the task out of the trash, with If-Match the task is restored only if its ETag still matches",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Restore deleted task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the task version the change is based on, * matches any version",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "restored task",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.restoreTaskResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID or If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found in trash",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to restore task",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/{id}/result": {
+            "get": {
+                "description": "Returns the JSON result of the task with its content type and completion time, result and completed_at are null until the task is finished.\nResults kept in the result store are not inlined, they have to be downloaded from content_url",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get structured task result by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "task result",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getTaskResultV2Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid task ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get task result",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Returns every webhook subscription including disabled ones",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "subscriptions",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.listSubscriptionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Subscribes the url to task lifecycle events (created, started, completed, failed, cancelled), events, types and labels filters are optional",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create a webhook subscription",
+                "parameters": [
+                    {
+                        "description": "Url and filters",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.subscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "created subscription",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.subscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to create webhook subscription",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Returns the subscription with its filters and delivery health",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhook subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "subscription",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.subscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid subscription ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get webhook subscription",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Replaces url, filters and active flag of the subscription, enabling a disabled subscription resets its failure counter",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Update webhook subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Url, filters and active flag",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.updateSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "updated subscription",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.subscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid subscription ID or request body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to update webhook subscription",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Deletes the subscription, its pending deliveries are dropped",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "webhook subscription deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.deleteSubscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid subscription ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to delete webhook subscription",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Returns the delivery log of the subscription in the order the deliveries were attempted, only the latest attempts made by this node are kept",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get deliveries of the webhook subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "delivery attempts",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.getSubscriptionDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid subscription ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get webhook deliveries",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{delivery_id}/replay": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Sends the event of the failed delivery to the subscription again, the new attempts are added to the delivery log",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Replay failed webhook delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Delivery ID",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "webhook delivery replay scheduled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.replayDeliveryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid subscription ID or delivery ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook subscription or delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "delivery succeeded or subscription is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to replay webhook delivery",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http-adapter_handlers.errorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_Util787_task-manager_internal_domain.CacheStats": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "type": "integer",
+                    "example": 10000
+                },
+                "entries": {
+                    "type": "integer",
+                    "example": 250
+                },
+                "hits": {
+                    "type": "integer",
+                    "example": 9120
+                },
+                "misses": {
+                    "type": "integer",
+                    "example": 311
+                },
+                "ttl": {
+                    "type": "string",
+                    "example": "2s"
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.Delivery": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "attempted_at": {
+                    "type": "string"
+                },
+                "delivery_id": {
+                    "type": "string"
+                },
+                "duration": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ],
+                    "example": 10
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "task_id": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.ImportError": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "task already exists"
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.ResultBlob": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 1048576
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.ResultSweep": {
+            "type": "object",
+            "properties": {
+                "blobs": {
+                    "description": "blobs in the store before the sweep",
+                    "type": "integer",
+                    "example": 120
+                },
+                "removed": {
+                    "description": "blobs no stored task referenced",
+                    "type": "integer",
+                    "example": 3
+                },
+                "removed_bytes": {
+                    "type": "integer",
+                    "example": 3145728
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.Task": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "callback_url": {
+                    "type": "string"
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "set while the task is in the trash",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "result": {
+                    "type": "object"
+                },
+                "result_blob": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.ResultBlob"
+                },
+                "result_content_type": {
+                    "type": "string"
+                },
+                "task_state": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskState"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "starts at 1 and grows with every stored change of the task",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "who caused the event, see ActorFromContext",
+                    "type": "string",
+                    "example": "executor-7"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "task": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.Task"
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEventType"
+                        }
+                    ],
+                    "example": "completed"
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskEventFilter": {
+            "type": "object",
+            "properties": {
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "statuses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskStatus"
+                    }
+                },
+                "types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskEventType": {
+            "type": "string",
+            "enum": [
+                "created",
+                "started",
+                "completed",
+                "failed",
+                "cancelled",
+                "progress",
+                "retried",
+                "deleted",
+                "restored",
+                "purged",
+                "snapshot"
+            ],
+            "x-enum-comments": {
+                "EventTaskDeleted": "moved to the trash",
+                "EventTaskPurged": "deleted for good, no events of the task follow",
+                "EventTaskRestored": "taken out of the trash",
+                "EventTaskRetried": "a failed or cancelled task started its next attempt"
+            },
+            "x-enum-varnames": [
+                "EventTaskCreated",
+                "EventTaskStarted",
+                "EventTaskCompleted",
+                "EventTaskFailed",
+                "EventTaskCancelled",
+                "EventTaskProgress",
+                "EventTaskRetried",
+                "EventTaskDeleted",
+                "EventTaskRestored",
+                "EventTaskPurged",
+                "EventTaskSnapshot"
+            ]
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskHistoryEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "executor-7"
+                },
+                "attempt": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "progress": {
+                    "type": "integer",
+                    "example": 40
+                },
+                "seq": {
+                    "description": "position in the history of the task, starts at 1",
+                    "type": "integer",
+                    "example": 3
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskStatus"
+                        }
+                    ],
+                    "example": "in_progress"
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEventType"
+                        }
+                    ],
+                    "example": "progress"
+                },
+                "version": {
+                    "description": "task version right after the event",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskHistoryMismatch": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "progress 40, stored 60"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskLogLine": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "level": {
+                    "type": "string",
+                    "example": "info"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "processed 100 rows"
+                },
+                "seq": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "task_id": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskPage": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string",
+                    "example": "eyJzIjoiLWNyZWF0ZWRfYXQiLCJ0IjoiMjAyNS0wMS0wMlQwMzowNDowNVoiLCJpZCI6IjZiY2QxNzVlLWNiYTktNGJhNi1iNmVmLWYzYWMzNzg2NDExOCJ9"
+                },
+                "tasks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.Task"
+                    }
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskState": {
+            "type": "object",
+            "properties": {
+                "progress": {
+                    "description": "percent reported by the executor, 100 once the task is completed",
+                    "type": "integer",
+                    "example": 40
+                },
+                "status": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskStatus"
+                },
+                "work_duration": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ],
+                    "example": 10
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.TaskStatus": {
+            "type": "string",
+            "enum": [
+                "failed",
+                "in_progress",
+                "completed",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "StatusFailed",
+                "StatusInProgress",
+                "StatusCompleted",
+                "StatusCancelled"
+            ]
+        },
+        "github_com_Util787_task-manager_internal_domain.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "attempted_at": {
+                    "type": "string"
+                },
+                "delivery_id": {
+                    "type": "string"
+                },
+                "duration": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ],
+                    "example": 10
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEvent"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "replay_of": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "subscription_id": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Util787_task-manager_internal_domain.WebhookSubscription": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "disabled_reason": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEventType"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/tasks"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.appendTaskLogsRequest": {
+            "type": "object",
+            "required": [
+                "lines"
+            ],
+            "properties": {
+                "attempt": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "lines": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/internal_adapters_http-adapter_handlers.taskLogLineRequest"
+                    }
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.appendTaskLogsResponse": {
+            "type": "object",
+            "properties": {
+                "appended": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "last_seq": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.checkTaskHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "checked": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "mismatches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskHistoryMismatch"
+                    }
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.createTaskRequest": {
+            "type": "object",
+            "required": [
+                "title"
+            ],
+            "properties": {
+                "callback_url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/tasks"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "billing",
+                        "nightly"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "report"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.createTaskResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "task created successfully with id 6bcd175e-cba9-4ba6-b6ef-f3ac37864118"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.deleteSubscriptionResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "webhook subscription deleted successfully"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.deleteTaskResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "task deleted successfully"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.errorResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.finishTaskRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "content_type": {
+                    "type": "string",
+                    "example": "application/json"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskStatus"
+                        }
+                    ],
+                    "example": "completed"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.finishTaskResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "task finished successfully"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.getSubscriptionDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.WebhookDelivery"
+                    }
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.getTaskDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.Delivery"
+                    }
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.getTaskHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskHistoryEvent"
+                    }
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.getTaskLogsResponse": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskLogLine"
+                    }
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.getTaskResultResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "task result: completed"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.getTaskResultV2Response": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string",
+                    "example": "2025-06-28T01:35:19.1864825+03:00"
+                },
+                "content_type": {
+                    "type": "string",
+                    "example": "application/json"
+                },
+                "content_url": {
+                    "type": "string",
+                    "example": "/api/v1/tasks/6bcd175e-cba9-4ba6-b6ef-f3ac37864118/result/content"
+                },
+                "result": {
+                    "type": "object"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.getTaskStateResponse": {
             "type": "object",
             "properties": {
                 "created_at": {
@@ -306,6 +2660,229 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_adapters_http-adapter_handlers.importTasksResponse": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer",
+                    "example": 98
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.ImportError"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "import stopped at line 7: task already exists"
+                },
+                "rejected": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.listSubscriptionsResponse": {
+            "type": "object",
+            "properties": {
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.WebhookSubscription"
+                    }
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.listTrashResponse": {
+            "type": "object",
+            "properties": {
+                "tasks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.Task"
+                    }
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.replayDeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "webhook delivery replay scheduled"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.reportTaskProgressRequest": {
+            "type": "object",
+            "required": [
+                "progress"
+            ],
+            "properties": {
+                "progress": {
+                    "type": "integer",
+                    "example": 40
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.reportTaskProgressResponse": {
+            "type": "object",
+            "properties": {
+                "state": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskState"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.restoreTaskResponse": {
+            "type": "object",
+            "properties": {
+                "task": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.Task"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.retryTaskResponse": {
+            "type": "object",
+            "properties": {
+                "task": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.Task"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.subscriptionRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEventType"
+                    },
+                    "example": [
+                        "completed",
+                        "failed"
+                    ]
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "billing"
+                    ]
+                },
+                "types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "report"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/tasks"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.subscriptionResponse": {
+            "type": "object",
+            "properties": {
+                "subscription": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.WebhookSubscription"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.taskLogLineRequest": {
+            "type": "object",
+            "required": [
+                "message"
+            ],
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "example": "info"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "processed 100 rows"
+                },
+                "time": {
+                    "type": "string",
+                    "example": "2025-06-28T01:31:19.1864825+03:00"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.updateSubscriptionRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEventType"
+                    },
+                    "example": [
+                        "completed",
+                        "failed"
+                    ]
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "billing"
+                    ]
+                },
+                "types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "report"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/tasks"
+                }
+            }
+        },
+        "internal_adapters_http-adapter_handlers.wsMessage": {
+            "type": "object",
+            "properties": {
+                "dropped": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "event": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEvent"
+                },
+                "filter": {
+                    "$ref": "#/definitions/github_com_Util787_task-manager_internal_domain.TaskEventFilter"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "event"
+                }
+            }
+        },
         "time.Duration": {
             "type": "integer",
             "enum": [
@@ -329,6 +2906,14 @@ const docTemplate = `{
                 "Hour"
             ]
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Admin token as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
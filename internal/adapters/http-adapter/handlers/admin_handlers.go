@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/pkg/logger/sl"
+	"github.com/gin-gonic/gin"
+)
+
+// BackupStorage godoc
+// @Summary Back up task storage
+// @Description Streams a consistent copy of the task database while the service keeps serving requests.
+// @Description Only storages with online backup support it (bbolt), requires the admin token
+// @Tags admin
+// @Produce application/octet-stream
+// @Security AdminToken
+// @Success 200 {file} binary "database file"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 500 {object} errorResponse "failed to back up storage"
+// @Failure 501 {object} errorResponse "storage does not support online backup"
+// @Router /admin/backup [get]
+func (h *Handlers) backupStorage(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	// backup size is not bounded, server write timeout must not cut it
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Debug("failed to clear write deadline", sl.Err(err))
+	}
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks-%s.bolt"`, time.Now().UTC().Format("20060102T150405Z")))
+
+	written, err := h.adminUsecase.BackupStorage(c.Request.Context(), c.Writer)
+	if err != nil {
+		if c.Writer.Written() {
+			// the status is already sent, the client sees a truncated file
+			log.Error("backup interrupted", slog.Int64("written", written), sl.Err(err))
+			c.Abort()
+			return
+		}
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		if errors.Is(err, domain.ErrBackupUnsupported) {
+			newErrorResponse(c, log, http.StatusNotImplemented, domain.ErrBackupUnsupported.Error(), err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to back up storage", err)
+		return
+	}
+
+	log.Info("storage backed up", slog.Int64("bytes", written))
+}
+
+type importTasksResponse struct {
+	domain.ImportReport
+	Message string `json:"message,omitempty" example:"import stopped at line 7: task already exists"`
+}
+
+// ExportTasks godoc
+// @Summary Export tasks
+// @Description Streams every stored task as NDJSON ordered by creation time, tasks in the trash included. Results kept in the result store
+// @Description are exported as references, the store has to be copied separately. Requires the admin token
+// @Tags admin
+// @Produce application/x-ndjson
+// @Security AdminToken
+// @Param status query string false "Only tasks with the status" Enums(in_progress, completed, failed, cancelled)
+// @Param created_from query string false "Only tasks created at or after the RFC3339 time"
+// @Param created_to query string false "Only tasks created before the RFC3339 time"
+// @Success 200 {object} domain.Task "a task per line"
+// @Failure 400 {object} errorResponse "invalid query"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 500 {object} errorResponse "failed to export tasks"
+// @Router /admin/export [get]
+func (h *Handlers) exportTasks(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	filter := domain.TaskExportFilter{Status: domain.TaskStatus(c.Query("status"))}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid status", domain.ErrInvalidStatus)
+		return
+	}
+	var err error
+	if raw := c.Query("created_from"); raw != "" {
+		if filter.CreatedFrom, err = time.Parse(time.RFC3339, raw); err != nil {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid created_from, must be RFC3339 time", err)
+			return
+		}
+	}
+	if raw := c.Query("created_to"); raw != "" {
+		if filter.CreatedTo, err = time.Parse(time.RFC3339, raw); err != nil {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid created_to, must be RFC3339 time", err)
+			return
+		}
+	}
+
+	// export size is not bounded, server write timeout must not cut it
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Debug("failed to clear write deadline", sl.Err(err))
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks-%s.ndjson"`, time.Now().UTC().Format("20060102T150405Z")))
+
+	encoder := json.NewEncoder(c.Writer)
+	exported := 0
+	err = h.adminUsecase.ExportTasks(c.Request.Context(), filter, func(task domain.Task) error {
+		exported++
+		return encoder.Encode(task)
+	})
+	if err != nil {
+		if c.Writer.Written() {
+			// the status is already sent, the client sees a truncated export
+			log.Error("export interrupted", slog.Int("exported", exported), sl.Err(err))
+			c.Abort()
+			return
+		}
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to export tasks", err)
+		return
+	}
+
+	// headers must be sent even if no task matched
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	log.Info("tasks exported", slog.Int("tasks", exported))
+}
+
+// ImportTasks godoc
+// @Summary Import tasks
+// @Description Stores tasks sent as NDJSON in the export format, tasks keep their ids, versions and timestamps and publish no events.
+// @Description Invalid lines are rejected and the import goes on. mode decides what happens to a task whose id is already stored:
+// @Description skip keeps the stored task, overwrite replaces it, fail (default) stops the import with 409, tasks imported before stay stored.
+// @Description Requires the admin token
+// @Tags admin
+// @Accept application/x-ndjson
+// @Produce json
+// @Security AdminToken
+// @Param mode query string false "What to do with conflicting ids" Enums(skip, overwrite, fail) default(fail)
+// @Param tasks body string true "A task per line"
+// @Success 200 {object} importTasksResponse "import report"
+// @Failure 400 {object} errorResponse "invalid mode or a line is too long"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 409 {object} importTasksResponse "import stopped at a conflicting id"
+// @Failure 500 {object} errorResponse "failed to import tasks"
+// @Router /admin/import [post]
+func (h *Handlers) importTasks(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	mode := domain.ImportFail
+	if raw := c.Query("mode"); raw != "" {
+		mode = domain.ImportMode(raw)
+	}
+
+	// import size is not bounded, server read timeout must not cut it
+	if err := http.NewResponseController(c.Writer).SetReadDeadline(time.Time{}); err != nil {
+		log.Debug("failed to clear read deadline", sl.Err(err))
+	}
+
+	report, err := h.adminUsecase.ImportTasks(c.Request.Context(), c.Request.Body, mode)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidImportMode):
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid mode, must be skip, overwrite or fail", err)
+		case errors.Is(err, domain.ErrImportLineTooLong):
+			newErrorResponse(c, log, http.StatusBadRequest, err.Error(), err)
+		case errors.Is(err, domain.ErrTaskExists):
+			log.Info("import stopped at conflicting task", slog.Int("accepted", report.Accepted), sl.Err(err))
+			c.JSON(http.StatusConflict, importTasksResponse{
+				ImportReport: report,
+				Message:      fmt.Sprintf("import stopped at a conflicting task, %d tasks imported before it", report.Accepted),
+			})
+		default:
+			newErrorResponse(c, log, http.StatusInternalServerError, "failed to import tasks", err)
+		}
+		return
+	}
+
+	log.Info("tasks imported", slog.Int("accepted", report.Accepted), slog.Int("rejected", report.Rejected))
+	c.JSON(http.StatusOK, importTasksResponse{
+		ImportReport: report,
+	})
+}
+
+// GetCacheStats godoc
+// @Summary Get task cache stats
+// @Description Returns hit and miss counters of the read-through cache in front of the task storage, requires the admin token
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} domain.CacheStats "cache stats"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 501 {object} errorResponse "task cache is disabled"
+// @Router /admin/cache [get]
+func (h *Handlers) getCacheStats(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	stats, err := h.adminUsecase.GetCacheStats()
+	if err != nil {
+		if errors.Is(err, domain.ErrCacheDisabled) {
+			newErrorResponse(c, log, http.StatusNotImplemented, domain.ErrCacheDisabled.Error(), err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to get cache stats", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// SweepResultBlobs godoc
+// @Summary Sweep result blobs
+// @Description Removes blobs of the result store that no stored task references, tasks in the trash included, and blobs put within the last hour are kept. Blobs are left behind by finishes whose task update failed, by retries and by purges. Requires the admin token
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} domain.ResultSweep "sweep report"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 500 {object} errorResponse "failed to sweep result blobs"
+// @Router /admin/results/sweep [post]
+func (h *Handlers) sweepResultBlobs(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	sweep, err := h.adminUsecase.SweepResultBlobs(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to sweep result blobs", err)
+		return
+	}
+
+	log.Info("result blobs swept", slog.Int("blobs", sweep.Blobs), slog.Int("removed", sweep.Removed), slog.Int64("removed_bytes", sweep.RemovedBytes))
+	c.JSON(http.StatusOK, sweep)
+}
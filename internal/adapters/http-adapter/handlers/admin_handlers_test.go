@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/adapters/http-adapter/handlers/middleware"
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/cache"
+	"github.com/Util787/task-manager/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type backuperStub struct {
+	data string
+}
+
+func (b backuperStub) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, b.data)
+	return int64(n), err
+}
+
+// backup storage tests
+
+func TestBackupStorage_OK(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	handlers.adminUsecase = usecase.NewAdminUsecase(repo, backuperStub{data: "bolt database"}, nil, nil)
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("GET", "/admin/backup", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	assert.Equal(t, "bolt database", w.Body.String())
+}
+
+func TestBackupStorage_Unsupported(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("GET", "/admin/backup", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "storage does not support online backup", response.Message)
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{name: "valid token", token: "secret", authorization: "Bearer secret", status: http.StatusOK},
+		{name: "wrong token", token: "secret", authorization: "Bearer guess", status: http.StatusUnauthorized},
+		{name: "missing token", token: "secret", status: http.StatusUnauthorized},
+		{name: "disabled", authorization: "Bearer ", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, repo := createTestHandlers()
+			handlers.adminUsecase = usecase.NewAdminUsecase(repo, backuperStub{data: "bolt database"}, nil, nil)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("op", "test")
+				c.Next()
+			})
+			router.GET("/admin/backup", middleware.AdminAuthMiddleware(tt.token), handlers.backupStorage)
+
+			// request
+			req, _ := http.NewRequest("GET", "/admin/backup", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// response check
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+// export and import tests
+
+func TestExportImportTasks_RoundTrip(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	running, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "running", TaskState: domain.TaskState{Status: domain.StatusInProgress}, Attempt: 1})
+	done, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "done", TaskState: domain.TaskState{Status: domain.StatusInProgress}, Attempt: 1})
+	_, err := handlers.taskUsecase.FinishTask(t.Context(), done, domain.StatusCompleted, json.RawMessage(`{"rows":3}`), "", domain.AnyVersion)
+	require.NoError(t, err)
+
+	// request
+	req, _ := http.NewRequest("GET", "/admin/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	export := w.Body.String()
+	lines := strings.Split(strings.TrimSpace(export), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], running.String(), "tasks are exported in creation order")
+
+	// request
+	req, _ = http.NewRequest("GET", "/admin/export?status=completed", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, strings.Count(w.Body.String(), "\n"))
+	assert.Contains(t, w.Body.String(), done.String())
+
+	// request
+	target, targetRepo := createTestHandlers()
+	targetRouter := setupTestRouter(target)
+	req, _ = http.NewRequest("POST", "/admin/import", strings.NewReader(export))
+	w = httptest.NewRecorder()
+	targetRouter.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"accepted":2,"rejected":0,"errors":[]}`, w.Body.String())
+
+	source, err := repo.GetTaskByID(t.Context(), done)
+	require.NoError(t, err)
+	imported, err := targetRepo.GetTaskByID(t.Context(), done)
+	require.NoError(t, err)
+	assert.Equal(t, source.Version, imported.Version)
+	assert.Equal(t, source.TaskState.Status, imported.TaskState.Status)
+	assert.JSONEq(t, `{"rows":3}`, string(imported.Result))
+	assert.True(t, source.CreatedAt.Equal(imported.CreatedAt))
+}
+
+func TestImportTasks_Modes(t *testing.T) {
+	id := uuid.New()
+	line := func(title string) string {
+		return `{"id":"` + id.String() + `","title":"` + title + `","task_state":{"status":"in_progress"},"version":3}`
+	}
+	body := strings.Join([]string{
+		`{"title":"no id","task_state":{"status":"in_progress"}}`,
+		"not json",
+		"",
+		line("imported"),
+	}, "\n")
+
+	tests := []struct {
+		name       string
+		mode       string
+		wantStatus int
+		wantTitle  string
+		wantReport domain.ImportReport
+	}{
+		{name: "skip", mode: "skip", wantStatus: http.StatusOK, wantTitle: "stored", wantReport: domain.ImportReport{Rejected: 3}},
+		{name: "overwrite", mode: "overwrite", wantStatus: http.StatusOK, wantTitle: "imported", wantReport: domain.ImportReport{Accepted: 1, Rejected: 2}},
+		{name: "fail by default", wantStatus: http.StatusConflict, wantTitle: "stored", wantReport: domain.ImportReport{Rejected: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, repo := createTestHandlers()
+			router := setupTestRouter(handlers)
+			require.NoError(t, repo.ImportTask(t.Context(), domain.Task{ID: id, Title: "stored", Version: 1}, false))
+
+			// request
+			req, _ := http.NewRequest("POST", "/admin/import?mode="+tt.mode, strings.NewReader(body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// response check
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			var response importTasksResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantReport.Accepted, response.Accepted)
+			assert.Equal(t, tt.wantReport.Rejected, response.Rejected)
+			require.Len(t, response.Errors, tt.wantReport.Rejected)
+			assert.Equal(t, 1, response.Errors[0].Line)
+			assert.Equal(t, "id is missing", response.Errors[0].Reason)
+			assert.Equal(t, 2, response.Errors[1].Line)
+
+			task, err := repo.GetTaskByID(t.Context(), id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTitle, task.Title)
+		})
+	}
+}
+
+func TestImportTasks_InvalidResultBlob(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	id := uuid.New()
+	body := `{"id":"` + id.String() + `","title":"stolen","task_state":{"status":"completed"},"result_blob":{"key":"` + strings.Repeat("../", 18) + `etc/passwd","size":1}}`
+
+	// request
+	req, _ := http.NewRequest("POST", "/admin/import?mode=skip", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response importTasksResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Rejected)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "invalid result blob key, must be a hex encoded sha256", response.Errors[0].Reason)
+
+	_, err := repo.GetTaskByID(t.Context(), id)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+}
+
+func TestImportTasks_InvalidMode(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("POST", "/admin/import?mode=merge", strings.NewReader(""))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"message":"invalid mode, must be skip, overwrite or fail"}`, w.Body.String())
+}
+
+func TestExportTasks_InvalidQuery(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	for query, message := range map[string]string{
+		"status=done":            "invalid status",
+		"created_from=yesterday": "invalid created_from, must be RFC3339 time",
+		"created_to=2025-13-01":  "invalid created_to, must be RFC3339 time",
+	} {
+		// request
+		req, _ := http.NewRequest("GET", "/admin/export?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// response check
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.JSONEq(t, `{"message":"`+message+`"}`, w.Body.String(), query)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	}
+}
+
+// cache stats tests
+
+func TestGetCacheStats(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	taskCache := cache.NewTaskRepository(repo, cache.Config{Size: 10, TTL: time.Minute})
+	handlers.adminUsecase = usecase.NewAdminUsecase(repo, nil, taskCache, nil)
+	router := setupTestRouter(handlers)
+
+	task := domain.Task{Title: "Cached"}
+	_, err := taskCache.CreateTask(t.Context(), &task)
+	require.NoError(t, err)
+	for range 3 {
+		_, err = taskCache.GetTaskByID(t.Context(), task.ID)
+		require.NoError(t, err)
+	}
+
+	// request
+	req, _ := http.NewRequest("GET", "/admin/cache", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"hits":2,"misses":1,"entries":1,"capacity":10,"ttl":"1m0s"}`, w.Body.String())
+}
+
+func TestGetCacheStats_Disabled(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("GET", "/admin/cache", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.JSONEq(t, `{"message":"task cache is disabled"}`, w.Body.String())
+}
+
+// result sweep tests
+
+func TestSweepResultBlobs(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	kept, err := deps.resultStore.Put([]byte("referenced result"))
+	require.NoError(t, err)
+	orphan, err := deps.resultStore.Put([]byte("orphaned result"))
+	require.NoError(t, err)
+	task := domain.Task{Title: "Finished", ResultBlob: &kept}
+	_, err = deps.repo.CreateTask(t.Context(), &task)
+	require.NoError(t, err)
+
+	// request
+	req, _ := http.NewRequest("POST", "/admin/results/sweep", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"blobs":2,"removed":1,"removed_bytes":15}`, w.Body.String())
+	assert.Contains(t, deps.resultStore.blobs, kept.Key)
+	assert.NotContains(t, deps.resultStore.blobs, orphan.Key)
+}
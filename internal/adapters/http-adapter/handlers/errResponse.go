@@ -1,17 +1,38 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 
 	"github.com/Util787/task-manager/pkg/logger/sl"
 	"github.com/gin-gonic/gin"
 )
 
+// statusClientClosedRequest is the nginx status for requests the client abandoned before the response was ready
+const statusClientClosedRequest = 499
+
 type errorResponse struct {
 	Message string `json:"message"`
 }
 
+// newErrorResponse aborts the request with the message. Internal errors caused by the end of the request context are not failures
+// of the service: they are answered with 499 when the client went away and 503 when a storage deadline ran out
 func newErrorResponse(c *gin.Context, log *slog.Logger, statusCode int, message string, err error) {
+	if statusCode == http.StatusInternalServerError {
+		switch {
+		case errors.Is(err, context.Canceled):
+			log.Info("request cancelled", slog.String("reason", message), sl.Err(err))
+			c.AbortWithStatusJSON(statusClientClosedRequest, errorResponse{"request cancelled"})
+			return
+		case errors.Is(err, context.DeadlineExceeded):
+			log.Warn("request timed out", slog.String("reason", message), sl.Err(err))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, errorResponse{"request timed out, try again later"})
+			return
+		}
+	}
+
 	log.Error(message, sl.Err(err))
 	c.AbortWithStatusJSON(statusCode, errorResponse{message})
 }
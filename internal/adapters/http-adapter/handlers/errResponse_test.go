@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Util787/task-manager/pkg/logger/handlers/slogdiscard"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewErrorResponse_ContextErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		wantStatus int
+		wantMsg    string
+	}{
+		{"cancelled", http.StatusInternalServerError, fmt.Errorf("TaskUsecase.GetTaskByID: %w", context.Canceled), statusClientClosedRequest, "request cancelled"},
+		{"deadline", http.StatusInternalServerError, fmt.Errorf("TaskUsecase.GetTaskByID: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, "request timed out, try again later"},
+		{"internal", http.StatusInternalServerError, errors.New("disk is full"), http.StatusInternalServerError, "failed to get task"},
+		{"client error keeps its status", http.StatusNotFound, context.Canceled, http.StatusNotFound, "failed to get task"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			newErrorResponse(c, slogdiscard.NewDiscardLogger(), tt.statusCode, "failed to get task", tt.err)
+
+			// response check
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			var response errorResponse
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMsg, response.Message)
+		})
+	}
+}
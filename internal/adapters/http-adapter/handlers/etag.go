@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// setETag sets the task version as a strong entity tag, clients send it back in If-Match to make conditional changes
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// parseIfMatch returns the task version required by the If-Match header, domain.AnyVersion when the header is missing or *
+func parseIfMatch(c *gin.Context) (int64, error) {
+	raw := strings.TrimSpace(c.GetHeader("If-Match"))
+	if raw == "" || raw == "*" {
+		return domain.AnyVersion, nil
+	}
+
+	unquoted, err := strconv.Unquote(raw)
+	if err != nil || !strings.HasPrefix(raw, `"`) {
+		return 0, fmt.Errorf("if-match %q is not a strong entity tag", raw)
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("if-match %q is not a task version", raw)
+	}
+	return version, nil
+}
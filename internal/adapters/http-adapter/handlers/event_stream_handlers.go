@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsMaxMessageSize    = 4096
+	wsDefaultWriteWait  = 10 * time.Second
+	wsPongWaitIntervals = 3 // connection is dropped after this many keep-alive intervals without a pong
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsMessage is sent by the server, Type is event, dropped, filter or error
+type wsMessage struct {
+	Type    string                  `json:"type" example:"event"`
+	Event   *domain.TaskEvent       `json:"event,omitempty"`
+	Dropped int                     `json:"dropped,omitempty" example:"12"`
+	Filter  *domain.TaskEventFilter `json:"filter,omitempty"`
+	Message string                  `json:"message,omitempty"`
+}
+
+// wsConn serializes writes, gorilla connections support only one concurrent writer
+type wsConn struct {
+	conn      *websocket.Conn
+	writeWait time.Duration
+	mu        sync.Mutex
+}
+
+func (w *wsConn) write(msg wsMessage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.writeWait)); err != nil {
+		return err
+	}
+	return w.conn.WriteJSON(msg)
+}
+
+func (w *wsConn) close(code int, text string) {
+	_ = w.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(w.writeWait))
+}
+
+// StreamEventsWS godoc
+// @Summary Stream events of all tasks over WebSocket
+// @Description Upgrades to WebSocket and sends lifecycle events of all tasks as {"type":"event","event":{...}} messages.
+// @Description Client messages are filters {"statuses":[...],"types":[...],"labels":[...]} that replace the current one and are acknowledged with {"type":"filter"},
+// @Description invalid ones are answered with {"type":"error"}. A slow client either gets {"type":"dropped","dropped":n} before the next event or is disconnected with code 1013
+// @Tags events
+// @Success 101 {object} wsMessage "switching protocols"
+// @Failure 400 {string} string "not a websocket handshake"
+// @Router /events/ws [get]
+func (h *Handlers) streamEventsWS(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// upgrader has already replied with the error status
+		log.Debug("Failed to upgrade to websocket", slog.String("reason", err.Error()))
+		return
+	}
+	defer conn.Close()
+
+	writeWait := h.writeTimeout
+	if writeWait <= 0 {
+		writeWait = wsDefaultWriteWait
+	}
+	ws := &wsConn{conn: conn, writeWait: writeWait}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	filters := make(chan domain.TaskEventFilter)
+	go h.readFilters(ctx, cancel, ws, filters)
+	go h.pingWS(ctx, cancel, ws)
+
+	err = h.eventStreamUsecase.StreamEvents(ctx, filters, func(event domain.TaskEvent, dropped int) error {
+		if dropped > 0 {
+			if err := ws.write(wsMessage{Type: "dropped", Dropped: dropped}); err != nil {
+				return err
+			}
+		}
+		return ws.write(wsMessage{Type: "event", Event: &event})
+	})
+	if errors.Is(err, domain.ErrSlowConsumer) {
+		log.Warn("Disconnecting slow events consumer", slog.String("remote_addr", c.Request.RemoteAddr))
+		ws.close(websocket.CloseTryAgainLater, domain.ErrSlowConsumer.Error())
+		return
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Debug("Events stream stopped", slog.String("reason", err.Error()))
+	}
+	ws.close(websocket.CloseNormalClosure, "")
+}
+
+// readFilters reads filter messages of the client until the connection is closed, which cancels the stream
+func (h *Handlers) readFilters(ctx context.Context, cancel context.CancelFunc, ws *wsConn, filters chan<- domain.TaskEventFilter) {
+	defer cancel()
+
+	pongWait := wsPongWaitIntervals * h.keepAliveInterval
+	ws.conn.SetReadLimit(wsMaxMessageSize)
+	_ = ws.conn.SetReadDeadline(time.Now().Add(pongWait))
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := ws.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var filter domain.TaskEventFilter
+		if err := json.Unmarshal(data, &filter); err != nil {
+			_ = ws.write(wsMessage{Type: "error", Message: "invalid filter: " + err.Error()})
+			continue
+		}
+		if err := filter.Validate(); err != nil {
+			_ = ws.write(wsMessage{Type: "error", Message: "invalid filter: " + err.Error()})
+			continue
+		}
+
+		select {
+		case filters <- filter:
+		case <-ctx.Done():
+			return
+		}
+		_ = ws.write(wsMessage{Type: "filter", Filter: &filter})
+	}
+}
+
+func (h *Handlers) pingWS(ctx context.Context, cancel context.CancelFunc, ws *wsConn) {
+	ticker := time.NewTicker(h.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(ws.writeWait)); err != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
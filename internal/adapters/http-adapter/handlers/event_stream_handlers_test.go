@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dialEventsWS(t *testing.T, server *httptest.Server) *websocket.Conn {
+	t.Helper()
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/events/ws", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readWSMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	var msg wsMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+// stream events ws tests
+
+func TestStreamEventsWS_Filter(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	server := httptest.NewServer(setupTestRouter(handlers))
+	defer server.Close()
+
+	conn := dialEventsWS(t, server)
+
+	// only failed tasks labeled billing
+	require.NoError(t, conn.WriteJSON(domain.TaskEventFilter{Statuses: []domain.TaskStatus{domain.StatusFailed}, Labels: []string{"billing"}}))
+	ack := readWSMessage(t, conn)
+	require.Equal(t, "filter", ack.Type)
+	assert.Equal(t, []string{"billing"}, ack.Filter.Labels)
+
+	// tasks
+	for _, labels := range [][]string{{"nightly"}, {"billing"}} {
+		taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", Labels: labels, TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+		postJSON(t, server.URL+"/tasks/"+taskID.String()+"/progress", map[string]int{"progress": 50})
+		postJSON(t, server.URL+"/tasks/"+taskID.String()+"/finish", finishTaskRequest{Status: domain.StatusFailed})
+	}
+
+	msg := readWSMessage(t, conn)
+	require.Equal(t, "event", msg.Type)
+	assert.Equal(t, domain.EventTaskFailed, msg.Event.Type)
+	assert.Equal(t, []string{"billing"}, msg.Event.Task.Labels)
+
+	// nothing else matches the filter
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, _, err := conn.ReadMessage()
+	assert.Error(t, err)
+}
+
+func TestStreamEventsWS_InvalidFilter(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	server := httptest.NewServer(setupTestRouter(handlers))
+	defer server.Close()
+
+	conn := dialEventsWS(t, server)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"statuses":["exploded"]}`)))
+	msg := readWSMessage(t, conn)
+	assert.Equal(t, "error", msg.Type)
+	assert.Contains(t, msg.Message, "invalid status")
+
+	// connection stays usable
+	require.NoError(t, conn.WriteJSON(domain.TaskEventFilter{Types: []string{"report"}}))
+	assert.Equal(t, "filter", readWSMessage(t, conn).Type)
+}
+
+func TestStreamEventsWS_NotWebSocket(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("GET", "/events/ws", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"log/slog"
 	"time"
 
@@ -8,18 +11,82 @@ import (
 	"github.com/google/uuid"
 )
 
+// defaultKeepAliveInterval is how often idle event streams send a comment, so proxies and clients do not drop them
+const defaultKeepAliveInterval = 15 * time.Second
+
 type Handlers struct {
-	log         *slog.Logger
-	taskUsecase TaskUsecase
+	log                *slog.Logger
+	writeTimeout       time.Duration // server write timeout, streaming handlers extend the deadline by it before every write
+	keepAliveInterval  time.Duration
+	taskUsecase        TaskUsecase
+	webhookUsecase     WebhookUsecase
+	taskLogUsecase     TaskLogUsecase
+	eventStreamUsecase EventStreamUsecase
+	adminUsecase       AdminUsecase
+	taskHistoryUsecase TaskHistoryUsecase
 }
 
 type TaskUsecase interface {
-	CreateTask(task *domain.Task) (uuid.UUID, error)
-	GetTaskStateByID(id uuid.UUID) (domain.TaskState, time.Time, error)
-	GetTaskResultByID(id uuid.UUID) (string, error)
-	DeleteTask(id uuid.UUID) error
+	CreateTask(ctx context.Context, task *domain.Task) (uuid.UUID, error)
+	GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error)
+	WaitTaskState(ctx context.Context, id uuid.UUID, until []domain.TaskStatus, wait time.Duration) (domain.Task, error)
+	GetTaskResultByID(ctx context.Context, id uuid.UUID) (domain.TaskResult, error)
+	OpenTaskResult(ctx context.Context, id uuid.UUID) (domain.ResultContent, error)
+	FinishTask(ctx context.Context, id uuid.UUID, status domain.TaskStatus, result json.RawMessage, contentType string, version int64) (domain.Task, error)
+	ReportProgress(ctx context.Context, id uuid.UUID, progress int, version int64) (domain.Task, error)
+	RetryTask(ctx context.Context, id uuid.UUID, version int64) (domain.Task, error)
+	WatchTask(ctx context.Context, id uuid.UUID) (<-chan domain.TaskEvent, error)
+	GetTaskDeliveries(ctx context.Context, id uuid.UUID) ([]domain.Delivery, error)
+	DeleteTask(ctx context.Context, id uuid.UUID, version int64) error
+	PurgeTask(ctx context.Context, id uuid.UUID, version int64) error
+	ListTasks(ctx context.Context, query domain.TaskListQuery) (domain.TaskPage, error)
+	ListDeletedTasks(ctx context.Context) ([]domain.Task, error)
+	RestoreTask(ctx context.Context, id uuid.UUID, version int64) (domain.Task, error)
+}
+
+type WebhookUsecase interface {
+	CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	GetSubscriptionByID(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) []domain.WebhookSubscription
+	UpdateSubscription(ctx context.Context, id uuid.UUID, changes domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	GetSubscriptionDeliveries(ctx context.Context, id uuid.UUID) ([]domain.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, subID, deliveryID uuid.UUID) error
+}
+
+type TaskLogUsecase interface {
+	AppendTaskLogs(ctx context.Context, id uuid.UUID, attempt int, lines []domain.TaskLogLine) ([]domain.TaskLogLine, error)
+	GetTaskLogs(ctx context.Context, id uuid.UUID, cursor domain.TaskLogCursor) ([]domain.TaskLogLine, error)
+	FollowTaskLogs(ctx context.Context, id uuid.UUID, cursor domain.TaskLogCursor, emit func(lines []domain.TaskLogLine) error) error
+}
+
+type EventStreamUsecase interface {
+	StreamEvents(ctx context.Context, filters <-chan domain.TaskEventFilter, emit func(event domain.TaskEvent, dropped int) error) error
+}
+
+type TaskHistoryUsecase interface {
+	GetTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error)
+	CheckTaskHistory(ctx context.Context) (int, []domain.TaskHistoryMismatch, error)
+}
+
+type AdminUsecase interface {
+	BackupStorage(ctx context.Context, w io.Writer) (int64, error)
+	ExportTasks(ctx context.Context, filter domain.TaskExportFilter, emit func(task domain.Task) error) error
+	ImportTasks(ctx context.Context, r io.Reader, mode domain.ImportMode) (domain.ImportReport, error)
+	GetCacheStats() (domain.CacheStats, error)
+	SweepResultBlobs(ctx context.Context) (domain.ResultSweep, error)
 }
 
-func New(log *slog.Logger, taskUsecase TaskUsecase) *Handlers {
-	return &Handlers{log: log, taskUsecase: taskUsecase}
+func New(log *slog.Logger, writeTimeout time.Duration, taskUsecase TaskUsecase, webhookUsecase WebhookUsecase, taskLogUsecase TaskLogUsecase, eventStreamUsecase EventStreamUsecase, adminUsecase AdminUsecase, taskHistoryUsecase TaskHistoryUsecase) *Handlers {
+	return &Handlers{
+		log:                log,
+		writeTimeout:       writeTimeout,
+		keepAliveInterval:  defaultKeepAliveInterval,
+		taskUsecase:        taskUsecase,
+		webhookUsecase:     webhookUsecase,
+		taskLogUsecase:     taskLogUsecase,
+		eventStreamUsecase: eventStreamUsecase,
+		adminUsecase:       adminUsecase,
+		taskHistoryUsecase: taskHistoryUsecase,
+	}
 }
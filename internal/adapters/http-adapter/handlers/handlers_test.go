@@ -2,15 +2,24 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Util787/task-manager/internal/adapters/http-adapter/handlers/middleware"
 	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/eventbus"
+	"github.com/Util787/task-manager/internal/infrastructure/eventstream"
 	"github.com/Util787/task-manager/internal/infrastructure/repo/inmemory"
+	"github.com/Util787/task-manager/internal/infrastructure/tasklog"
 	"github.com/Util787/task-manager/internal/usecase"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -28,22 +37,154 @@ func setupTestRouter(handlers *Handlers) *gin.Engine {
 		c.Set("op", "test")
 		c.Next()
 	})
+	router.Use(middleware.ActorMiddleware())
 
 	// routes init
 	router.POST("/tasks", handlers.createTask)
+	router.GET("/tasks", handlers.listTasks)
 	router.GET("/tasks/:id/state", handlers.getTaskStateByID)
 	router.GET("/tasks/:id/result", handlers.getTaskResultByID)
+	router.GET("/tasks/:id/result/content", handlers.getTaskResultContent)
+	router.GET("/v2/tasks/:id/result", handlers.getTaskResultByIDV2)
+	router.POST("/tasks/:id/finish", handlers.finishTask)
+	router.POST("/tasks/:id/progress", handlers.reportTaskProgress)
+	router.POST("/tasks/:id/retry", handlers.retryTask)
+	router.GET("/tasks/:id/events", handlers.streamTaskEvents)
+	router.GET("/tasks/:id/deliveries", handlers.getTaskDeliveries)
+	router.POST("/tasks/:id/logs", handlers.appendTaskLogs)
+	router.GET("/tasks/:id/logs", handlers.getTaskLogs)
+	router.GET("/tasks/:id/history", handlers.getTaskHistory)
 	router.DELETE("/tasks/:id", handlers.deleteTask)
+	router.GET("/trash", handlers.listTrash)
+	router.POST("/trash/:id/restore", handlers.restoreTask)
+	router.POST("/webhooks", handlers.createSubscription)
+	router.GET("/webhooks", handlers.listSubscriptions)
+	router.GET("/webhooks/:id", handlers.getSubscriptionByID)
+	router.PUT("/webhooks/:id", handlers.updateSubscription)
+	router.DELETE("/webhooks/:id", handlers.deleteSubscription)
+	router.GET("/webhooks/:id/deliveries", handlers.getSubscriptionDeliveries)
+	router.POST("/webhooks/:id/deliveries/:delivery_id/replay", handlers.replayDelivery)
+	router.GET("/events/ws", handlers.streamEventsWS)
+	router.GET("/admin/backup", handlers.backupStorage)
+	router.GET("/admin/export", handlers.exportTasks)
+	router.POST("/admin/import", handlers.importTasks)
+	router.GET("/admin/cache", handlers.getCacheStats)
+	router.POST("/admin/results/sweep", handlers.sweepResultBlobs)
+	router.GET("/admin/history/check", handlers.checkTaskHistory)
 
 	return router
 }
 
+// publisherStub records published events and passes them to the bus, so watchers get them too
+type publisherStub struct {
+	*eventbus.Bus
+	events []domain.TaskEvent
+}
+
+func (p *publisherStub) Publish(event domain.TaskEvent) {
+	p.events = append(p.events, event)
+	p.Bus.Publish(event)
+}
+
+type replayerStub struct {
+	replayed []domain.WebhookDelivery
+}
+
+func (r *replayerStub) Replay(delivery domain.WebhookDelivery) {
+	r.replayed = append(r.replayed, delivery)
+}
+
+// resultStoreStub keeps blobs in memory, results longer than testResultInlineLimit are stored in it
+type resultStoreStub struct {
+	blobs map[string][]byte
+}
+
+const testResultInlineLimit = 32
+
+func (s *resultStoreStub) Put(content []byte) (domain.ResultBlob, error) {
+	key := fmt.Sprintf("%064x", len(s.blobs)+1)
+	s.blobs[key] = content
+	return domain.ResultBlob{Key: key, Size: int64(len(content))}, nil
+}
+
+func (s *resultStoreStub) Open(key string) (io.ReadSeekCloser, time.Time, error) {
+	content, exists := s.blobs[key]
+	if !exists {
+		return nil, time.Time{}, domain.ErrResultBlobMissing
+	}
+	return readSeekNopCloser{bytes.NewReader(content)}, time.Now(), nil
+}
+
+// WalkBlobs reports every blob as put long ago
+func (s *resultStoreStub) WalkBlobs(fn func(key string, size int64, modTime time.Time) error) error {
+	for key, content := range s.blobs {
+		if err := fn(key, int64(len(content)), time.Time{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *resultStoreStub) RemoveBlob(key string, before time.Time) (bool, error) {
+	_, exists := s.blobs[key]
+	delete(s.blobs, key)
+	return exists, nil
+}
+
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error { return nil }
+
+// resultValidatorStub rejects results of "strict" tasks that are not JSON objects
+type resultValidatorStub struct{}
+
+func (resultValidatorStub) ValidateResult(taskType string, result json.RawMessage) error {
+	if taskType == "strict" && !bytes.HasPrefix(result, []byte("{")) {
+		return domain.ErrResultSchemaViolation
+	}
+	return nil
+}
+
+type testDeps struct {
+	repo                *inmemory.TaskRepository
+	deliveryRepo        *inmemory.DeliveryRepository
+	subRepo             *inmemory.SubscriptionRepository
+	webhookDeliveryRepo *inmemory.WebhookDeliveryRepository
+	resultStore         *resultStoreStub
+	publisher           *publisherStub
+	replayer            *replayerStub
+	taskLogStore        *tasklog.Store
+}
+
 func createTestHandlers() (*Handlers, *inmemory.TaskRepository) {
+	handlers, deps := createTestHandlersWithDeps()
+	return handlers, deps.repo
+}
+
+func createTestHandlersWithDeps() (*Handlers, testDeps) {
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
-	repo := inmemory.NewTaskRepository(logger)
-	usecase := usecase.NewTaskUsecase(repo)
-	handlers := New(logger, usecase)
-	return handlers, repo
+	deps := testDeps{
+		repo:                inmemory.NewTaskRepository(logger),
+		deliveryRepo:        inmemory.NewDeliveryRepository(inmemory.DeliveryLogConfig{MaxAttempts: 100, Retention: time.Hour}),
+		subRepo:             inmemory.NewSubscriptionRepository(),
+		webhookDeliveryRepo: inmemory.NewWebhookDeliveryRepository(inmemory.DeliveryLogConfig{MaxAttempts: 100, Retention: time.Hour}),
+		resultStore:         &resultStoreStub{blobs: make(map[string][]byte)},
+		publisher:           &publisherStub{Bus: eventbus.New()},
+		replayer:            &replayerStub{},
+		taskLogStore:        tasklog.NewStore(tasklog.Config{MaxLines: 100, MaxLineBytes: 64, Retention: time.Hour}),
+	}
+	taskUsecase := usecase.NewTaskUsecase(deps.repo, deps.deliveryRepo, deps.resultStore, testResultInlineLimit, resultValidatorStub{}, deps.publisher, deps.publisher)
+	webhookUsecase := usecase.NewWebhookUsecase(deps.subRepo, deps.webhookDeliveryRepo, deps.replayer)
+	taskLogUsecase := usecase.NewTaskLogUsecase(deps.repo, deps.taskLogStore)
+	eventHub, _ := eventstream.NewHub(eventstream.Config{Buffer: 16, SlowConsumerPolicy: eventstream.PolicyDrop})
+	deps.publisher.Subscribe(eventHub)
+	eventStreamUsecase := usecase.NewEventStreamUsecase(eventHub)
+	adminUsecase := usecase.NewAdminUsecase(deps.repo, nil, nil, deps.resultStore)
+	taskHistoryUsecase := usecase.NewTaskHistoryUsecase(deps.repo)
+	handlers := New(logger, time.Second, taskUsecase, webhookUsecase, taskLogUsecase, eventStreamUsecase, adminUsecase, taskHistoryUsecase)
+	return handlers, deps
 }
 
 // create task tests
@@ -182,6 +323,140 @@ func TestCreateTask_TitleTooLong(t *testing.T) {
 	assert.Contains(t, response.Message, "invalid request body")
 }
 
+func TestCreateTask_InvalidCallbackURL(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	requestBody := createTaskRequest{
+		Title:       "Test Task",
+		CallbackURL: "ftp://example.com/hook",
+	}
+	jsonBody, _ := json.Marshal(requestBody)
+
+	req, _ := http.NewRequest("POST", "/tasks", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Message, "invalid callback url")
+}
+
+// list tasks tests
+
+func TestListTasks_FilterAndPages(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	var reports []uuid.UUID
+	for i := range 3 {
+		id, _ := repo.CreateTask(t.Context(), &domain.Task{Title: fmt.Sprintf("report %d", i), Type: "report", Labels: []string{"billing"}})
+		reports = append(reports, id)
+		time.Sleep(time.Millisecond)
+	}
+	repo.CreateTask(t.Context(), &domain.Task{Title: "export", Type: "export", Labels: []string{"billing"}})
+	deleted, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "deleted report", Type: "report"})
+	req, _ := http.NewRequest("DELETE", "/tasks/"+deleted.String(), nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var got []uuid.UUID
+	cursor := ""
+	for range len(reports) + 1 {
+		// request
+		req, _ := http.NewRequest("GET", "/tasks?type=report&label=billing,urgent&sort=created_at&limit=2&cursor="+cursor, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// response check
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var page domain.TaskPage
+		err := json.Unmarshal(w.Body.Bytes(), &page)
+		assert.NoError(t, err)
+		for _, task := range page.Tasks {
+			got = append(got, task.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, reports, got, "deleted and other tasks are not listed")
+}
+
+func TestListTasks_LatestCreatedFirst(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	first, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "first"})
+	time.Sleep(time.Millisecond)
+	second, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "second"})
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "next_cursor")
+
+	var page domain.TaskPage
+	err := json.Unmarshal(w.Body.Bytes(), &page)
+	assert.NoError(t, err)
+	if assert.Len(t, page.Tasks, 2) {
+		assert.Equal(t, second, page.Tasks[0].ID)
+		assert.Equal(t, first, page.Tasks[1].ID)
+	}
+}
+
+func TestListTasks_Empty(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks?status=failed", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"tasks":[]}`, w.Body.String())
+}
+
+func TestListTasks_InvalidQuery(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	updatedCursor := domain.TaskCursor{Sort: domain.SortUpdatedAsc, At: time.Now(), ID: uuid.New()}.Encode()
+	for _, query := range []string{
+		"status=done",
+		"sort=title",
+		"limit=0",
+		"limit=201",
+		"limit=ten",
+		"created_from=yesterday",
+		"updated_to=2025-01-02",
+		"cursor=not-a-cursor",
+		"cursor=" + updatedCursor, // made for another sort
+	} {
+		// request
+		req, _ := http.NewRequest("GET", "/tasks?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// response check
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
 // get task state tests
 
 func TestGetTaskStateByID_OK(t *testing.T) {
@@ -198,7 +473,7 @@ func TestGetTaskStateByID_OK(t *testing.T) {
 		},
 	}
 
-	taskID := repo.CreateTask(task)
+	taskID, _ := repo.CreateTask(t.Context(), task)
 
 	// request
 	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/state", nil)
@@ -214,6 +489,7 @@ func TestGetTaskStateByID_OK(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, task.TaskState, response.State)
 	assert.WithinDuration(t, task.CreatedAt, response.CreatedAt, timeDelta)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
 }
 
 func TestGetTaskStateByID_InvalidUUID(t *testing.T) {
@@ -255,6 +531,99 @@ func TestGetTaskStateByID_TaskNotFound(t *testing.T) {
 	assert.Equal(t, "task not found", response.Message)
 }
 
+func TestGetTaskStateByID_WaitUntilFinished(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// executor finishes the task while the client waits
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusFailed})
+		finishReq, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+		finishReq.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), finishReq)
+	}()
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/state?wait=5s&until=completed,failed", nil)
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Less(t, time.Since(start), time.Second)
+
+	var response getTaskStateResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusFailed, response.State.Status)
+}
+
+func TestGetTaskStateByID_WaitExpires(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/state?wait=50ms", nil)
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	var response getTaskStateResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusInProgress, response.State.Status)
+}
+
+func TestGetTaskStateByID_WaitClientLeft(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/tasks/"+taskID.String()+"/state?wait=30s", nil)
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestGetTaskStateByID_InvalidWait(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	for _, query := range []string{"wait=forever", "wait=2h", "wait=1s&until=done"} {
+		// request
+		req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/state?"+query, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// response check
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
 // get task result tests
 
 func TestGetTaskResultByID_OK(t *testing.T) {
@@ -265,10 +634,10 @@ func TestGetTaskResultByID_OK(t *testing.T) {
 	task := &domain.Task{
 		Title:       "Test Task",
 		Description: "Test Description",
-		Result:      "task completed successfully",
+		Result:      json.RawMessage(`"task completed successfully"`),
 	}
 
-	taskID := repo.CreateTask(task)
+	taskID, _ := repo.CreateTask(t.Context(), task)
 
 	// request
 	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/result", nil)
@@ -282,7 +651,7 @@ func TestGetTaskResultByID_OK(t *testing.T) {
 	var response getTaskResultResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "task result: "+task.Result, response.Message)
+	assert.Equal(t, "task result: task completed successfully", response.Message)
 }
 
 func TestGetTaskResultByID_InvalidUUID(t *testing.T) {
@@ -324,6 +693,214 @@ func TestGetTaskResultByID_TaskNotFound(t *testing.T) {
 	assert.Equal(t, "task not found", response.Message)
 }
 
+// get task result v2 tests
+
+func TestGetTaskResultByIDV2_OK(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", Type: "strict", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// finish task
+	jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusCompleted, Result: json.RawMessage(`{"rows":3}`)})
+	finishReq, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+	finishReq.Header.Set("Content-Type", "application/json")
+	finishW := httptest.NewRecorder()
+	router.ServeHTTP(finishW, finishReq)
+	assert.Equal(t, http.StatusOK, finishW.Code)
+
+	// request
+	req, _ := http.NewRequest("GET", "/v2/tasks/"+taskID.String()+"/result", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response getTaskResultV2Response
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"rows":3}`, string(response.Result))
+	assert.Equal(t, domain.DefaultResultContentType, response.ContentType)
+	if assert.NotNil(t, response.CompletedAt) {
+		assert.WithinDuration(t, time.Now(), *response.CompletedAt, timeDelta)
+	}
+	assert.Empty(t, response.ContentURL)
+
+	// v1 keeps the message format
+	v1Req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/result", nil)
+	v1W := httptest.NewRecorder()
+	router.ServeHTTP(v1W, v1Req)
+
+	var v1Response getTaskResultResponse
+	err = json.Unmarshal(v1W.Body.Bytes(), &v1Response)
+	assert.NoError(t, err)
+	assert.Equal(t, `task result: {"rows":3}`, v1Response.Message)
+}
+
+func TestGetTaskResultByIDV2_NotFinished(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	req, _ := http.NewRequest("GET", "/v2/tasks/"+taskID.String()+"/result", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"result":null,"completed_at":null}`, w.Body.String())
+}
+
+func TestFinishTask_ResultSchemaViolation(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", Type: "strict", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusCompleted, Result: json.RawMessage(`[1,2,3]`)})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Message, "does not match the schema")
+
+	task, err := repo.GetTaskByID(t.Context(), taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusInProgress, task.TaskState.Status)
+}
+
+// get task result content tests
+
+func TestGetTaskResultContent_Inline(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{
+		Title:             "Test Task",
+		TaskState:         domain.TaskState{Status: domain.StatusCompleted},
+		Result:            json.RawMessage(`"short result"`),
+		ResultContentType: "application/json",
+	})
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/result/content", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "14", w.Header().Get("Content-Length"))
+	assert.Equal(t, `"short result"`, w.Body.String())
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+}
+
+func TestGetTaskResultContent_NotModified(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{
+		Title:     "Test Task",
+		TaskState: domain.TaskState{Status: domain.StatusCompleted},
+		Result:    json.RawMessage(`"short result"`),
+	})
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/result/content", nil)
+	req.Header.Set("If-None-Match", `"1"`)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestGetTaskResultContent_StoredResultWithRange(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := deps.repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// finish task with result that does not fit inline limit
+	result := `{"rows":["` + strings.Repeat("a", 100) + `"]}`
+	jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusCompleted, Result: json.RawMessage(result), ContentType: "application/vnd.report+json"})
+	finishReq, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+	finishReq.Header.Set("Content-Type", "application/json")
+	finishW := httptest.NewRecorder()
+	router.ServeHTTP(finishW, finishReq)
+	assert.Equal(t, http.StatusOK, finishW.Code)
+
+	// v1 result points to the content endpoint
+	resultReq, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/result", nil)
+	resultW := httptest.NewRecorder()
+	router.ServeHTTP(resultW, resultReq)
+
+	var resultResponse getTaskResultResponse
+	err := json.Unmarshal(resultW.Body.Bytes(), &resultResponse)
+	assert.NoError(t, err)
+	assert.Contains(t, resultResponse.Message, "/result/content")
+
+	// whole content
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/result/content", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/vnd.report+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, strconv.Itoa(len(result)), w.Header().Get("Content-Length"))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.Equal(t, result, w.Body.String())
+
+	// range
+	rangeReq, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/result/content", nil)
+	rangeReq.Header.Set("Range", "bytes=0-6")
+	rangeW := httptest.NewRecorder()
+	router.ServeHTTP(rangeW, rangeReq)
+
+	assert.Equal(t, http.StatusPartialContent, rangeW.Code)
+	assert.Equal(t, "7", rangeW.Header().Get("Content-Length"))
+	assert.Equal(t, fmt.Sprintf("bytes 0-6/%d", len(result)), rangeW.Header().Get("Content-Range"))
+	assert.Equal(t, `{"rows"`, rangeW.Body.String())
+}
+
+func TestGetTaskResultContent_NotFinished(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/result/content", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "task is not finished", response.Message)
+}
+
 // delete task tests
 
 func TestDeleteTask_OK(t *testing.T) {
@@ -336,7 +913,7 @@ func TestDeleteTask_OK(t *testing.T) {
 		Description: "Test Description",
 	}
 
-	taskID := repo.CreateTask(task)
+	taskID, _ := repo.CreateTask(t.Context(), task)
 
 	// request
 	req, _ := http.NewRequest("DELETE", "/tasks/"+taskID.String(), nil)
@@ -352,10 +929,67 @@ func TestDeleteTask_OK(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "task deleted successfully", response.Message)
 
-	// check if task is deleted
-	_, _, err = repo.GetTaskStateByID(taskID)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "task not found")
+	// check if task is in the trash and hidden from reads
+	stored, err := repo.GetTaskByID(t.Context(), taskID)
+	assert.NoError(t, err)
+	assert.True(t, stored.IsDeleted())
+
+	req, _ = http.NewRequest("GET", "/tasks/"+taskID.String()+"/state", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// deleting it again is reported as not found
+	req, _ = http.NewRequest("DELETE", "/tasks/"+taskID.String(), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteTask_VersionMismatch(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+	_, err := repo.UpdateTask(t.Context(), taskID, func(task *domain.Task) error { return nil })
+	assert.NoError(t, err)
+
+	// request
+	req, _ := http.NewRequest("DELETE", "/tasks/"+taskID.String(), nil)
+	req.Header.Set("If-Match", `"1"`)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	var response errorResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "task version does not match", response.Message)
+
+	_, err = repo.GetTaskByID(t.Context(), taskID)
+	assert.NoError(t, err, "task is kept")
+}
+
+func TestDeleteTask_InvalidIfMatch(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+
+	for _, ifMatch := range []string{"1", `W/"1"`, `"0"`, `"one"`, `"1", "2"`} {
+		// request
+		req, _ := http.NewRequest("DELETE", "/tasks/"+taskID.String(), nil)
+		req.Header.Set("If-Match", ifMatch)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// response check
+		assert.Equal(t, http.StatusBadRequest, w.Code, ifMatch)
+	}
 }
 
 func TestDeleteTask_InvalidUUID(t *testing.T) {
@@ -397,6 +1031,206 @@ func TestDeleteTask_TaskNotFound(t *testing.T) {
 	assert.Equal(t, "task not found", response.Message)
 }
 
+// finish task tests
+
+func TestFinishTask_OK(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	repo := deps.repo
+	router := setupTestRouter(handlers)
+
+	// create task
+	task := &domain.Task{
+		Title:       "Test Task",
+		CallbackURL: "https://example.com/hook",
+		TaskState: domain.TaskState{
+			Status: domain.StatusInProgress,
+		},
+	}
+
+	taskID, _ := repo.CreateTask(t.Context(), task)
+
+	// request
+	jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusCompleted, Result: json.RawMessage(`"done"`)})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response finishTaskResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "task finished successfully", response.Message)
+
+	// check that task is finished and event is published
+	finished, err := repo.GetTaskByID(t.Context(), taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusCompleted, finished.TaskState.Status)
+
+	if assert.Len(t, deps.publisher.events, 1) {
+		assert.Equal(t, domain.EventTaskCompleted, deps.publisher.events[0].Type)
+		assert.Equal(t, taskID, deps.publisher.events[0].Task.ID)
+		assert.JSONEq(t, `"done"`, string(deps.publisher.events[0].Task.Result))
+	}
+}
+
+func TestFinishTask_IfMatch(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusCompleted})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+}
+
+func TestFinishTask_VersionMismatch(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := deps.repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	_, err := deps.repo.UpdateTask(t.Context(), taskID, func(task *domain.Task) error {
+		task.TaskState.Progress = 40
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// request
+	jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusCompleted})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	var response errorResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "task version does not match", response.Message)
+
+	task, err := deps.repo.GetTaskByID(t.Context(), taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusInProgress, task.TaskState.Status)
+	assert.Empty(t, deps.publisher.events)
+}
+
+func TestFinishTask_InvalidStatus(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+
+	// request
+	jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusInProgress})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Message, "invalid status")
+}
+
+func TestFinishTask_AlreadyFinished(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{
+		Title: "Test Task",
+		TaskState: domain.TaskState{
+			Status: domain.StatusFailed,
+		},
+	})
+
+	// request
+	jsonBody, _ := json.Marshal(finishTaskRequest{Status: domain.StatusCompleted})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/finish", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "task is already finished", response.Message)
+}
+
+// get task deliveries tests
+
+func TestGetTaskDeliveries_OK(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := deps.repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", CallbackURL: "https://example.com/hook"})
+	deps.deliveryRepo.SaveDelivery(domain.Delivery{ID: uuid.New(), TaskID: taskID, URL: "https://example.com/hook", Attempt: 1, AttemptedAt: time.Now(), StatusCode: http.StatusBadGateway})
+	deps.deliveryRepo.SaveDelivery(domain.Delivery{ID: uuid.New(), TaskID: taskID, URL: "https://example.com/hook", Attempt: 2, AttemptedAt: time.Now(), StatusCode: http.StatusOK, Success: true})
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/deliveries", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response getTaskDeliveriesResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	if assert.Len(t, response.Deliveries, 2) {
+		assert.Equal(t, 1, response.Deliveries[0].Attempt)
+		assert.False(t, response.Deliveries[0].Success)
+		assert.Equal(t, 2, response.Deliveries[1].Attempt)
+		assert.True(t, response.Deliveries[1].Success)
+	}
+}
+
+func TestGetTaskDeliveries_TaskNotFound(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+uuid.New().String()+"/deliveries", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "task not found", response.Message)
+}
+
 // Integration test
 func TestTaskFullLifecycle(t *testing.T) {
 	handlers, _ := createTestHandlers()
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ActorHeader names who makes the request, it is recorded in the task history
+	ActorHeader = "X-Actor"
+	// AnonymousActor is recorded for requests without the actor header
+	AnonymousActor = "anonymous"
+
+	maxActorLength = 128
+)
+
+// ActorMiddleware puts the actor of ActorHeader into the request context, the header is not authenticated
+// and only tells the history who made the change. Actors longer than 128 characters are cut
+func ActorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := strings.TrimSpace(c.GetHeader(ActorHeader))
+		if utf8.RuneCountInString(actor) > maxActorLength {
+			actor = string([]rune(actor)[:maxActorLength])
+		}
+		if actor == "" {
+			actor = AnonymousActor
+		}
+
+		c.Request = c.Request.WithContext(domain.ContextWithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
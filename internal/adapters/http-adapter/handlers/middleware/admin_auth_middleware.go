@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuthMiddleware lets through only requests with "Authorization: Bearer <token>", admin endpoints are disabled when token is empty
+func AdminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "admin endpoints are disabled"})
+			return
+		}
+
+		provided, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "invalid admin token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AdminAuthWhen requires the admin token like AdminAuthMiddleware only for requests that need reports true for,
+// so a public endpoint can keep some of its options to admins
+func AdminAuthWhen(token string, need func(c *gin.Context) bool) gin.HandlerFunc {
+	auth := AdminAuthMiddleware(token)
+	return func(c *gin.Context) {
+		if need(c) {
+			auth(c)
+			return
+		}
+		c.Next()
+	}
+}
@@ -8,7 +8,8 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func (h *Handlers) InitRoutes(env string) *gin.Engine {
+// InitRoutes registers the API, admin routes, webhook subscriptions and hard deletes accept only requests with adminToken
+func (h *Handlers) InitRoutes(env string, adminToken string) *gin.Engine {
 	if env == "prod" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -22,6 +23,7 @@ func (h *Handlers) InitRoutes(env string) *gin.Engine {
 
 	router.Use(gin.Recovery())
 	router.Use(middleware.LoggingMiddleware(h.log))
+	router.Use(middleware.ActorMiddleware())
 	api := router.Group("/api")
 	{
 		v1 := api.Group("/v1")
@@ -29,9 +31,60 @@ func (h *Handlers) InitRoutes(env string) *gin.Engine {
 			tasks := v1.Group("/tasks")
 			{
 				tasks.POST("/", h.createTask)
+				tasks.GET("/", h.listTasks)
 				tasks.GET("/:id/state", h.getTaskStateByID)
 				tasks.GET("/:id/result", h.getTaskResultByID)
-				tasks.DELETE("/:id", h.deleteTask)
+				tasks.GET("/:id/result/content", h.getTaskResultContent)
+				tasks.POST("/:id/finish", h.finishTask)
+				tasks.POST("/:id/progress", h.reportTaskProgress)
+				tasks.POST("/:id/retry", h.retryTask)
+				tasks.GET("/:id/events", h.streamTaskEvents)
+				tasks.GET("/:id/deliveries", h.getTaskDeliveries)
+				tasks.POST("/:id/logs", h.appendTaskLogs)
+				tasks.GET("/:id/logs", h.getTaskLogs)
+				tasks.GET("/:id/history", h.getTaskHistory)
+				tasks.DELETE("/:id", middleware.AdminAuthWhen(adminToken, isHardDelete), h.deleteTask)
+			}
+
+			trash := v1.Group("/trash")
+			{
+				trash.GET("/", h.listTrash)
+				trash.POST("/:id/restore", h.restoreTask)
+			}
+
+			// subscriptions choose where task data is sent, only admins manage them
+			webhooks := v1.Group("/webhooks", middleware.AdminAuthMiddleware(adminToken))
+			{
+				webhooks.POST("/", h.createSubscription)
+				webhooks.GET("/", h.listSubscriptions)
+				webhooks.GET("/:id", h.getSubscriptionByID)
+				webhooks.PUT("/:id", h.updateSubscription)
+				webhooks.DELETE("/:id", h.deleteSubscription)
+				webhooks.GET("/:id/deliveries", h.getSubscriptionDeliveries)
+				webhooks.POST("/:id/deliveries/:delivery_id/replay", h.replayDelivery)
+			}
+
+			events := v1.Group("/events")
+			{
+				events.GET("/ws", h.streamEventsWS)
+			}
+
+			admin := v1.Group("/admin", middleware.AdminAuthMiddleware(adminToken))
+			{
+				admin.GET("/backup", h.backupStorage)
+				admin.GET("/export", h.exportTasks)
+				admin.POST("/import", h.importTasks)
+				admin.GET("/cache", h.getCacheStats)
+				admin.POST("/results/sweep", h.sweepResultBlobs)
+				admin.GET("/history/check", h.checkTaskHistory)
+			}
+		}
+
+		v2 := api.Group("/v2")
+		{
+			tasks := v2.Group("/tasks")
+			{
+				tasks.GET("/:id/result", h.getTaskResultByIDV2)
 			}
 		}
 	}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamWriter writes long living responses. Server write timeout would cut them, so the write deadline
+// is moved forward by the write timeout before every write, which keeps the timeout for every single write
+type streamWriter struct {
+	c            *gin.Context
+	rc           *http.ResponseController
+	writeTimeout time.Duration
+	contentType  string
+	started      bool
+}
+
+func newStreamWriter(c *gin.Context, writeTimeout time.Duration, contentType string) *streamWriter {
+	return &streamWriter{
+		c:            c,
+		rc:           http.NewResponseController(c.Writer),
+		writeTimeout: writeTimeout,
+		contentType:  contentType,
+	}
+}
+
+// start sends response headers once
+func (s *streamWriter) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+
+	s.c.Header("Content-Type", s.contentType)
+	s.c.Header("Cache-Control", "no-cache")
+	s.c.Header("X-Accel-Buffering", "no") // disable proxy buffering
+	s.c.Status(http.StatusOK)
+	s.c.Writer.WriteHeaderNow()
+}
+
+// write extends the write deadline, runs fn that writes to the response and flushes it to the client
+func (s *streamWriter) write(fn func() error) error {
+	if s.writeTimeout > 0 {
+		// not every writer supports deadlines (e.g. httptest.ResponseRecorder), the server one does
+		_ = s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	}
+
+	s.start()
+	if err := fn(); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// extendWriteDeadline gives the response another write timeout, for handlers that wait before replying
+func extendWriteDeadline(c *gin.Context, writeTimeout time.Duration) {
+	if writeTimeout > 0 {
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+}
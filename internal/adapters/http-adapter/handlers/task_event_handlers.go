@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type reportTaskProgressRequest struct {
+	Progress *int `json:"progress" binding:"required" example:"40"`
+}
+
+type reportTaskProgressResponse struct {
+	State domain.TaskState `json:"state"`
+}
+
+// ReportTaskProgress godoc
+// @Summary Report task progress
+// @Description Stores the progress percent of the running task, watchers of the task get it as a progress event.
+// @Description With If-Match the progress is stored only if the task ETag still matches
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID" format(uuid)
+// @Param If-Match header string false "ETag of the task version the change is based on, * matches any version"
+// @Param progress body reportTaskProgressRequest true "Progress percent from 0 to 100"
+// @Success 200 {object} reportTaskProgressResponse "task state"
+// @Header 200 {string} ETag "new task version"
+// @Failure 400 {object} errorResponse "invalid task ID, If-Match or request body"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 409 {object} errorResponse "task is already finished"
+// @Failure 412 {object} errorResponse "task version does not match"
+// @Failure 500 {object} errorResponse "failed to report task progress"
+// @Router /tasks/{id}/progress [post]
+func (h *Handlers) reportTaskProgress(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	var req reportTaskProgressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid If-Match, must be a task ETag or *", err)
+		return
+	}
+
+	task, err := h.taskUsecase.ReportProgress(c.Request.Context(), uuid, *req.Progress, version)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidProgress) {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid request body: "+err.Error(), err)
+			return
+		}
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			newErrorResponse(c, log, http.StatusPreconditionFailed, "task version does not match", err)
+			return
+		}
+		if errors.Is(err, domain.ErrTaskAlreadyFinished) {
+			newErrorResponse(c, log, http.StatusConflict, "task is already finished", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to report task progress", err)
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, reportTaskProgressResponse{
+		State: task.TaskState,
+	})
+}
+
+type retryTaskResponse struct {
+	Task domain.Task `json:"task"`
+}
+
+// RetryTask godoc
+// @Summary Retry failed task
+// @Description Starts the next attempt of the failed or cancelled task, the result of the last attempt is dropped and watchers get a retried event.
+// @Description With If-Match the task is retried only if its ETag still matches
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID" format(uuid)
+// @Param If-Match header string false "ETag of the task version the change is based on, * matches any version"
+// @Success 200 {object} retryTaskResponse "retried task"
+// @Header 200 {string} ETag "new task version"
+// @Failure 400 {object} errorResponse "invalid task ID or If-Match"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 409 {object} errorResponse "task is not failed or cancelled"
+// @Failure 412 {object} errorResponse "task version does not match"
+// @Failure 500 {object} errorResponse "failed to retry task"
+// @Router /tasks/{id}/retry [post]
+func (h *Handlers) retryTask(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid If-Match, must be a task ETag or *", err)
+		return
+	}
+
+	task, err := h.taskUsecase.RetryTask(c.Request.Context(), id, version)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			newErrorResponse(c, log, http.StatusPreconditionFailed, "task version does not match", err)
+			return
+		}
+		if errors.Is(err, domain.ErrTaskNotRetryable) {
+			newErrorResponse(c, log, http.StatusConflict, "task is not failed or cancelled", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to retry task", err)
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, retryTaskResponse{
+		Task: task,
+	})
+}
+
+// StreamTaskEvents godoc
+// @Summary Stream task events
+// @Description Server-Sent Events stream of the task. The first event is snapshot with the current task, then every transition and progress update follows,
+// @Description the stream is closed after the terminal event. Idle stream sends keep-alive comments. Every event data is a task event JSON
+// @Tags tasks
+// @Produce text/event-stream
+// @Param id path string true "Task ID" format(uuid)
+// @Success 200 {object} domain.TaskEvent "event stream"
+// @Failure 400 {object} errorResponse "invalid task ID"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 500 {object} errorResponse "failed to watch task"
+// @Router /tasks/{id}/events [get]
+func (h *Handlers) streamTaskEvents(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, err := h.taskUsecase.WatchTask(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to watch task", err)
+		return
+	}
+
+	stream := newStreamWriter(c, h.writeTimeout, "text/event-stream")
+	keepAlive := time.NewTicker(h.keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			err = stream.write(func() error {
+				_, err := fmt.Fprint(c.Writer, ": keep-alive\n\n")
+				return err
+			})
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			err = stream.write(func() error {
+				return writeSSEEvent(c.Writer, event)
+			})
+		}
+		if err != nil {
+			log.Debug("Task events stream stopped", slog.String("reason", err.Error()))
+			return
+		}
+	}
+}
+
+func writeSSEEvent(w gin.ResponseWriter, event domain.TaskEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readSSEEvents reads event types and data of the stream until it is closed
+func readSSEEvents(t *testing.T, body *bufio.Reader, stop func(eventType string) bool) ([]string, []domain.TaskEvent) {
+	t.Helper()
+
+	var types []string
+	var events []domain.TaskEvent
+	var eventType string
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			return types, events
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var event domain.TaskEvent
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+			types = append(types, eventType)
+			events = append(events, event)
+			if stop != nil && stop(eventType) {
+				return types, events
+			}
+		case strings.HasPrefix(line, ":"):
+			types = append(types, "comment")
+			if stop != nil && stop("comment") {
+				return types, events
+			}
+		}
+	}
+}
+
+func postJSON(t *testing.T, url string, body any) *http.Response {
+	t.Helper()
+
+	jsonBody, _ := json.Marshal(body)
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+// report task progress tests
+
+func TestReportTaskProgress_OK(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := deps.repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	jsonBody, _ := json.Marshal(map[string]int{"progress": 40})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/progress", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response reportTaskProgressResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 40, response.State.Progress)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	if assert.Len(t, deps.publisher.events, 1) {
+		assert.Equal(t, domain.EventTaskProgress, deps.publisher.events[0].Type)
+	}
+}
+
+func TestReportTaskProgress_OutOfRange(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	jsonBody, _ := json.Marshal(map[string]int{"progress": 101})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/progress", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReportTaskProgress_VersionMismatch(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	jsonBody, _ := json.Marshal(map[string]int{"progress": 40})
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/progress", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	task, err := repo.GetTaskByID(t.Context(), taskID)
+	assert.NoError(t, err)
+	assert.Zero(t, task.TaskState.Progress)
+	assert.Equal(t, int64(1), task.Version)
+}
+
+// retry task tests
+
+func TestRetryTask_OK(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	taskID, err := handlers.taskUsecase.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+	require.NoError(t, err)
+	_, err = handlers.taskUsecase.FinishTask(t.Context(), taskID, domain.StatusFailed, json.RawMessage(`{"error":"timeout"}`), "", domain.AnyVersion)
+	require.NoError(t, err)
+
+	// request
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID.String()+"/retry", nil)
+	req.Header.Set("If-Match", `"2"`)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	var response retryTaskResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusInProgress, response.Task.TaskState.Status)
+	assert.Equal(t, 2, response.Task.Attempt)
+	assert.Nil(t, response.Task.Result)
+	assert.Nil(t, response.Task.CompletedAt)
+
+	if assert.NotEmpty(t, deps.publisher.events) {
+		assert.Equal(t, domain.EventTaskRetried, deps.publisher.events[len(deps.publisher.events)-1].Type)
+	}
+
+	// the history rebuilds the retried task
+	history, err := deps.repo.ReadTaskHistory(t.Context(), taskID)
+	require.NoError(t, err)
+	state, err := domain.ReplayTaskHistory(history)
+	require.NoError(t, err)
+	assert.Empty(t, state.Mismatch(response.Task))
+}
+
+func TestRetryTask_NotRetryable(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	running, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "running", TaskState: domain.TaskState{Status: domain.StatusInProgress}, Attempt: 1})
+	completed, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "completed", TaskState: domain.TaskState{Status: domain.StatusCompleted}, Attempt: 1})
+
+	for _, id := range []string{running.String(), completed.String()} {
+		// request
+		req, _ := http.NewRequest("POST", "/tasks/"+id+"/retry", nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// response check
+		assert.Equal(t, http.StatusConflict, w.Code)
+	}
+
+	task, err := repo.GetTaskByID(t.Context(), completed)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, task.Attempt)
+}
+
+// stream task events tests
+
+func TestStreamTaskEvents_UntilFinished(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	server := httptest.NewServer(setupTestRouter(handlers))
+	defer server.Close()
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	resp, err := http.Get(server.URL + "/tasks/" + taskID.String() + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	body := bufio.NewReader(resp.Body)
+	types, events := readSSEEvents(t, body, func(eventType string) bool { return eventType == string(domain.EventTaskSnapshot) })
+	require.Equal(t, []string{string(domain.EventTaskSnapshot)}, types)
+	assert.Equal(t, taskID, events[0].Task.ID)
+
+	// executor reports progress and finishes the task
+	postJSON(t, server.URL+"/tasks/"+taskID.String()+"/progress", map[string]int{"progress": 50})
+	postJSON(t, server.URL+"/tasks/"+taskID.String()+"/finish", finishTaskRequest{Status: domain.StatusCompleted})
+
+	// stream is closed by the server after the terminal event
+	types, events = readSSEEvents(t, body, nil)
+	assert.Equal(t, []string{string(domain.EventTaskProgress), string(domain.EventTaskCompleted)}, types)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, 50, events[0].Task.TaskState.Progress)
+		assert.Equal(t, domain.StatusCompleted, events[1].Task.TaskState.Status)
+	}
+}
+
+func TestStreamTaskEvents_SkipsSentVersions(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	server := httptest.NewServer(setupTestRouter(handlers))
+	defer server.Close()
+
+	task := domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}}
+	taskID, _ := deps.repo.CreateTask(t.Context(), &task)
+
+	// request
+	resp, err := http.Get(server.URL + "/tasks/" + taskID.String() + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := bufio.NewReader(resp.Body)
+	types, _ := readSSEEvents(t, body, func(eventType string) bool { return eventType == string(domain.EventTaskSnapshot) })
+	require.Equal(t, []string{string(domain.EventTaskSnapshot)}, types)
+
+	// outbox relay publishes the events of the snapshot and the progress again, after the progress
+	postJSON(t, server.URL+"/tasks/"+taskID.String()+"/progress", map[string]int{"progress": 50})
+	deps.publisher.Bus.Publish(domain.NewTaskEvent(domain.EventTaskStarted, task))
+	deps.publisher.Bus.Publish(deps.publisher.events[0])
+	postJSON(t, server.URL+"/tasks/"+taskID.String()+"/finish", finishTaskRequest{Status: domain.StatusCompleted})
+
+	// response check
+	types, events := readSSEEvents(t, body, nil)
+	assert.Equal(t, []string{string(domain.EventTaskProgress), string(domain.EventTaskCompleted)}, types)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, int64(2), events[0].Task.Version)
+		assert.Equal(t, int64(3), events[1].Task.Version)
+	}
+}
+
+func TestStreamTaskEvents_HardDeleted(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	server := httptest.NewServer(setupTestRouter(handlers))
+	defer server.Close()
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	resp, err := http.Get(server.URL + "/tasks/" + taskID.String() + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := bufio.NewReader(resp.Body)
+	readSSEEvents(t, body, func(eventType string) bool { return eventType == string(domain.EventTaskSnapshot) })
+
+	// purge keeps the version of the snapshot, it still ends the stream
+	req, _ := http.NewRequest("DELETE", server.URL+"/tasks/"+taskID.String()+"?hard=true", nil)
+	deleteResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	deleteResp.Body.Close()
+	require.Equal(t, http.StatusOK, deleteResp.StatusCode)
+
+	// response check
+	types, events := readSSEEvents(t, body, nil)
+	assert.Equal(t, []string{string(domain.EventTaskPurged)}, types)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, int64(1), events[0].Task.Version)
+	}
+}
+
+func TestStreamTaskEvents_KeepAlive(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	handlers.keepAliveInterval = 10 * time.Millisecond
+	server := httptest.NewServer(setupTestRouter(handlers))
+	defer server.Close()
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	resp, err := http.Get(server.URL + "/tasks/" + taskID.String() + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	types, _ := readSSEEvents(t, bufio.NewReader(resp.Body), func(eventType string) bool { return eventType == "comment" })
+	assert.Equal(t, []string{string(domain.EventTaskSnapshot), "comment"}, types)
+}
+
+func TestStreamTaskEvents_FinishedTask(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusFailed}})
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/events", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	types, events := readSSEEvents(t, bufio.NewReader(w.Body), nil)
+	assert.Equal(t, []string{string(domain.EventTaskSnapshot)}, types)
+	assert.Equal(t, domain.StatusFailed, events[0].Task.TaskState.Status)
+}
+
+func TestStreamTaskEvents_NotFound(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/8d1b5c5e-9a7f-4d2b-a1c3-3f0e2b9d6a11/events", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,20 +1,27 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/pkg/logger/sl"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type createTaskRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
+	Title       string   `json:"title" binding:"required"`
+	Description string   `json:"description"`
+	Type        string   `json:"type" example:"report"`
+	Labels      []string `json:"labels" example:"billing,nightly"`
+	CallbackURL string   `json:"callback_url" example:"https://example.com/hooks/tasks"`
 }
 
 type createTaskResponse struct {
@@ -23,11 +30,11 @@ type createTaskResponse struct {
 
 // CreateTask godoc
 // @Summary Create a new task
-// @Description Creates a new task with the specified title and description
+// @Description Creates a new task with the specified title and description, if callback url is set the final task is posted there once the task is finished
 // @Tags tasks
 // @Accept json
 // @Produce json
-// @Param task body createTaskRequest true "Task title and description"
+// @Param task body createTaskRequest true "Task title, description and optional type, labels and callback url"
 // @Success 201 {object} createTaskResponse "task created successfully with id {task_id}"
 // @Failure 400 {object} errorResponse "invalid request body"
 // @Failure 500 {object} errorResponse "failed to create task"
@@ -44,12 +51,16 @@ func (h *Handlers) createTask(c *gin.Context) {
 		return
 	}
 
-	taskID, err := h.taskUsecase.CreateTask(&domain.Task{
+	taskID, err := h.taskUsecase.CreateTask(c.Request.Context(), &domain.Task{
 		Title:       req.Title,
 		Description: req.Description,
+		Type:        req.Type,
+		Labels:      req.Labels,
+		CallbackURL: req.CallbackURL,
 	})
 	if err != nil {
-		if errors.Is(err, domain.ErrTitleEmpty) || errors.Is(err, domain.ErrTitleTooLong) || errors.Is(err, domain.ErrDescriptionTooLong) {
+		if errors.Is(err, domain.ErrTitleEmpty) || errors.Is(err, domain.ErrTitleTooLong) || errors.Is(err, domain.ErrDescriptionTooLong) ||
+			errors.Is(err, domain.ErrTypeTooLong) || errors.Is(err, domain.ErrInvalidLabel) || errors.Is(err, domain.ErrInvalidCallbackURL) {
 			newErrorResponse(c, log, http.StatusBadRequest, "invalid request body: "+err.Error(), err)
 			return
 		}
@@ -62,20 +73,125 @@ func (h *Handlers) createTask(c *gin.Context) {
 	})
 }
 
+// ListTasks godoc
+// @Summary List tasks
+// @Description Returns a page of tasks that match the filters, tasks in the trash are not listed. Comma separated lists match tasks with any of the values,
+// @Description time ranges include their from and exclude their to. Pass next_cursor of a page as cursor with the same query to get the next page
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param status query string false "Comma separated statuses" example(in_progress,failed)
+// @Param type query string false "Comma separated task types" example(report)
+// @Param label query string false "Comma separated labels, tasks with at least one of them match" example(billing,nightly)
+// @Param created_from query string false "Only tasks created at or after the RFC3339 time"
+// @Param created_to query string false "Only tasks created before the RFC3339 time"
+// @Param updated_from query string false "Only tasks updated at or after the RFC3339 time"
+// @Param updated_to query string false "Only tasks updated before the RFC3339 time"
+// @Param sort query string false "Order of tasks, a leading minus lists the latest first" Enums(created_at, -created_at, updated_at, -updated_at) default(-created_at)
+// @Param limit query int false "Page size, up to 200" default(50)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} domain.TaskPage "page of tasks"
+// @Failure 400 {object} errorResponse "invalid query"
+// @Failure 500 {object} errorResponse "failed to list tasks"
+// @Router /tasks [get]
+func (h *Handlers) listTasks(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	query := domain.TaskListQuery{
+		Filter: domain.TaskListFilter{
+			Types:  parseList(c.Query("type")),
+			Labels: parseList(c.Query("label")),
+		},
+		Sort: domain.TaskSort(c.Query("sort")),
+	}
+	var err error
+	if query.Filter.Statuses, err = parseStatuses(c.Query("status")); err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid status: "+err.Error(), err)
+		return
+	}
+	for _, param := range []struct {
+		name string
+		time *time.Time
+	}{
+		{"created_from", &query.Filter.CreatedFrom},
+		{"created_to", &query.Filter.CreatedTo},
+		{"updated_from", &query.Filter.UpdatedFrom},
+		{"updated_to", &query.Filter.UpdatedTo},
+	} {
+		if raw := c.Query(param.name); raw != "" {
+			if *param.time, err = time.Parse(time.RFC3339, raw); err != nil {
+				newErrorResponse(c, log, http.StatusBadRequest, "invalid "+param.name+", must be RFC3339 time", err)
+				return
+			}
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		query.Limit, err = strconv.Atoi(raw)
+		if err == nil && query.Limit < 1 {
+			err = fmt.Errorf("limit %d is out of range", query.Limit)
+		}
+		if err != nil {
+			newErrorResponse(c, log, http.StatusBadRequest, fmt.Sprintf("invalid limit, must be between 1 and %d", domain.MaxTaskPageSize), err)
+			return
+		}
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := domain.ParseTaskCursor(raw)
+		if err != nil {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid cursor", err)
+			return
+		}
+		query.After = &cursor
+	}
+
+	page, err := h.taskUsecase.ListTasks(c.Request.Context(), query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidSort) || errors.Is(err, domain.ErrInvalidPageSize) || errors.Is(err, domain.ErrInvalidCursor) ||
+			errors.Is(err, domain.ErrInvalidStatus) {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid query: "+err.Error(), err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to list tasks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseList splits a comma separated query value, empty items are dropped
+func parseList(raw string) []string {
+	var values []string
+	for _, part := range strings.Split(raw, ",") {
+		if value := strings.TrimSpace(part); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 type deleteTaskResponse struct {
 	Message string `json:"message" example:"task deleted successfully"`
 }
 
 // DeleteTask godoc
 // @Summary Delete task by ID
-// @Description Deletes a task with the specified ID
+// @Description Moves the task with the specified ID to the trash, it is hidden from reads and purged for good once the trash grace period is over.
+// @Description With hard=true the task is deleted for good right away, which requires the admin token. With If-Match the task is deleted only if its ETag still matches
 // @Tags tasks
 // @Accept json
 // @Produce json
 // @Param id path string true "Task ID" format(uuid)
-// @Success 200 {object} deleteTaskResponse "task deleted successfully"
-// @Failure 400 {object} errorResponse "invalid task ID"
+// @Param hard query bool false "Delete the task for good instead of moving it to the trash, admin only"
+// @Param If-Match header string false "ETag of the task version the change is based on, * matches any version"
+// @Success 200 {object} deleteTaskResponse "task moved to trash or deleted for good"
+// @Failure 400 {object} errorResponse "invalid task ID, hard or If-Match"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
 // @Failure 404 {object} errorResponse "task not found"
+// @Failure 412 {object} errorResponse "task version does not match"
 // @Failure 500 {object} errorResponse "failed to delete task"
 // @Router /tasks/{id} [delete]
 func (h *Handlers) deleteTask(c *gin.Context) {
@@ -92,33 +208,66 @@ func (h *Handlers) deleteTask(c *gin.Context) {
 		return
 	}
 
-	err = h.taskUsecase.DeleteTask(uuid)
+	hard := false
+	if raw := c.Query("hard"); raw != "" {
+		hard, err = strconv.ParseBool(raw)
+		if err != nil {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid hard, must be true or false", err)
+			return
+		}
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid If-Match, must be a task ETag or *", err)
+		return
+	}
+
+	message := "task deleted successfully"
+	if hard {
+		message = "task deleted for good"
+		err = h.taskUsecase.PurgeTask(c.Request.Context(), uuid, version)
+	} else {
+		err = h.taskUsecase.DeleteTask(c.Request.Context(), uuid, version)
+	}
 	if err != nil {
 		if errors.Is(err, domain.ErrTaskNotFound) {
 			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
 			return
 		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			newErrorResponse(c, log, http.StatusPreconditionFailed, "task version does not match", err)
+			return
+		}
 		newErrorResponse(c, log, http.StatusInternalServerError, "failed to delete task", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, deleteTaskResponse{
-		Message: "task deleted successfully",
+		Message: message,
 	})
 }
 
+// isHardDelete reports whether the delete request asks to skip the trash, invalid values are rejected by the handler
+func isHardDelete(c *gin.Context) bool {
+	hard, err := strconv.ParseBool(c.Query("hard"))
+	return err == nil && hard
+}
+
 type getTaskResultResponse struct {
 	Message string `json:"message" example:"task result: completed"`
 }
 
 // GetTaskResultByID godoc
 // @Summary Get task result by ID
-// @Description Returns the result of task execution
+// @Description Returns the result of task execution as text, string results are unquoted and other JSON values are inlined as is. Use /v2/tasks/{id}/result for the structured result.
+// @Description Results kept in the result store are not inlined and have to be downloaded from /tasks/{id}/result/content
 // @Tags tasks
 // @Accept json
 // @Produce json
 // @Param id path string true "Task ID" format(uuid)
 // @Success 200 {object} getTaskResultResponse "task result: {task_result}"
+// @Header 200 {string} ETag "task version"
 // @Failure 400 {object} errorResponse "invalid task ID"
 // @Failure 404 {object} errorResponse "task not found"
 // @Failure 500 {object} errorResponse "failed to get task result"
@@ -137,7 +286,7 @@ func (h *Handlers) getTaskResultByID(c *gin.Context) {
 		return
 	}
 
-	result, err := h.taskUsecase.GetTaskResultByID(uuid)
+	result, err := h.taskUsecase.GetTaskResultByID(c.Request.Context(), uuid)
 	if err != nil {
 		if errors.Is(err, domain.ErrTaskNotFound) {
 			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
@@ -147,25 +296,157 @@ func (h *Handlers) getTaskResultByID(c *gin.Context) {
 		return
 	}
 
+	setETag(c, result.Version)
+	if result.Blob != nil {
+		c.JSON(http.StatusOK, getTaskResultResponse{
+			Message: fmt.Sprintf("task result: stored in result store (%d bytes), download it from /api/v1/tasks/%s/result/content", result.Blob.Size, uuid),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, getTaskResultResponse{
-		Message: fmt.Sprintf("task result: %s", result),
+		Message: fmt.Sprintf("task result: %s", result.Text()),
 	})
 }
 
+type getTaskResultV2Response struct {
+	Result      json.RawMessage `json:"result" swaggertype:"object"`
+	ContentType string          `json:"content_type,omitempty" example:"application/json"`
+	CompletedAt *time.Time      `json:"completed_at" example:"2025-06-28T01:35:19.1864825+03:00"`
+	ContentURL  string          `json:"content_url,omitempty" example:"/api/v1/tasks/6bcd175e-cba9-4ba6-b6ef-f3ac37864118/result/content"`
+}
+
+// GetTaskResultByIDV2 godoc
+// @Summary Get structured task result by ID
+// @Description Returns the JSON result of the task with its content type and completion time, result and completed_at are null until the task is finished.
+// @Description Results kept in the result store are not inlined, they have to be downloaded from content_url
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID" format(uuid)
+// @Success 200 {object} getTaskResultV2Response "task result"
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} errorResponse "invalid task ID"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 500 {object} errorResponse "failed to get task result"
+// @Router /v2/tasks/{id}/result [get]
+func (h *Handlers) getTaskResultByIDV2(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	result, err := h.taskUsecase.GetTaskResultByID(c.Request.Context(), uuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to get task result", err)
+		return
+	}
+
+	response := getTaskResultV2Response{
+		Result:      result.Content,
+		ContentType: result.ContentType,
+		CompletedAt: result.CompletedAt,
+	}
+	if len(response.Result) == 0 {
+		response.Result = json.RawMessage("null")
+	}
+	if result.Blob != nil {
+		response.ContentURL = fmt.Sprintf("/api/v1/tasks/%s/result/content", uuid)
+	}
+
+	setETag(c, result.Version)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTaskResultContent godoc
+// @Summary Download task result
+// @Description Streams the raw result of the finished task with its content type, supports Range and conditional requests
+// @Tags tasks
+// @Produce octet-stream
+// @Param id path string true "Task ID" format(uuid)
+// @Param Range header string false "Byte range, e.g. bytes=0-1023"
+// @Success 200 {file} file "task result"
+// @Success 206 {file} file "requested part of task result"
+// @Header 200,206 {string} ETag "task version"
+// @Failure 400 {object} errorResponse "invalid task ID"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 409 {object} errorResponse "task is not finished"
+// @Failure 416 {string} string "requested range not satisfiable"
+// @Failure 500 {object} errorResponse "failed to get task result"
+// @Router /tasks/{id}/result/content [get]
+func (h *Handlers) getTaskResultContent(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	content, err := h.taskUsecase.OpenTaskResult(c.Request.Context(), uuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		if errors.Is(err, domain.ErrTaskNotFinished) {
+			newErrorResponse(c, log, http.StatusConflict, "task is not finished", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to get task result", err)
+		return
+	}
+	defer content.Close()
+
+	// stored results may be large, server write timeout must not cut the download
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Debug("failed to clear write deadline", sl.Err(err))
+	}
+
+	// ServeContent handles Range, conditional headers and Content-Length, content type is set up front so it is not sniffed
+	c.Header("Content-Type", content.ContentType)
+	setETag(c, content.Version)
+	http.ServeContent(c.Writer, c.Request, "", content.ModTime, content)
+}
+
 type getTaskStateResponse struct {
 	State     domain.TaskState `json:"state"`
 	CreatedAt time.Time        `json:"created_at" example:"2025-06-28T01:31:19.1864825+03:00"`
 }
 
+// maxStateWait limits how long a single state request may wait for the task
+const maxStateWait = time.Minute
+
 // GetTaskStateByID godoc
 // @Summary Get task state by ID
-// @Description Returns the current state (status, work duration in nanoseconds) of the task and its creation time
+// @Description Returns the current state (status, work duration in nanoseconds) of the task and its creation time.
+// @Description With wait the request blocks until the task reaches one of the until statuses (terminal statuses by default) or the wait expires, and returns the current state either way
 // @Tags tasks
 // @Accept json
 // @Produce json
 // @Param id path string true "Task ID" format(uuid)
+// @Param wait query string false "How long to wait, Go duration up to 1m" example(30s)
+// @Param until query string false "Comma separated statuses to wait for" example(completed,failed)
 // @Success 200 {object} getTaskStateResponse "task state: {state}, created at: {created_at}"
-// @Failure 400 {object} errorResponse "invalid task ID"
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} errorResponse "invalid task ID, wait or until"
 // @Failure 404 {object} errorResponse "task not found"
 // @Failure 500 {object} errorResponse "failed to get task state"
 // @Router /tasks/{id}/state [get]
@@ -183,8 +464,38 @@ func (h *Handlers) getTaskStateByID(c *gin.Context) {
 		return
 	}
 
-	state, createdAt, err := h.taskUsecase.GetTaskStateByID(uuid)
+	var wait time.Duration
+	if raw := c.Query("wait"); raw != "" {
+		wait, err = time.ParseDuration(raw)
+		if err == nil && (wait <= 0 || wait > maxStateWait) {
+			err = fmt.Errorf("wait %s is out of range", wait)
+		}
+		if err != nil {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid wait, must be a positive duration up to "+maxStateWait.String(), err)
+			return
+		}
+	}
+
+	until, err := parseStatuses(c.Query("until"))
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid until: "+err.Error(), err)
+		return
+	}
+	if len(until) == 0 {
+		until = []domain.TaskStatus{domain.StatusCompleted, domain.StatusFailed, domain.StatusCancelled}
+	}
+
+	var task domain.Task
+	if wait > 0 {
+		task, err = h.taskUsecase.WaitTaskState(c.Request.Context(), uuid, until, wait)
+	} else {
+		task, err = h.taskUsecase.GetTaskByID(c.Request.Context(), uuid)
+	}
 	if err != nil {
+		if c.Request.Context().Err() != nil {
+			log.Debug("Client left while waiting for task state", slog.String("task_id", uuid.String()))
+			return
+		}
 		if errors.Is(err, domain.ErrTaskNotFound) {
 			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
 			return
@@ -193,8 +504,154 @@ func (h *Handlers) getTaskStateByID(c *gin.Context) {
 		return
 	}
 
+	// waiting could take longer than the server write timeout
+	extendWriteDeadline(c, h.writeTimeout)
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, getTaskStateResponse{
-		State:     state,
-		CreatedAt: createdAt,
+		State:     task.TaskState,
+		CreatedAt: task.CreatedAt,
+	})
+}
+
+func parseStatuses(raw string) ([]domain.TaskStatus, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var statuses []domain.TaskStatus
+	for _, part := range strings.Split(raw, ",") {
+		status := domain.TaskStatus(strings.TrimSpace(part))
+		if !status.IsValid() {
+			return nil, fmt.Errorf("%w: %s", domain.ErrInvalidStatus, status)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+type finishTaskRequest struct {
+	Status      domain.TaskStatus `json:"status" binding:"required" example:"completed"`
+	Result      json.RawMessage   `json:"result" swaggertype:"object"`
+	ContentType string            `json:"content_type" example:"application/json"`
+}
+
+type finishTaskResponse struct {
+	Message string `json:"message" example:"task finished successfully"`
+}
+
+// FinishTask godoc
+// @Summary Finish task by ID
+// @Description Moves the task to a terminal status (completed, failed or cancelled) and stores its JSON result, the result is checked against the schema of the task type if there is one, the final task is posted to its callback url if it is set.
+// @Description With If-Match the task is finished only if its ETag still matches
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID" format(uuid)
+// @Param If-Match header string false "ETag of the task version the change is based on, * matches any version"
+// @Param task body finishTaskRequest true "Terminal status, JSON result and its media type (application/json by default)"
+// @Success 200 {object} finishTaskResponse "task finished successfully"
+// @Header 200 {string} ETag "new task version"
+// @Failure 400 {object} errorResponse "invalid task ID, If-Match or request body"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 409 {object} errorResponse "task is already finished"
+// @Failure 412 {object} errorResponse "task version does not match"
+// @Failure 500 {object} errorResponse "failed to finish task"
+// @Router /tasks/{id}/finish [post]
+func (h *Handlers) finishTask(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	var req finishTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid If-Match, must be a task ETag or *", err)
+		return
+	}
+
+	task, err := h.taskUsecase.FinishTask(c.Request.Context(), uuid, req.Status, req.Result, req.ContentType, version)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidStatus) || errors.Is(err, domain.ErrInvalidResult) || errors.Is(err, domain.ErrResultSchemaViolation) {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid request body: "+err.Error(), err)
+			return
+		}
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			newErrorResponse(c, log, http.StatusPreconditionFailed, "task version does not match", err)
+			return
+		}
+		if errors.Is(err, domain.ErrTaskAlreadyFinished) {
+			newErrorResponse(c, log, http.StatusConflict, "task is already finished", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to finish task", err)
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, finishTaskResponse{
+		Message: "task finished successfully",
+	})
+}
+
+type getTaskDeliveriesResponse struct {
+	Deliveries []domain.Delivery `json:"deliveries"`
+}
+
+// GetTaskDeliveries godoc
+// @Summary Get callback deliveries of the task
+// @Description Returns the latest attempts to deliver the finished task to its callback url made by this node, see DELIVERY_LOG_MAX_ATTEMPTS and DELIVERY_LOG_RETENTION
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID" format(uuid)
+// @Success 200 {object} getTaskDeliveriesResponse "delivery attempts"
+// @Failure 400 {object} errorResponse "invalid task ID"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 500 {object} errorResponse "failed to get task deliveries"
+// @Router /tasks/{id}/deliveries [get]
+func (h *Handlers) getTaskDeliveries(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	deliveries, err := h.taskUsecase.GetTaskDeliveries(c.Request.Context(), uuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to get task deliveries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getTaskDeliveriesResponse{
+		Deliveries: deliveries,
 	})
 }
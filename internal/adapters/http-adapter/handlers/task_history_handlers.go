@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type getTaskHistoryResponse struct {
+	Events []domain.TaskHistoryEvent `json:"events"`
+}
+
+type checkTaskHistoryResponse struct {
+	Checked    int                          `json:"checked" example:"120"`
+	Mismatches []domain.TaskHistoryMismatch `json:"mismatches"`
+}
+
+// GetTaskHistory godoc
+// @Summary Get task history
+// @Description Returns the append-only history of the task oldest first: created, started, progress, completed, failed, cancelled, deleted and restored events.
+// @Description Every event records who caused it (the X-Actor header of the request, system for changes made by the service) and the task version right after it.
+// @Description History is stored with the task in the same write as the change, imported tasks have none
+// @Tags tasks
+// @Produce json
+// @Param id path string true "Task ID" format(uuid)
+// @Success 200 {object} getTaskHistoryResponse "task history"
+// @Failure 400 {object} errorResponse "invalid task ID"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 500 {object} errorResponse "failed to get task history"
+// @Router /tasks/{id}/history [get]
+func (h *Handlers) getTaskHistory(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	events, err := h.taskHistoryUsecase.GetTaskHistory(c.Request.Context(), uuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to get task history", err)
+		return
+	}
+
+	if events == nil {
+		events = []domain.TaskHistoryEvent{}
+	}
+	c.JSON(http.StatusOK, getTaskHistoryResponse{
+		Events: events,
+	})
+}
+
+// CheckTaskHistory godoc
+// @Summary Check task history
+// @Description Rebuilds every task with a history from its events and compares the result with the stored task.
+// @Description Tasks whose history is invalid or does not match the stored status, progress, attempt, version or trash state are reported, requires the admin token
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} checkTaskHistoryResponse "check report"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 500 {object} errorResponse "failed to check task history"
+// @Router /admin/history/check [get]
+func (h *Handlers) checkTaskHistory(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	checked, mismatches, err := h.taskHistoryUsecase.CheckTaskHistory(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to check task history", err)
+		return
+	}
+
+	if len(mismatches) > 0 {
+		log.Warn("task history does not match stored tasks", slog.Int("checked", checked), slog.Int("mismatches", len(mismatches)))
+	}
+	if mismatches == nil {
+		mismatches = []domain.TaskHistoryMismatch{}
+	}
+	c.JSON(http.StatusOK, checkTaskHistoryResponse{
+		Checked:    checked,
+		Mismatches: mismatches,
+	})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// task history tests
+
+func TestGetTaskHistory_OK(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(`{"title":"Test Task"}`))
+	req.Header.Set("X-Actor", "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created createTaskResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	taskID := strings.TrimPrefix(created.Message, "task created successfully with id ")
+
+	steps := []struct {
+		method, path, actor, body string
+	}{
+		{"POST", "/tasks/" + taskID + "/progress", "executor-7", `{"progress":40}`},
+		{"POST", "/tasks/" + taskID + "/finish", "executor-7", `{"status":"completed"}`},
+		{"DELETE", "/tasks/" + taskID, "", ""},
+		{"POST", "/trash/" + taskID + "/restore", "bob", ""},
+	}
+	for _, step := range steps {
+		req, _ := http.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		if step.actor != "" {
+			req.Header.Set("X-Actor", step.actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, step.path)
+	}
+
+	// request
+	req, _ = http.NewRequest("GET", "/tasks/"+taskID+"/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response getTaskHistoryResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	var types []domain.TaskEventType
+	var actors []string
+	for i, event := range response.Events {
+		assert.Equal(t, int64(i+1), event.Seq)
+		types = append(types, event.Type)
+		actors = append(actors, event.Actor)
+	}
+	assert.Equal(t, []domain.TaskEventType{
+		domain.EventTaskCreated, domain.EventTaskStarted, domain.EventTaskProgress, domain.EventTaskCompleted, domain.EventTaskDeleted, domain.EventTaskRestored,
+	}, types)
+	assert.Equal(t, []string{"alice", "alice", "executor-7", "executor-7", "anonymous", "bob"}, actors)
+	assert.Equal(t, 40, response.Events[2].Progress)
+	assert.Equal(t, int64(5), response.Events[5].Version)
+}
+
+func TestGetTaskHistory_Errors(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// created in the repository directly, so the task has no events
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "no events", id: taskID.String(), wantStatus: http.StatusOK, wantBody: `{"events":[]}`},
+		{name: "unknown task", id: uuid.NewString(), wantStatus: http.StatusNotFound, wantBody: `{"message":"task not found"}`},
+		{name: "invalid id", id: "invalid", wantStatus: http.StatusBadRequest, wantBody: `{"message":"invalid task id"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// request
+			req, _ := http.NewRequest("GET", "/tasks/"+tt.id+"/history", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// response check
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestCheckTaskHistory(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	var ids []string
+	for range 2 {
+		req, _ := http.NewRequest("POST", "/tasks", strings.NewReader(`{"title":"Test Task"}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var created createTaskResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		ids = append(ids, strings.TrimPrefix(created.Message, "task created successfully with id "))
+	}
+
+	check := func() checkTaskHistoryResponse {
+		req, _ := http.NewRequest("GET", "/admin/history/check", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response checkTaskHistoryResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// request
+	response := check()
+
+	// response check
+	assert.Equal(t, 2, response.Checked)
+	assert.Empty(t, response.Mismatches)
+
+	// the task is changed behind the usecase, so no event is published
+	tampered := uuid.MustParse(ids[1])
+	_, err := repo.UpdateTask(t.Context(), tampered, func(task *domain.Task) error {
+		task.TaskState.Progress = 70
+		return nil
+	})
+	require.NoError(t, err)
+
+	// request
+	response = check()
+
+	// response check
+	assert.Equal(t, 2, response.Checked)
+	require.Len(t, response.Mismatches, 1)
+	assert.Equal(t, tampered, response.Mismatches[0].TaskID)
+	assert.Equal(t, "progress 0, stored 70; version 1, stored 2", response.Mismatches[0].Reason)
+
+	// purged tasks are not checked, their history is dropped with them
+	req, _ := http.NewRequest("DELETE", "/tasks/"+ids[1]+"?hard=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	response = check()
+	assert.Equal(t, 1, response.Checked)
+	assert.Empty(t, response.Mismatches)
+}
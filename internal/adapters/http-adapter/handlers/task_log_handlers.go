@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type taskLogLineRequest struct {
+	Level   string    `json:"level" example:"info"`
+	Message string    `json:"message" binding:"required" example:"processed 100 rows"`
+	Time    time.Time `json:"time" example:"2025-06-28T01:31:19.1864825+03:00"`
+}
+
+type appendTaskLogsRequest struct {
+	Attempt int                  `json:"attempt" example:"1"`
+	Lines   []taskLogLineRequest `json:"lines" binding:"required,min=1,dive"`
+}
+
+type appendTaskLogsResponse struct {
+	Appended int   `json:"appended" example:"2"`
+	LastSeq  int64 `json:"last_seq" example:"42"`
+}
+
+type getTaskLogsResponse struct {
+	Lines []domain.TaskLogLine `json:"lines"`
+}
+
+// AppendTaskLogs godoc
+// @Summary Append task log lines
+// @Description Stores output lines of the task executor, lines are tagged with the task ID and the attempt (current attempt of the task by default).
+// @Description Only the latest lines of every task are kept
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID" format(uuid)
+// @Param logs body appendTaskLogsRequest true "Attempt and log lines"
+// @Success 201 {object} appendTaskLogsResponse "lines appended"
+// @Failure 400 {object} errorResponse "invalid task ID or request body"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 500 {object} errorResponse "failed to append task logs"
+// @Router /tasks/{id}/logs [post]
+func (h *Handlers) appendTaskLogs(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	var req appendTaskLogsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	lines := make([]domain.TaskLogLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		lines = append(lines, domain.TaskLogLine{
+			Level:   line.Level,
+			Message: line.Message,
+			Time:    line.Time,
+		})
+	}
+
+	stored, err := h.taskLogUsecase.AppendTaskLogs(c.Request.Context(), uuid, req.Attempt, lines)
+	if err != nil {
+		if errors.Is(err, domain.ErrLogLinesEmpty) || errors.Is(err, domain.ErrInvalidAttempt) {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid request body: "+err.Error(), err)
+			return
+		}
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to append task logs", err)
+		return
+	}
+
+	last := stored[len(stored)-1]
+	// same op as the middleware output, so the request can be matched with the stored lines
+	log.Debug("Task log lines appended", slog.String("task_id", uuid.String()), slog.Int("attempt", last.Attempt), slog.Int("count", len(stored)), slog.Int64("last_seq", last.Seq))
+
+	c.JSON(http.StatusCreated, appendTaskLogsResponse{
+		Appended: len(stored),
+		LastSeq:  last.Seq,
+	})
+}
+
+// GetTaskLogs godoc
+// @Summary Get task log lines
+// @Description Returns stored log lines of the task. since is either the seq of the last seen line or RFC3339 time, only later lines are returned.
+// @Description With follow=true lines are streamed as NDJSON while the task is running, the stream ends when the task is finished
+// @Tags tasks
+// @Produce json
+// @Produce application/x-ndjson
+// @Param id path string true "Task ID" format(uuid)
+// @Param since query string false "Seq of the last seen line or RFC3339 time"
+// @Param follow query bool false "Stream new lines until the task is finished"
+// @Success 200 {object} getTaskLogsResponse "log lines, with follow=true every line is a separate JSON object"
+// @Failure 400 {object} errorResponse "invalid task ID or query"
+// @Failure 404 {object} errorResponse "task not found"
+// @Failure 500 {object} errorResponse "failed to get task logs"
+// @Router /tasks/{id}/logs [get]
+func (h *Handlers) getTaskLogs(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	cursor, err := parseLogCursor(c.Query("since"))
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid since, must be a seq or RFC3339 time", err)
+		return
+	}
+
+	follow := false
+	if raw := c.Query("follow"); raw != "" {
+		follow, err = strconv.ParseBool(raw)
+		if err != nil {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid follow, must be true or false", err)
+			return
+		}
+	}
+
+	if follow {
+		h.followTaskLogs(c, log, uuid, cursor)
+		return
+	}
+
+	lines, err := h.taskLogUsecase.GetTaskLogs(c.Request.Context(), uuid, cursor)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to get task logs", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getTaskLogsResponse{
+		Lines: lines,
+	})
+}
+
+func (h *Handlers) followTaskLogs(c *gin.Context, log *slog.Logger, id uuid.UUID, cursor domain.TaskLogCursor) {
+	stream := newStreamWriter(c, h.writeTimeout, "application/x-ndjson")
+	encoder := json.NewEncoder(c.Writer)
+
+	err := h.taskLogUsecase.FollowTaskLogs(c.Request.Context(), id, cursor, func(lines []domain.TaskLogLine) error {
+		return stream.write(func() error {
+			for _, line := range lines {
+				if err := encoder.Encode(line); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil && !stream.started {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found", err)
+			return
+		}
+		if c.Request.Context().Err() == nil {
+			newErrorResponse(c, log, http.StatusInternalServerError, "failed to get task logs", err)
+			return
+		}
+	}
+	if err != nil {
+		log.Debug("Task logs stream stopped", slog.String("reason", err.Error()))
+		return
+	}
+
+	// task is finished, make sure headers are sent even if it had no lines
+	stream.start()
+}
+
+func parseLogCursor(since string) (domain.TaskLogCursor, error) {
+	if since == "" {
+		return domain.TaskLogCursor{}, nil
+	}
+	if seq, err := strconv.ParseInt(since, 10, 64); err == nil {
+		return domain.TaskLogCursor{SinceSeq: seq}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, since)
+	if err != nil {
+		return domain.TaskLogCursor{}, err
+	}
+	return domain.TaskLogCursor{SinceTime: t}, nil
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendTestLogs(t *testing.T, h *Handlers, taskID string, body appendTaskLogsRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	router := setupTestRouter(h)
+
+	jsonBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/tasks/"+taskID+"/logs", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// append task logs tests
+
+func TestAppendTaskLogs_OK(t *testing.T) {
+	handlers, repo := createTestHandlers()
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", Attempt: 1, TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	w := appendTestLogs(t, handlers, taskID.String(), appendTaskLogsRequest{
+		Lines: []taskLogLineRequest{{Level: "info", Message: "first"}, {Message: "second"}},
+	})
+
+	// response check
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response appendTaskLogsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, response.Appended)
+	assert.Equal(t, int64(2), response.LastSeq)
+}
+
+func TestAppendTaskLogs_InvalidAttempt(t *testing.T) {
+	handlers, repo := createTestHandlers()
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", Attempt: 1, TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	w := appendTestLogs(t, handlers, taskID.String(), appendTaskLogsRequest{
+		Attempt: 2,
+		Lines:   []taskLogLineRequest{{Message: "from the future"}},
+	})
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Message, "invalid attempt")
+}
+
+// get task logs tests
+
+func TestGetTaskLogs_Since(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", Attempt: 1, TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	w := appendTestLogs(t, handlers, taskID.String(), appendTaskLogsRequest{
+		Lines: []taskLogLineRequest{{Message: "first"}, {Message: "second"}, {Message: "third"}},
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/logs?since=1", nil)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response getTaskLogsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	if assert.Len(t, response.Lines, 2) {
+		assert.Equal(t, "second", response.Lines[0].Message)
+		assert.Equal(t, int64(3), response.Lines[1].Seq)
+		assert.Equal(t, 1, response.Lines[1].Attempt)
+	}
+}
+
+func TestGetTaskLogs_InvalidSince(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", Attempt: 1, TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/logs?since=yesterday", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetTaskLogs_FollowUntilFinished(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", Attempt: 1, TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	w := appendTestLogs(t, handlers, taskID.String(), appendTaskLogsRequest{Lines: []taskLogLineRequest{{Message: "first"}}})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// executor writes one more line and finishes the task while the client follows
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		appendTestLogs(t, handlers, taskID.String(), appendTaskLogsRequest{Lines: []taskLogLineRequest{{Message: "last"}}})
+		_, _ = repo.UpdateTask(t.Context(), taskID, func(task *domain.Task) error {
+			task.TaskState.Status = domain.StatusCompleted
+			return nil
+		})
+	}()
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/"+taskID.String()+"/logs?follow=true", nil)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	var messages []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var line domain.TaskLogLine
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		messages = append(messages, line.Message)
+	}
+	assert.Equal(t, []string{"first", "last"}, messages)
+}
+
+func TestGetTaskLogs_FollowNotFound(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("GET", "/tasks/8d1b5c5e-9a7f-4d2b-a1c3-3f0e2b9d6a11/logs?follow=true", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
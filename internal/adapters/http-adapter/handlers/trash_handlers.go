@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type listTrashResponse struct {
+	Tasks []domain.Task `json:"tasks"`
+}
+
+// ListTrash godoc
+// @Summary List deleted tasks
+// @Description Returns tasks in the trash, earliest deleted first. They are purged for good once the trash grace period is over
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Success 200 {object} listTrashResponse "deleted tasks"
+// @Failure 500 {object} errorResponse "failed to list deleted tasks"
+// @Router /trash [get]
+func (h *Handlers) listTrash(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	tasks, err := h.taskUsecase.ListDeletedTasks(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to list deleted tasks", err)
+		return
+	}
+	if tasks == nil {
+		tasks = []domain.Task{}
+	}
+
+	c.JSON(http.StatusOK, listTrashResponse{
+		Tasks: tasks,
+	})
+}
+
+type restoreTaskResponse struct {
+	Task domain.Task `json:"task"`
+}
+
+// RestoreTask godoc
+// @Summary Restore deleted task
+// @Description Takes the task out of the trash, with If-Match the task is restored only if its ETag still matches
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID" format(uuid)
+// @Param If-Match header string false "ETag of the task version the change is based on, * matches any version"
+// @Success 200 {object} restoreTaskResponse "restored task"
+// @Header 200 {string} ETag "new task version"
+// @Failure 400 {object} errorResponse "invalid task ID or If-Match"
+// @Failure 404 {object} errorResponse "task not found in trash"
+// @Failure 412 {object} errorResponse "task version does not match"
+// @Failure 500 {object} errorResponse "failed to restore task"
+// @Router /trash/{id}/restore [post]
+func (h *Handlers) restoreTask(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid task id", err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid If-Match, must be a task ETag or *", err)
+		return
+	}
+
+	task, err := h.taskUsecase.RestoreTask(c.Request.Context(), id, version)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) || errors.Is(err, domain.ErrTaskNotInTrash) {
+			newErrorResponse(c, log, http.StatusNotFound, "task not found in trash", err)
+			return
+		}
+		if errors.Is(err, domain.ErrVersionMismatch) {
+			newErrorResponse(c, log, http.StatusPreconditionFailed, "task version does not match", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to restore task", err)
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, restoreTaskResponse{
+		Task: task,
+	})
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/adapters/http-adapter/handlers/middleware"
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trash tests
+
+func TestTrash_ListAndRestore(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	kept, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "kept"})
+	deleted, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "deleted"})
+
+	req, _ := http.NewRequest("DELETE", "/tasks/"+deleted.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// request
+	req, _ = http.NewRequest("GET", "/trash", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var trash listTrashResponse
+	err := json.Unmarshal(w.Body.Bytes(), &trash)
+	assert.NoError(t, err)
+	require.Len(t, trash.Tasks, 1)
+	assert.Equal(t, deleted, trash.Tasks[0].ID)
+	assert.NotNil(t, trash.Tasks[0].DeletedAt)
+	assert.NotEqual(t, kept, trash.Tasks[0].ID)
+
+	// request
+	req, _ = http.NewRequest("POST", "/trash/"+deleted.String()+"/restore", nil)
+	req.Header.Set("If-Match", `"2"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	var restored restoreTaskResponse
+	err = json.Unmarshal(w.Body.Bytes(), &restored)
+	assert.NoError(t, err)
+	assert.Equal(t, deleted, restored.Task.ID)
+	assert.Nil(t, restored.Task.DeletedAt)
+
+	req, _ = http.NewRequest("GET", "/tasks/"+deleted.String()+"/state", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, "restored task is visible again")
+
+	req, _ = http.NewRequest("GET", "/trash", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"tasks":[]}`, w.Body.String())
+}
+
+func TestTrash_RestoreNotInTrash(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+
+	for _, id := range []uuid.UUID{taskID, uuid.New()} {
+		// request
+		req, _ := http.NewRequest("POST", "/trash/"+id.String()+"/restore", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// response check
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var response errorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "task not found in trash", response.Message)
+	}
+}
+
+func TestTrash_HiddenFromUpdates(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, handlers.taskUsecase.DeleteTask(t.Context(), taskID, domain.AnyVersion))
+
+	requests := map[string]string{
+		"/tasks/" + taskID.String() + "/progress": `{"progress":10}`,
+		"/tasks/" + taskID.String() + "/finish":   `{"status":"completed"}`,
+	}
+	for path, body := range requests {
+		// request
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// response check
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
+
+func TestDeleteTask_Hard(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		authorization string
+		status        int
+		purged        bool
+	}{
+		{name: "admin", query: "?hard=true", authorization: "Bearer secret", status: http.StatusOK, purged: true},
+		{name: "not admin", query: "?hard=true", status: http.StatusUnauthorized},
+		{name: "soft without token", query: "?hard=false", status: http.StatusOK},
+		{name: "invalid", query: "?hard=maybe", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, repo := createTestHandlers()
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("op", "test")
+				c.Next()
+			})
+			router.DELETE("/tasks/:id", middleware.AdminAuthWhen("secret", isHardDelete), handlers.deleteTask)
+
+			taskID, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+
+			// request
+			req, _ := http.NewRequest("DELETE", "/tasks/"+taskID.String()+tt.query, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// response check
+			assert.Equal(t, tt.status, w.Code)
+
+			_, err := repo.GetTaskByID(t.Context(), taskID)
+			if tt.purged {
+				assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+			} else {
+				assert.NoError(t, err, "task is kept")
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedTasks(t *testing.T) {
+	handlers, repo := createTestHandlers()
+	tasks := handlers.taskUsecase.(*usecase.TaskUsecase)
+
+	expired, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "expired"})
+	recent, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "recent"})
+	restored, _ := repo.CreateTask(t.Context(), &domain.Task{Title: "restored"})
+	for _, id := range []uuid.UUID{expired, restored} {
+		require.NoError(t, tasks.DeleteTask(t.Context(), id, domain.AnyVersion))
+	}
+	_, err := tasks.RestoreTask(t.Context(), restored, domain.AnyVersion)
+	require.NoError(t, err)
+	deadline := time.Now()
+	require.NoError(t, tasks.DeleteTask(t.Context(), recent, domain.AnyVersion))
+
+	purged, err := tasks.PurgeDeletedTasks(t.Context(), deadline)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	_, err = repo.GetTaskByID(t.Context(), expired)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	for _, id := range []uuid.UUID{recent, restored} {
+		_, err = repo.GetTaskByID(t.Context(), id)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type subscriptionRequest struct {
+	URL    string                 `json:"url" binding:"required" example:"https://example.com/hooks/tasks"`
+	Events []domain.TaskEventType `json:"events" example:"completed,failed"`
+	Types  []string               `json:"types" example:"report"`
+	Labels []string               `json:"labels" example:"billing"`
+}
+
+type updateSubscriptionRequest struct {
+	subscriptionRequest
+	Active bool `json:"active"`
+}
+
+type subscriptionResponse struct {
+	Subscription domain.WebhookSubscription `json:"subscription"`
+}
+
+type listSubscriptionsResponse struct {
+	Subscriptions []domain.WebhookSubscription `json:"subscriptions"`
+}
+
+type getSubscriptionDeliveriesResponse struct {
+	Deliveries []domain.WebhookDelivery `json:"deliveries"`
+}
+
+type deleteSubscriptionResponse struct {
+	Message string `json:"message" example:"webhook subscription deleted successfully"`
+}
+
+type replayDeliveryResponse struct {
+	Message string `json:"message" example:"webhook delivery replay scheduled"`
+}
+
+// CreateSubscription godoc
+// @Summary Create a webhook subscription
+// @Description Subscribes the url to task lifecycle events (created, started, completed, failed, cancelled), events, types and labels filters are optional
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param subscription body subscriptionRequest true "Url and filters"
+// @Success 201 {object} subscriptionResponse "created subscription"
+// @Failure 400 {object} errorResponse "invalid request body"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 500 {object} errorResponse "failed to create webhook subscription"
+// @Router /webhooks [post]
+func (h *Handlers) createSubscription(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	sub, err := h.webhookUsecase.CreateSubscription(c.Request.Context(), &domain.WebhookSubscription{
+		URL:    req.URL,
+		Events: req.Events,
+		Types:  req.Types,
+		Labels: req.Labels,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidWebhookURL) || errors.Is(err, domain.ErrInvalidEventType) {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid request body: "+err.Error(), err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to create webhook subscription", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscriptionResponse{
+		Subscription: sub,
+	})
+}
+
+// ListSubscriptions godoc
+// @Summary List webhook subscriptions
+// @Description Returns every webhook subscription including disabled ones
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} listSubscriptionsResponse "subscriptions"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Router /webhooks [get]
+func (h *Handlers) listSubscriptions(c *gin.Context) {
+	c.JSON(http.StatusOK, listSubscriptionsResponse{
+		Subscriptions: h.webhookUsecase.ListSubscriptions(c.Request.Context()),
+	})
+}
+
+// GetSubscriptionByID godoc
+// @Summary Get webhook subscription by ID
+// @Description Returns the subscription with its filters and delivery health
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Subscription ID" format(uuid)
+// @Success 200 {object} subscriptionResponse "subscription"
+// @Failure 400 {object} errorResponse "invalid subscription ID"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 404 {object} errorResponse "webhook subscription not found"
+// @Failure 500 {object} errorResponse "failed to get webhook subscription"
+// @Router /webhooks/{id} [get]
+func (h *Handlers) getSubscriptionByID(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid subscription id", err)
+		return
+	}
+
+	sub, err := h.webhookUsecase.GetSubscriptionByID(c.Request.Context(), uuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "webhook subscription not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to get webhook subscription", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptionResponse{
+		Subscription: sub,
+	})
+}
+
+// UpdateSubscription godoc
+// @Summary Update webhook subscription by ID
+// @Description Replaces url, filters and active flag of the subscription, enabling a disabled subscription resets its failure counter
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Subscription ID" format(uuid)
+// @Param subscription body updateSubscriptionRequest true "Url, filters and active flag"
+// @Success 200 {object} subscriptionResponse "updated subscription"
+// @Failure 400 {object} errorResponse "invalid subscription ID or request body"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 404 {object} errorResponse "webhook subscription not found"
+// @Failure 500 {object} errorResponse "failed to update webhook subscription"
+// @Router /webhooks/{id} [put]
+func (h *Handlers) updateSubscription(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid subscription id", err)
+		return
+	}
+
+	var req updateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	sub, err := h.webhookUsecase.UpdateSubscription(c.Request.Context(), uuid, domain.WebhookSubscription{
+		URL:    req.URL,
+		Events: req.Events,
+		Types:  req.Types,
+		Labels: req.Labels,
+		Active: req.Active,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidWebhookURL) || errors.Is(err, domain.ErrInvalidEventType) {
+			newErrorResponse(c, log, http.StatusBadRequest, "invalid request body: "+err.Error(), err)
+			return
+		}
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "webhook subscription not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to update webhook subscription", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptionResponse{
+		Subscription: sub,
+	})
+}
+
+// DeleteSubscription godoc
+// @Summary Delete webhook subscription by ID
+// @Description Deletes the subscription, its pending deliveries are dropped
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Subscription ID" format(uuid)
+// @Success 200 {object} deleteSubscriptionResponse "webhook subscription deleted successfully"
+// @Failure 400 {object} errorResponse "invalid subscription ID"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 404 {object} errorResponse "webhook subscription not found"
+// @Failure 500 {object} errorResponse "failed to delete webhook subscription"
+// @Router /webhooks/{id} [delete]
+func (h *Handlers) deleteSubscription(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid subscription id", err)
+		return
+	}
+
+	err = h.webhookUsecase.DeleteSubscription(c.Request.Context(), uuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "webhook subscription not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to delete webhook subscription", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deleteSubscriptionResponse{
+		Message: "webhook subscription deleted successfully",
+	})
+}
+
+// GetSubscriptionDeliveries godoc
+// @Summary Get deliveries of the webhook subscription
+// @Description Returns the delivery log of the subscription in the order the deliveries were attempted, only the latest attempts made by this node are kept
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Subscription ID" format(uuid)
+// @Success 200 {object} getSubscriptionDeliveriesResponse "delivery attempts"
+// @Failure 400 {object} errorResponse "invalid subscription ID"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 404 {object} errorResponse "webhook subscription not found"
+// @Failure 500 {object} errorResponse "failed to get webhook deliveries"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *Handlers) getSubscriptionDeliveries(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	id := c.Param("id")
+
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid subscription id", err)
+		return
+	}
+
+	deliveries, err := h.webhookUsecase.GetSubscriptionDeliveries(c.Request.Context(), uuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "webhook subscription not found", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to get webhook deliveries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getSubscriptionDeliveriesResponse{
+		Deliveries: deliveries,
+	})
+}
+
+// ReplayDelivery godoc
+// @Summary Replay failed webhook delivery
+// @Description Sends the event of the failed delivery to the subscription again, the new attempts are added to the delivery log
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "Subscription ID" format(uuid)
+// @Param delivery_id path string true "Delivery ID" format(uuid)
+// @Success 202 {object} replayDeliveryResponse "webhook delivery replay scheduled"
+// @Failure 400 {object} errorResponse "invalid subscription ID or delivery ID"
+// @Failure 401 {object} errorResponse "invalid admin token"
+// @Failure 403 {object} errorResponse "admin endpoints are disabled"
+// @Failure 404 {object} errorResponse "webhook subscription or delivery not found"
+// @Failure 409 {object} errorResponse "delivery succeeded or subscription is disabled"
+// @Failure 500 {object} errorResponse "failed to replay webhook delivery"
+// @Router /webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (h *Handlers) replayDelivery(c *gin.Context) {
+	op, _ := c.Get("op")
+	log := h.log.With(
+		slog.Any("op", op),
+	)
+
+	subID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid subscription id", err)
+		return
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		newErrorResponse(c, log, http.StatusBadRequest, "invalid delivery id", err)
+		return
+	}
+
+	err = h.webhookUsecase.ReplayDelivery(c.Request.Context(), subID, deliveryID)
+	if err != nil {
+		if errors.Is(err, domain.ErrSubscriptionNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "webhook subscription not found", err)
+			return
+		}
+		if errors.Is(err, domain.ErrWebhookDeliveryNotFound) {
+			newErrorResponse(c, log, http.StatusNotFound, "webhook delivery not found", err)
+			return
+		}
+		if errors.Is(err, domain.ErrWebhookDeliverySucceeded) {
+			newErrorResponse(c, log, http.StatusConflict, "webhook delivery succeeded, only failed deliveries can be replayed", err)
+			return
+		}
+		if errors.Is(err, domain.ErrSubscriptionDisabled) {
+			newErrorResponse(c, log, http.StatusConflict, "webhook subscription is disabled", err)
+			return
+		}
+		newErrorResponse(c, log, http.StatusInternalServerError, "failed to replay webhook delivery", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, replayDeliveryResponse{
+		Message: "webhook delivery replay scheduled",
+	})
+}
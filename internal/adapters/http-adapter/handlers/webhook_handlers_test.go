@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// create subscription tests
+
+func TestCreateSubscription_OK(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	requestBody := subscriptionRequest{
+		URL:    "https://example.com/hook",
+		Events: []domain.TaskEventType{domain.EventTaskCompleted, domain.EventTaskFailed},
+		Types:  []string{"report"},
+	}
+	jsonBody, _ := json.Marshal(requestBody)
+
+	req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response subscriptionResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, response.Subscription.ID)
+	assert.Equal(t, requestBody.URL, response.Subscription.URL)
+	assert.Equal(t, requestBody.Events, response.Subscription.Events)
+	assert.True(t, response.Subscription.Active)
+}
+
+func TestCreateSubscription_InvalidEventType(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	jsonBody, _ := json.Marshal(subscriptionRequest{
+		URL:    "https://example.com/hook",
+		Events: []domain.TaskEventType{"exploded"},
+	})
+
+	req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Message, "invalid event type")
+}
+
+// update subscription tests
+
+func TestUpdateSubscription_EnableResetsFailures(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	subID := deps.subRepo.CreateSubscription(&domain.WebhookSubscription{
+		URL:                 "https://example.com/hook",
+		Active:              false,
+		DisabledReason:      "disabled after 20 consecutive failed deliveries",
+		ConsecutiveFailures: 20,
+	})
+
+	// request
+	requestBody := updateSubscriptionRequest{
+		subscriptionRequest: subscriptionRequest{URL: "https://example.com/new-hook"},
+		Active:              true,
+	}
+	jsonBody, _ := json.Marshal(requestBody)
+
+	req, _ := http.NewRequest("PUT", "/webhooks/"+subID.String(), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response subscriptionResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/new-hook", response.Subscription.URL)
+	assert.True(t, response.Subscription.Active)
+	assert.Zero(t, response.Subscription.ConsecutiveFailures)
+	assert.Empty(t, response.Subscription.DisabledReason)
+}
+
+// delete subscription tests
+
+func TestDeleteSubscription_NotFound(t *testing.T) {
+	handlers, _ := createTestHandlers()
+	router := setupTestRouter(handlers)
+
+	// request
+	req, _ := http.NewRequest("DELETE", "/webhooks/"+uuid.New().String(), nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "webhook subscription not found", response.Message)
+}
+
+// replay delivery tests
+
+func TestReplayDelivery_OK(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	subID := deps.subRepo.CreateSubscription(&domain.WebhookSubscription{URL: "https://example.com/hook", Active: true})
+	delivery := domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: subID, Attempt: 5, StatusCode: http.StatusBadGateway, AttemptedAt: time.Now()}
+	deps.webhookDeliveryRepo.SaveWebhookDelivery(delivery)
+
+	// request
+	req, _ := http.NewRequest("POST", "/webhooks/"+subID.String()+"/deliveries/"+delivery.ID.String()+"/replay", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response replayDeliveryResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "webhook delivery replay scheduled", response.Message)
+
+	if assert.Len(t, deps.replayer.replayed, 1) {
+		assert.Equal(t, delivery.ID, deps.replayer.replayed[0].ID)
+	}
+}
+
+func TestReplayDelivery_Succeeded(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	subID := deps.subRepo.CreateSubscription(&domain.WebhookSubscription{URL: "https://example.com/hook", Active: true})
+	delivery := domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: subID, Attempt: 1, StatusCode: http.StatusOK, Success: true, AttemptedAt: time.Now()}
+	deps.webhookDeliveryRepo.SaveWebhookDelivery(delivery)
+
+	// request
+	req, _ := http.NewRequest("POST", "/webhooks/"+subID.String()+"/deliveries/"+delivery.ID.String()+"/replay", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, deps.replayer.replayed)
+}
+
+func TestReplayDelivery_OtherSubscription(t *testing.T) {
+	handlers, deps := createTestHandlersWithDeps()
+	router := setupTestRouter(handlers)
+
+	subID := deps.subRepo.CreateSubscription(&domain.WebhookSubscription{URL: "https://example.com/hook", Active: true})
+	otherSubID := deps.subRepo.CreateSubscription(&domain.WebhookSubscription{URL: "https://example.com/other", Active: true})
+	delivery := domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: otherSubID, Attempt: 1, StatusCode: http.StatusBadGateway, AttemptedAt: time.Now()}
+	deps.webhookDeliveryRepo.SaveWebhookDelivery(delivery)
+
+	// request
+	req, _ := http.NewRequest("POST", "/webhooks/"+subID.String()+"/deliveries/"+delivery.ID.String()+"/replay", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// response check
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response errorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "webhook delivery not found", response.Message)
+}
+
+// webhook routes auth tests
+
+func TestWebhookRoutes_RequireAdminToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{name: "valid token", token: "secret", authorization: "Bearer secret", status: http.StatusOK},
+		{name: "missing token", token: "secret", status: http.StatusUnauthorized},
+		{name: "disabled", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, _ := createTestHandlers()
+			router := handlers.InitRoutes("prod", tt.token)
+
+			// request
+			req, _ := http.NewRequest("GET", "/api/v1/webhooks/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// response check
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -14,9 +14,9 @@ type HttpAdapter struct {
 	server *http_server.Server
 }
 
-func New(cfg config.Config, logger *slog.Logger, svc *usecase.TaskUsecase) *HttpAdapter {
-	handler := handlers.New(logger, svc)
-	router := handler.InitRoutes(cfg.Env)
+func New(cfg config.Config, logger *slog.Logger, svc *usecase.TaskUsecase, webhookSvc *usecase.WebhookUsecase, taskLogSvc *usecase.TaskLogUsecase, eventStreamSvc *usecase.EventStreamUsecase, adminSvc *usecase.AdminUsecase, taskHistorySvc *usecase.TaskHistoryUsecase) *HttpAdapter {
+	handler := handlers.New(logger, cfg.HttpServerCfg.WriteTimeout, svc, webhookSvc, taskLogSvc, eventStreamSvc, adminSvc, taskHistorySvc)
+	router := handler.InitRoutes(cfg.Env, cfg.AdminToken)
 	s := http_server.New(cfg.HttpServerCfg, router)
 
 	return &HttpAdapter{
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/Util787/task-manager/internal/config"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/migrate"
+	"github.com/Util787/task-manager/internal/usecase"
+)
+
+// migrationTimeout limits applying or checking migrations, long data migrations must fit into it
+const migrationTimeout = 10 * time.Minute
+
+// schemaMigrator is implemented by storages with a versioned SQL schema
+type schemaMigrator interface {
+	Migrator() *migrate.Migrator
+}
+
+// prepareSchema refuses storages with pending migrations unless autoMigrate is set, then it applies them
+func prepareSchema(repo usecase.TaskRepository, autoMigrate bool, logger *slog.Logger) error {
+	sm, ok := repo.(schemaMigrator)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	if !autoMigrate {
+		return sm.Migrator().Check(ctx)
+	}
+
+	applied, err := sm.Migrator().Up(ctx)
+	for _, migration := range applied {
+		logger.Info("migration applied", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+	}
+	return err
+}
+
+// NewMigrator opens the configured storage for the migrate command, closer releases the storage
+func NewMigrator(cfg config.Config, logger *slog.Logger) (*migrate.Migrator, io.Closer, error) {
+	const op = "app.NewMigrator"
+
+	repo, storage, err := newTaskRepository(cfg, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sm, ok := repo.(schemaMigrator)
+	if !ok {
+		if storage != nil {
+			storage.Close()
+		}
+		return nil, nil, fmt.Errorf("%s: storage driver %s has no schema migrations", op, cfg.StorageDriver)
+	}
+	return sm.Migrator(), storage, nil
+}
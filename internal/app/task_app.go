@@ -1,24 +1,148 @@
 package app
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 
 	http_adapter "github.com/Util787/task-manager/internal/adapters/http-adapter"
 	"github.com/Util787/task-manager/internal/config"
+	"github.com/Util787/task-manager/internal/infrastructure/eventbus"
+	"github.com/Util787/task-manager/internal/infrastructure/eventstream"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/bbolt"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/cache"
 	"github.com/Util787/task-manager/internal/infrastructure/repo/inmemory"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/postgres"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/redis"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/sqlite"
+	"github.com/Util787/task-manager/internal/infrastructure/resultschema"
+	"github.com/Util787/task-manager/internal/infrastructure/resultstore"
+	"github.com/Util787/task-manager/internal/infrastructure/tasklog"
+	"github.com/Util787/task-manager/internal/infrastructure/webhook"
 	"github.com/Util787/task-manager/internal/usecase"
 )
 
 type App struct {
-	HttpAdapter *http_adapter.HttpAdapter
+	HttpAdapter            *http_adapter.HttpAdapter
+	CallbackDispatcher     *webhook.CallbackDispatcher
+	SubscriptionDispatcher *webhook.SubscriptionDispatcher
+	TaskLogStore           *tasklog.Store
+	TrashJanitor           *usecase.TrashJanitor
+	OutboxRelay            *usecase.OutboxRelay
+
+	storage io.Closer // nil for in-memory storage
 }
 
-func New(cfg config.Config, logger *slog.Logger) *App {
-	taskRepo := inmemory.NewTaskRepository(logger)
-	taskUsecase := usecase.NewTaskUsecase(taskRepo)
-	httpAdapter := http_adapter.New(cfg, logger, taskUsecase)
+func New(cfg config.Config, logger *slog.Logger) (*App, error) {
+	const op = "app.New"
+
+	resultStore, err := resultstore.NewFileStore(cfg.ResultStoreCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	resultSchemas, err := resultschema.NewRegistry(cfg.ResultSchemaCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	eventHub, err := eventstream.NewHub(cfg.EventStreamCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	taskRepo, storage, err := newTaskRepository(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := prepareSchema(taskRepo, cfg.AutoMigrate, logger); err != nil {
+		storage.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	backuper, _ := taskRepo.(usecase.StorageBackuper)
+	var taskCache usecase.TaskCache
+	if cfg.CacheCfg.Size > 0 {
+		cachedRepo := cache.NewTaskRepository(taskRepo, cfg.CacheCfg)
+		taskRepo, taskCache = cachedRepo, cachedRepo
+	}
+	deliveryRepo := inmemory.NewDeliveryRepository(cfg.DeliveryLogCfg)
+	subRepo := inmemory.NewSubscriptionRepository()
+	webhookDeliveryRepo := inmemory.NewWebhookDeliveryRepository(cfg.DeliveryLogCfg)
+	taskLogStore := tasklog.NewStore(cfg.TaskLogCfg)
+
+	callbackDispatcher := webhook.NewCallbackDispatcher(cfg.WebhookCfg, logger, deliveryRepo)
+	subscriptionDispatcher := webhook.NewSubscriptionDispatcher(cfg.WebhookCfg, logger, subRepo, webhookDeliveryRepo)
+
+	bus := eventbus.New()
+	bus.Subscribe(callbackDispatcher)
+	bus.Subscribe(subscriptionDispatcher)
+	bus.Subscribe(eventHub)
+
+	taskUsecase := usecase.NewTaskUsecase(taskRepo, deliveryRepo, resultStore, cfg.ResultStoreCfg.InlineLimit, resultSchemas, bus, bus)
+	trashJanitor := usecase.NewTrashJanitor(logger, taskUsecase, cfg.TrashGracePeriod)
+	outboxRelay := usecase.NewOutboxRelay(logger, taskRepo, bus, cfg.OutboxRelayInterval, cfg.OutboxRelayDelay)
+	webhookUsecase := usecase.NewWebhookUsecase(subRepo, webhookDeliveryRepo, subscriptionDispatcher)
+	taskLogUsecase := usecase.NewTaskLogUsecase(taskRepo, taskLogStore)
+	eventStreamUsecase := usecase.NewEventStreamUsecase(eventHub)
+	adminUsecase := usecase.NewAdminUsecase(taskRepo, backuper, taskCache, resultStore)
+	taskHistoryUsecase := usecase.NewTaskHistoryUsecase(taskRepo)
+	httpAdapter := http_adapter.New(cfg, logger, taskUsecase, webhookUsecase, taskLogUsecase, eventStreamUsecase, adminUsecase, taskHistoryUsecase)
 
 	return &App{
-		HttpAdapter: httpAdapter,
+		HttpAdapter:            httpAdapter,
+		CallbackDispatcher:     callbackDispatcher,
+		SubscriptionDispatcher: subscriptionDispatcher,
+		TaskLogStore:           taskLogStore,
+		TrashJanitor:           trashJanitor,
+		OutboxRelay:            outboxRelay,
+		storage:                storage,
+	}, nil
+}
+
+// newTaskRepository creates the task repository of the configured storage driver, closer releases the storage
+func newTaskRepository(cfg config.Config, logger *slog.Logger) (usecase.TaskRepository, io.Closer, error) {
+	switch cfg.StorageDriver {
+	case config.StorageSQLite:
+		repo, err := sqlite.NewTaskRepository(cfg.SQLiteCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, repo, nil
+	case config.StoragePostgres:
+		repo, err := postgres.NewTaskRepository(cfg.PostgresCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, repo, nil
+	case config.StorageBbolt:
+		repo, err := bbolt.NewTaskRepository(cfg.BboltCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, repo, nil
+	case config.StorageRedis:
+		repo, err := redis.NewTaskRepository(cfg.RedisCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, repo, nil
+	default:
+		if !cfg.WALCfg.Enabled {
+			if cfg.ShardCfg.Shards > 1 {
+				return inmemory.NewShardedTaskRepository(cfg.ShardCfg.Shards), nil, nil
+			}
+			return inmemory.NewTaskRepository(logger), nil, nil
+		}
+		repo, err := inmemory.NewDurableTaskRepository(logger, cfg.WALCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, repo, nil
 	}
-}
\ No newline at end of file
+}
+
+// Close releases the storage, it must be called after the server and dispatchers are stopped
+func (a *App) Close() error {
+	if a.storage == nil {
+		return nil
+	}
+	return a.storage.Close()
+}
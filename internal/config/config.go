@@ -2,17 +2,56 @@ package config
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/Util787/task-manager/internal/infrastructure/eventstream"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/bbolt"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/cache"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/inmemory"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/postgres"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/redis"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/sqlite"
+	"github.com/Util787/task-manager/internal/infrastructure/resultschema"
+	"github.com/Util787/task-manager/internal/infrastructure/resultstore"
+	"github.com/Util787/task-manager/internal/infrastructure/tasklog"
+	"github.com/Util787/task-manager/internal/infrastructure/webhook"
 	http_server "github.com/Util787/task-manager/pkg/http-server"
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Env           string `env:"ENV" envDefault:"prod"`
-	HttpServerCfg http_server.Config
+	Env                 string        `env:"ENV" envDefault:"prod"`
+	StorageDriver       string        `env:"STORAGE_DRIVER" envDefault:"memory"`    // memory, sqlite, postgres, bbolt or redis
+	AdminToken          string        `env:"ADMIN_TOKEN"`                           // bearer token of admin endpoints, empty disables them
+	AutoMigrate         bool          `env:"AUTO_MIGRATE" envDefault:"false"`       // apply pending schema migrations on start instead of refusing to start
+	TrashGracePeriod    time.Duration `env:"TRASH_GRACE_PERIOD" envDefault:"168h"`  // deleted tasks stay in the trash this long before they are purged for good
+	OutboxRelayInterval time.Duration `env:"OUTBOX_RELAY_INTERVAL" envDefault:"1s"` // how often events left in the outbox are published
+	OutboxRelayDelay    time.Duration `env:"OUTBOX_RELAY_DELAY" envDefault:"5s"`    // how long an outbox event is left to the request that stored it
+	HttpServerCfg       http_server.Config
+	WebhookCfg          webhook.Config
+	ResultStoreCfg      resultstore.Config
+	ResultSchemaCfg     resultschema.Config
+	TaskLogCfg          tasklog.Config
+	EventStreamCfg      eventstream.Config
+	WALCfg              inmemory.WALConfig
+	DeliveryLogCfg      inmemory.DeliveryLogConfig
+	ShardCfg            inmemory.ShardConfig
+	SQLiteCfg           sqlite.Config
+	PostgresCfg         postgres.Config
+	BboltCfg            bbolt.Config
+	RedisCfg            redis.Config
+	CacheCfg            cache.Config
 }
 
+const (
+	StorageMemory   = "memory"
+	StorageSQLite   = "sqlite"
+	StoragePostgres = "postgres"
+	StorageBbolt    = "bbolt"
+	StorageRedis    = "redis"
+)
+
 func Load() (*Config, error) {
 	_ = godotenv.Load()
 
@@ -26,5 +65,55 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid environment: %s, must be prod, dev or local", cfg.Env)
 	}
 
+	switch cfg.StorageDriver {
+	case StorageMemory, StorageSQLite, StoragePostgres, StorageBbolt, StorageRedis:
+	default:
+		return nil, fmt.Errorf("invalid storage driver: %s, must be %s, %s, %s, %s or %s", cfg.StorageDriver, StorageMemory, StorageSQLite, StoragePostgres, StorageBbolt, StorageRedis)
+	}
+
+	if cfg.TrashGracePeriod <= 0 {
+		return nil, fmt.Errorf("invalid trash grace period: %s, must be positive", cfg.TrashGracePeriod)
+	}
+
+	if cfg.OutboxRelayInterval <= 0 {
+		return nil, fmt.Errorf("invalid outbox relay interval: %s, must be positive", cfg.OutboxRelayInterval)
+	}
+	if cfg.OutboxRelayDelay < 0 {
+		return nil, fmt.Errorf("invalid outbox relay delay: %s, must not be negative", cfg.OutboxRelayDelay)
+	}
+
+	if cfg.TaskLogCfg.MaxLines < 1 {
+		return nil, fmt.Errorf("invalid task log max lines: %d, must be at least 1", cfg.TaskLogCfg.MaxLines)
+	}
+	if cfg.TaskLogCfg.MaxLineBytes < 1 {
+		return nil, fmt.Errorf("invalid task log max line bytes: %d, must be at least 1", cfg.TaskLogCfg.MaxLineBytes)
+	}
+	// the janitor of the task log store runs ten times per retention
+	if cfg.TaskLogCfg.Retention < time.Second {
+		return nil, fmt.Errorf("invalid task log retention: %s, must be at least 1s", cfg.TaskLogCfg.Retention)
+	}
+
+	if cfg.DeliveryLogCfg.MaxAttempts < 1 {
+		return nil, fmt.Errorf("invalid delivery log max attempts: %d, must be at least 1", cfg.DeliveryLogCfg.MaxAttempts)
+	}
+	if cfg.DeliveryLogCfg.Retention <= 0 {
+		return nil, fmt.Errorf("invalid delivery log retention: %s, must be positive", cfg.DeliveryLogCfg.Retention)
+	}
+
+	if cfg.ShardCfg.Shards < 1 {
+		return nil, fmt.Errorf("invalid memory shards: %d, must be at least 1", cfg.ShardCfg.Shards)
+	}
+	// records of different shards could reach the write-ahead log out of order
+	if cfg.ShardCfg.Shards > 1 && cfg.WALCfg.Enabled {
+		return nil, fmt.Errorf("memory shards can't be used with the write-ahead log, set MEMORY_SHARDS=1 or WAL_ENABLED=false")
+	}
+
+	if cfg.CacheCfg.Size < 0 {
+		return nil, fmt.Errorf("invalid task cache size: %d, must not be negative", cfg.CacheCfg.Size)
+	}
+	if cfg.CacheCfg.Size > 0 && cfg.CacheCfg.TTL <= 0 {
+		return nil, fmt.Errorf("invalid task cache ttl: %s, must be positive", cfg.CacheCfg.TTL)
+	}
+
 	return cfg, nil
 }
@@ -0,0 +1,21 @@
+package domain
+
+import "context"
+
+// ActorSystem is the actor of changes made by the service itself, like purging the trash
+const ActorSystem = "system"
+
+type actorKey struct{}
+
+// ContextWithActor returns ctx that carries who makes the request, events of the changes made with it record the actor
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, ActorSystem if there is none
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return ActorSystem
+}
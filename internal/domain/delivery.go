@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery is a single attempt to deliver a task to its callback url. DeliveryID is sent to the receiver and is the same
+// for every attempt of the finish, so receivers can drop duplicates
+type Delivery struct {
+	ID          uuid.UUID     `json:"id"`
+	DeliveryID  uuid.UUID     `json:"delivery_id"`
+	TaskID      uuid.UUID     `json:"task_id"`
+	URL         string        `json:"url"`
+	Attempt     int           `json:"attempt"`
+	StatusCode  int           `json:"status_code,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Success     bool          `json:"success"`
+	Duration    time.Duration `json:"duration" example:"10"`
+	AttemptedAt time.Time     `json:"attempted_at"`
+}
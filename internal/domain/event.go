@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TaskEventType string
+
+const (
+	EventTaskCreated   TaskEventType = "created"
+	EventTaskStarted   TaskEventType = "started"
+	EventTaskCompleted TaskEventType = "completed"
+	EventTaskFailed    TaskEventType = "failed"
+	EventTaskCancelled TaskEventType = "cancelled"
+	EventTaskProgress  TaskEventType = "progress"
+	EventTaskRetried   TaskEventType = "retried"  // a failed or cancelled task started its next attempt
+	EventTaskDeleted   TaskEventType = "deleted"  // moved to the trash
+	EventTaskRestored  TaskEventType = "restored" // taken out of the trash
+	EventTaskPurged    TaskEventType = "purged"   // deleted for good, no events of the task follow
+
+	// EventTaskSnapshot carries the current task to a watcher before its events, it is never published
+	EventTaskSnapshot TaskEventType = "snapshot"
+)
+
+var TaskEventTypes = []TaskEventType{
+	EventTaskCreated,
+	EventTaskStarted,
+	EventTaskCompleted,
+	EventTaskFailed,
+	EventTaskCancelled,
+	EventTaskProgress,
+	EventTaskRetried,
+	EventTaskDeleted,
+	EventTaskRestored,
+	EventTaskPurged,
+}
+
+func (t TaskEventType) IsValid() bool {
+	return slices.Contains(TaskEventTypes, t)
+}
+
+// IsFinish reports whether the event moves the task to a terminal status
+func (t TaskEventType) IsFinish() bool {
+	return t == EventTaskCompleted || t == EventTaskFailed || t == EventTaskCancelled
+}
+
+// TaskEvent is a lifecycle event of the task, Task holds the task as it was right after the event
+type TaskEvent struct {
+	ID         uuid.UUID     `json:"id"`
+	Type       TaskEventType `json:"type" example:"completed"`
+	Task       Task          `json:"task"`
+	Actor      string        `json:"actor,omitempty" example:"executor-7"` // who caused the event, see ActorFromContext
+	OccurredAt time.Time     `json:"occurred_at"`
+}
+
+func NewTaskEvent(eventType TaskEventType, task Task) TaskEvent {
+	return TaskEvent{
+		ID:         uuid.New(),
+		Type:       eventType,
+		Task:       task,
+		OccurredAt: time.Now(),
+	}
+}
+
+// FinishEventType returns the event type that is published when the task is finished with the terminal status
+func FinishEventType(status TaskStatus) TaskEventType {
+	switch status {
+	case StatusFailed:
+		return EventTaskFailed
+	case StatusCancelled:
+		return EventTaskCancelled
+	default:
+		return EventTaskCompleted
+	}
+}
+
+// TaskEventFilter selects events of the tasks with one of the statuses, one of the types and at least one of the labels, empty list matches everything
+type TaskEventFilter struct {
+	Statuses []TaskStatus `json:"statuses,omitempty"`
+	Types    []string     `json:"types,omitempty"`
+	Labels   []string     `json:"labels,omitempty"`
+}
+
+func (f TaskEventFilter) Matches(event TaskEvent) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, event.Task.TaskState.Status) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, event.Task.Type) {
+		return false
+	}
+	if len(f.Labels) > 0 && !slices.ContainsFunc(f.Labels, event.Task.HasLabel) {
+		return false
+	}
+	return true
+}
+
+func (f TaskEventFilter) Validate() error {
+	for _, status := range f.Statuses {
+		if !status.IsValid() {
+			return fmt.Errorf("%w: %s, must be one of %s, %s, %s or %s", ErrInvalidStatus, status, StatusInProgress, StatusCompleted, StatusFailed, StatusCancelled)
+		}
+	}
+	return nil
+}
+
+var ErrSlowConsumer = errors.New("consumer is too slow, events buffer is full")
@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskHistoryEvent is an entry of the append-only history of the task. It records the status, progress and attempt of the task
+// right after the event, ReplayTaskHistory takes from every event only what its type may change
+type TaskHistoryEvent struct {
+	Seq        int64         `json:"seq" example:"3"` // position in the history of the task, starts at 1
+	Type       TaskEventType `json:"type" example:"progress"`
+	Actor      string        `json:"actor" example:"executor-7"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	Version    int64         `json:"version" example:"2"` // task version right after the event
+	Status     TaskStatus    `json:"status" example:"in_progress"`
+	Progress   int           `json:"progress" example:"40"`
+	Attempt    int           `json:"attempt" example:"1"`
+}
+
+// NewTaskHistoryEvent makes the history entry of the stored event, Seq is assigned by the repository
+func NewTaskHistoryEvent(event TaskEvent) TaskHistoryEvent {
+	return TaskHistoryEvent{
+		Type:       event.Type,
+		Actor:      event.Actor,
+		OccurredAt: event.OccurredAt,
+		Version:    event.Task.Version,
+		Status:     event.Task.TaskState.Status,
+		Progress:   event.Task.TaskState.Progress,
+		Attempt:    event.Task.Attempt,
+	}
+}
+
+// TaskHistoryState is the part of the task rebuilt from its history
+type TaskHistoryState struct {
+	Status   TaskStatus `json:"status" example:"completed"`
+	Progress int        `json:"progress" example:"100"`
+	Attempt  int        `json:"attempt" example:"1"`
+	Version  int64      `json:"version" example:"4"`
+	Deleted  bool       `json:"deleted"`
+	Purged   bool       `json:"purged"`
+}
+
+// ReplayTaskHistory rebuilds the task state from its history, the history must start with the created event
+// and every event must be possible in the state left by the events before it
+func ReplayTaskHistory(events []TaskHistoryEvent) (TaskHistoryState, error) {
+	if len(events) == 0 {
+		return TaskHistoryState{}, fmt.Errorf("%w: history is empty", ErrInvalidHistory)
+	}
+
+	var state TaskHistoryState
+	for i, event := range events {
+		if err := state.apply(i, event); err != nil {
+			return TaskHistoryState{}, fmt.Errorf("%w: event %d (%s): %w", ErrInvalidHistory, event.Seq, event.Type, err)
+		}
+		state.Version = event.Version
+	}
+	return state, nil
+}
+
+func (s *TaskHistoryState) apply(i int, event TaskHistoryEvent) error {
+	if (i == 0) != (event.Type == EventTaskCreated) {
+		return errors.New("history must start with the only created event")
+	}
+	if s.Purged {
+		return errors.New("task is purged")
+	}
+	if event.Version < s.Version {
+		return fmt.Errorf("version goes back from %d to %d", s.Version, event.Version)
+	}
+
+	switch {
+	case event.Type == EventTaskCreated:
+		*s = TaskHistoryState{Status: event.Status, Progress: event.Progress, Attempt: event.Attempt}
+	case event.Type == EventTaskStarted:
+		if s.Status.IsTerminal() {
+			return fmt.Errorf("task is already %s", s.Status)
+		}
+		s.Status = event.Status
+	case event.Type == EventTaskProgress:
+		if s.Status.IsTerminal() || s.Deleted {
+			return errors.New("task is not running")
+		}
+		s.Progress = event.Progress
+	case event.Type == EventTaskRetried:
+		if !s.Status.IsRetryable() || s.Deleted {
+			return errors.New("task is not failed or cancelled")
+		}
+		if event.Status != StatusInProgress || event.Attempt != s.Attempt+1 {
+			return fmt.Errorf("status %s and attempt %d do not start attempt %d", event.Status, event.Attempt, s.Attempt+1)
+		}
+		s.Status = event.Status
+		s.Progress = event.Progress
+		s.Attempt = event.Attempt
+	case event.Type.IsFinish():
+		if s.Status.IsTerminal() || s.Deleted {
+			return errors.New("task is not running")
+		}
+		if FinishEventType(event.Status) != event.Type || !event.Status.IsTerminal() {
+			return fmt.Errorf("status %s does not match the event", event.Status)
+		}
+		s.Status = event.Status
+		s.Progress = event.Progress
+	case event.Type == EventTaskDeleted:
+		if s.Deleted {
+			return errors.New("task is already in the trash")
+		}
+		s.Deleted = true
+	case event.Type == EventTaskRestored:
+		if !s.Deleted {
+			return errors.New("task is not in the trash")
+		}
+		s.Deleted = false
+	case event.Type == EventTaskPurged:
+		s.Purged = true
+	default:
+		return errors.New("unknown event type")
+	}
+	return nil
+}
+
+// Mismatch describes how the stored task differs from the rebuilt state, it is empty when they match
+func (s TaskHistoryState) Mismatch(task Task) string {
+	var diffs []string
+	if s.Purged {
+		diffs = append(diffs, "task is purged but still stored")
+	}
+	if s.Status != task.TaskState.Status {
+		diffs = append(diffs, fmt.Sprintf("status %s, stored %s", s.Status, task.TaskState.Status))
+	}
+	if s.Progress != task.TaskState.Progress {
+		diffs = append(diffs, fmt.Sprintf("progress %d, stored %d", s.Progress, task.TaskState.Progress))
+	}
+	if s.Attempt != task.Attempt {
+		diffs = append(diffs, fmt.Sprintf("attempt %d, stored %d", s.Attempt, task.Attempt))
+	}
+	if s.Version != task.Version {
+		diffs = append(diffs, fmt.Sprintf("version %d, stored %d", s.Version, task.Version))
+	}
+	if s.Deleted != task.IsDeleted() {
+		diffs = append(diffs, fmt.Sprintf("deleted %t, stored %t", s.Deleted, task.IsDeleted()))
+	}
+	return strings.Join(diffs, "; ")
+}
+
+// TaskHistoryMismatch is a task whose history does not rebuild its stored state
+type TaskHistoryMismatch struct {
+	TaskID uuid.UUID `json:"task_id"`
+	Reason string    `json:"reason" example:"progress 40, stored 60"`
+}
+
+var ErrInvalidHistory = errors.New("invalid task history")
@@ -0,0 +1,35 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayTaskHistory_Invalid(t *testing.T) {
+	created := domain.TaskHistoryEvent{Seq: 1, Type: domain.EventTaskCreated, Status: domain.StatusInProgress, Attempt: 1, Version: 1}
+	completed := domain.TaskHistoryEvent{Seq: 2, Type: domain.EventTaskCompleted, Status: domain.StatusCompleted, Progress: 100, Version: 2}
+
+	tests := []struct {
+		name   string
+		events []domain.TaskHistoryEvent
+	}{
+		{name: "empty"},
+		{name: "no created event", events: []domain.TaskHistoryEvent{completed}},
+		{name: "created twice", events: []domain.TaskHistoryEvent{created, created}},
+		{name: "progress after finish", events: []domain.TaskHistoryEvent{created, completed, {Seq: 3, Type: domain.EventTaskProgress, Version: 3}}},
+		{name: "status does not match", events: []domain.TaskHistoryEvent{created, {Seq: 2, Type: domain.EventTaskFailed, Status: domain.StatusCompleted, Version: 2}}},
+		{name: "retried while running", events: []domain.TaskHistoryEvent{created, {Seq: 2, Type: domain.EventTaskRetried, Status: domain.StatusInProgress, Attempt: 2, Version: 2}}},
+		{name: "retried without next attempt", events: []domain.TaskHistoryEvent{created, {Seq: 2, Type: domain.EventTaskFailed, Status: domain.StatusFailed, Attempt: 1, Version: 2}, {Seq: 3, Type: domain.EventTaskRetried, Status: domain.StatusInProgress, Attempt: 1, Version: 3}}},
+		{name: "restored while not deleted", events: []domain.TaskHistoryEvent{created, {Seq: 2, Type: domain.EventTaskRestored, Version: 2}}},
+		{name: "version goes back", events: []domain.TaskHistoryEvent{created, completed, {Seq: 3, Type: domain.EventTaskDeleted, Version: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := domain.ReplayTaskHistory(tt.events)
+			assert.ErrorIs(t, err, domain.ErrInvalidHistory)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultTaskPageSize = 50
+	MaxTaskPageSize     = 200
+)
+
+// TaskListFilter selects listed tasks with one of the statuses, one of the types and at least one of the labels,
+// created in [CreatedFrom, CreatedTo) and updated in [UpdatedFrom, UpdatedTo). Empty lists and zero times match everything
+type TaskListFilter struct {
+	Statuses    []TaskStatus
+	Types       []string
+	Labels      []string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+}
+
+func (f TaskListFilter) Matches(task Task) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, task.TaskState.Status) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, task.Type) {
+		return false
+	}
+	if len(f.Labels) > 0 && !slices.ContainsFunc(f.Labels, task.HasLabel) {
+		return false
+	}
+	return inRange(task.CreatedAt, f.CreatedFrom, f.CreatedTo) && inRange(task.UpdatedAt, f.UpdatedFrom, f.UpdatedTo)
+}
+
+func (f TaskListFilter) Validate() error {
+	for _, status := range f.Statuses {
+		if !status.IsValid() {
+			return fmt.Errorf("%w: %s, must be one of %s, %s, %s or %s", ErrInvalidStatus, status, StatusInProgress, StatusCompleted, StatusFailed, StatusCancelled)
+		}
+	}
+	return nil
+}
+
+// Range returns the bounds the filter puts on the time the tasks are sorted by
+func (f TaskListFilter) Range(sort TaskSort) (from, to time.Time) {
+	if sort.ByUpdate() {
+		return f.UpdatedFrom, f.UpdatedTo
+	}
+	return f.CreatedFrom, f.CreatedTo
+}
+
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+// TaskSort is the order of listed tasks, a leading minus lists the latest first. Tasks with the same time are ordered by id
+type TaskSort string
+
+const (
+	SortCreatedAsc  TaskSort = "created_at"
+	SortCreatedDesc TaskSort = "-created_at"
+	SortUpdatedAsc  TaskSort = "updated_at"
+	SortUpdatedDesc TaskSort = "-updated_at"
+)
+
+var TaskSorts = []TaskSort{SortCreatedAsc, SortCreatedDesc, SortUpdatedAsc, SortUpdatedDesc}
+
+func (s TaskSort) IsValid() bool {
+	return slices.Contains(TaskSorts, s)
+}
+
+func (s TaskSort) ByUpdate() bool {
+	return s == SortUpdatedAsc || s == SortUpdatedDesc
+}
+
+func (s TaskSort) Desc() bool {
+	return s == SortCreatedDesc || s == SortUpdatedDesc
+}
+
+// Key returns the time of the task the order is based on
+func (s TaskSort) Key(task Task) time.Time {
+	if s.ByUpdate() {
+		return task.UpdatedAt
+	}
+	return task.CreatedAt
+}
+
+// Compare orders tasks the way they are listed
+func (s TaskSort) Compare(a, b Task) int {
+	return s.ComparePosition(a, s.Key(b), b.ID)
+}
+
+// ComparePosition orders the task against the position of a task with the sort time at and the id
+func (s TaskSort) ComparePosition(task Task, at time.Time, id uuid.UUID) int {
+	c := s.Key(task).Compare(at)
+	if c == 0 {
+		c = bytes.Compare(task.ID[:], id[:])
+	}
+	if s.Desc() {
+		return -c
+	}
+	return c
+}
+
+// TaskCursor is the position of the last task of a page, the next page starts right after it.
+// It is handed out encoded and is only valid with the sort it was made for
+type TaskCursor struct {
+	Sort TaskSort  `json:"s"`
+	At   time.Time `json:"t"`
+	ID   uuid.UUID `json:"id"`
+}
+
+func NewTaskCursor(sort TaskSort, task Task) TaskCursor {
+	return TaskCursor{Sort: sort, At: sort.Key(task), ID: task.ID}
+}
+
+// Encode returns the cursor as an opaque url safe string
+func (c TaskCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ParseTaskCursor(raw string) (TaskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return TaskCursor{}, ErrInvalidCursor
+	}
+	var cursor TaskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || !cursor.Sort.IsValid() {
+		return TaskCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// TaskListQuery selects up to Limit tasks that match the filter in the sort order, starting after the cursor when it is set.
+// Tasks in the trash are never listed
+type TaskListQuery struct {
+	Filter TaskListFilter
+	Sort   TaskSort
+	After  *TaskCursor
+	Limit  int
+}
+
+// Matches reports whether the task is listed by the query, leaving the limit aside
+func (q TaskListQuery) Matches(task Task) bool {
+	if task.IsDeleted() || !q.Filter.Matches(task) {
+		return false
+	}
+	return q.After == nil || q.Sort.ComparePosition(task, q.After.At, q.After.ID) > 0
+}
+
+// Select returns up to Limit of the tasks the query lists in its order, it reorders tasks.
+// It serves storages that can't list tasks in order themselves
+func (q TaskListQuery) Select(tasks []Task) []Task {
+	tasks = slices.DeleteFunc(tasks, func(task Task) bool { return !q.Matches(task) })
+	slices.SortFunc(tasks, q.Sort.Compare)
+	return tasks[:min(len(tasks), q.Limit)]
+}
+
+// TaskPage is a page of listed tasks, NextCursor is empty on the last page
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJzIjoiLWNyZWF0ZWRfYXQiLCJ0IjoiMjAyNS0wMS0wMlQwMzowNDowNVoiLCJpZCI6IjZiY2QxNzVlLWNiYTktNGJhNi1iNmVmLWYzYWMzNzg2NDExOCJ9"`
+}
+
+var (
+	ErrInvalidSort     = errors.New("invalid sort")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrInvalidPageSize = errors.New("invalid page size")
+)
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskLogLine is a line of task output written by the executor, Seq grows by one with every line of the task
+type TaskLogLine struct {
+	Seq     int64     `json:"seq" example:"42"`
+	TaskID  uuid.UUID `json:"task_id"`
+	Attempt int       `json:"attempt" example:"1"`
+	Level   string    `json:"level,omitempty" example:"info"`
+	Message string    `json:"message" example:"processed 100 rows"`
+	Time    time.Time `json:"time"`
+}
+
+// TaskLogCursor selects lines written after SinceSeq and after SinceTime, zero values select everything
+type TaskLogCursor struct {
+	SinceSeq  int64
+	SinceTime time.Time
+}
+
+func (c TaskLogCursor) Matches(line TaskLogLine) bool {
+	return line.Seq > c.SinceSeq && (c.SinceTime.IsZero() || line.Time.After(c.SinceTime))
+}
+
+var (
+	ErrLogLinesEmpty  = errors.New("log lines are empty")
+	ErrInvalidAttempt = errors.New("invalid attempt")
+)
@@ -0,0 +1,28 @@
+package domain
+
+// EventsWithTask returns copies of the events that carry the task, repositories store the events of a change this way
+// so every event holds the task as the change stored it
+func EventsWithTask(events []TaskEvent, task Task) []TaskEvent {
+	if len(events) == 0 {
+		return nil
+	}
+	withTask := make([]TaskEvent, len(events))
+	for i, event := range events {
+		event.Task = task.Clone()
+		withTask[i] = event
+	}
+	return withTask
+}
+
+// CloneEvents deep copies the events, so the copies share no memory with them
+func CloneEvents(events []TaskEvent) []TaskEvent {
+	if events == nil {
+		return nil
+	}
+	clones := make([]TaskEvent, len(events))
+	for i, event := range events {
+		event.Task = event.Task.Clone()
+		clones[i] = event
+	}
+	return clones
+}
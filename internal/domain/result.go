@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// DefaultResultContentType is used when executor doesn't declare the media type of the result, results are always JSON
+const DefaultResultContentType = "application/json"
+
+// ResultBlob references a result that is too large to be kept with the task and is stored in the result store
+type ResultBlob struct {
+	Key  string `json:"key" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Size int64  `json:"size" example:"1048576"`
+}
+
+// ValidKey reports whether the key is a hex encoded sha256, the only keys the result store gives out
+func (b ResultBlob) ValidKey() bool {
+	sum, err := hex.DecodeString(b.Key)
+	return err == nil && len(sum) == sha256.Size
+}
+
+// ResultSweep reports a sweep of the result store
+type ResultSweep struct {
+	Blobs        int   `json:"blobs" example:"120"` // blobs in the store before the sweep
+	Removed      int   `json:"removed" example:"3"` // blobs no stored task referenced
+	RemovedBytes int64 `json:"removed_bytes" example:"3145728"`
+}
+
+// TaskResult is the result of the task, either Content or Blob is set
+type TaskResult struct {
+	Content     json.RawMessage `json:"content" swaggertype:"object"`
+	ContentType string          `json:"content_type"`
+	Blob        *ResultBlob     `json:"blob,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	Version     int64           `json:"version"` // version of the task the result was read from
+}
+
+// Text returns the result as plain text: JSON strings are unquoted, any other JSON value is returned as is
+func (r TaskResult) Text() string {
+	var text string
+	if err := json.Unmarshal(r.Content, &text); err == nil {
+		return text
+	}
+	return string(r.Content)
+}
+
+// ResultContent is an opened result ready to be streamed, caller must close it
+type ResultContent struct {
+	io.ReadSeekCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	Version     int64 // version of the task the content belongs to
+}
+
+var (
+	ErrTaskNotFinished       = errors.New("task is not finished")
+	ErrResultBlobMissing     = errors.New("result blob is missing")
+	ErrInvalidResultBlob     = errors.New("invalid result blob key")
+	ErrInvalidResult         = errors.New("result is not valid JSON")
+	ErrResultSchemaViolation = errors.New("result does not match the schema of the task type")
+)
@@ -0,0 +1,18 @@
+package domain
+
+import "errors"
+
+var (
+	ErrBackupUnsupported = errors.New("storage does not support online backup")
+	ErrQueueEmpty        = errors.New("task queue is empty")
+	ErrCacheDisabled     = errors.New("task cache is disabled")
+)
+
+// CacheStats are the counters of the task cache
+type CacheStats struct {
+	Hits     uint64 `json:"hits" example:"9120"`
+	Misses   uint64 `json:"misses" example:"311"`
+	Entries  int    `json:"entries" example:"250"`
+	Capacity int    `json:"capacity" example:"10000"`
+	TTL      string `json:"ttl" example:"2s"`
+}
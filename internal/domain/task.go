@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"encoding/json"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,26 +15,96 @@ const (
 	StatusFailed     TaskStatus = "failed"
 	StatusInProgress TaskStatus = "in_progress"
 	StatusCompleted  TaskStatus = "completed"
+	StatusCancelled  TaskStatus = "cancelled"
 )
 
+func (s TaskStatus) IsValid() bool {
+	return s == StatusInProgress || s.IsTerminal()
+}
+
+// IsTerminal reports whether the task can no longer change its status but by a retry
+func (s TaskStatus) IsTerminal() bool {
+	return s == StatusCompleted || s == StatusFailed || s == StatusCancelled
+}
+
+// IsRetryable reports whether a task with the status can start its next attempt
+func (s TaskStatus) IsRetryable() bool {
+	return s == StatusFailed || s == StatusCancelled
+}
+
 type Task struct {
-	ID          uuid.UUID `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	TaskState   TaskState `json:"task_state"`
-	Result      string    `json:"result"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uuid.UUID       `json:"id"`
+	Title             string          `json:"title"`
+	Description       string          `json:"description"`
+	Type              string          `json:"type,omitempty"`
+	Labels            []string        `json:"labels,omitempty"`
+	TaskState         TaskState       `json:"task_state"`
+	Attempt           int             `json:"attempt" example:"1"`
+	Version           int64           `json:"version" example:"3"` // starts at 1 and grows with every stored change of the task
+	Result            json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	ResultContentType string          `json:"result_content_type,omitempty"`
+	ResultBlob        *ResultBlob     `json:"result_blob,omitempty"`
+	CallbackURL       string          `json:"callback_url,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	CompletedAt       *time.Time      `json:"completed_at,omitempty"`
+	DeletedAt         *time.Time      `json:"deleted_at,omitempty"` // set while the task is in the trash
+}
+
+// AnyVersion skips the version check of conditional updates
+const AnyVersion int64 = 0
+
+// MatchesVersion reports whether the task has the version, AnyVersion matches every task
+func (t Task) MatchesVersion(version int64) bool {
+	return version == AnyVersion || t.Version == version
+}
+
+// IsDeleted reports whether the task is in the trash, deleted tasks are hidden from every read but the trash
+func (t Task) IsDeleted() bool {
+	return t.DeletedAt != nil
+}
+
+// Clone deep copies the task, so the copy shares no memory with it
+func (t Task) Clone() Task {
+	t.Labels = slices.Clone(t.Labels)
+	t.Result = slices.Clone(t.Result)
+	t.ResultBlob = clonePtr(t.ResultBlob)
+	t.CompletedAt = clonePtr(t.CompletedAt)
+	t.DeletedAt = clonePtr(t.DeletedAt)
+	return t
+}
+
+func clonePtr[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+// HasLabel reports whether the task is marked with the label
+func (t Task) HasLabel(label string) bool {
+	return slices.Contains(t.Labels, label)
 }
 
 type TaskState struct {
 	Status       TaskStatus    `json:"status"`
 	WorkDuration time.Duration `json:"work_duration" example:"10"`
+	Progress     int           `json:"progress" example:"40"` // percent reported by the executor, 100 once the task is completed
 }
 
 var (
-	ErrTaskNotFound = errors.New("task not found")
-	ErrTitleEmpty   = errors.New("title is empty")
-	ErrTitleTooLong = errors.New("title is too long")
-	ErrDescriptionTooLong = errors.New("description is too long")
+	ErrTaskNotFound        = errors.New("task not found")
+	ErrTitleEmpty          = errors.New("title is empty")
+	ErrTitleTooLong        = errors.New("title is too long")
+	ErrDescriptionTooLong  = errors.New("description is too long")
+	ErrTypeTooLong         = errors.New("type is too long")
+	ErrInvalidLabel        = errors.New("invalid label")
+	ErrInvalidCallbackURL  = errors.New("invalid callback url")
+	ErrInvalidProgress     = errors.New("invalid progress")
+	ErrInvalidStatus       = errors.New("invalid status")
+	ErrTaskAlreadyFinished = errors.New("task is already finished")
+	ErrTaskNotRetryable    = errors.New("task is not failed or cancelled")
+	ErrVersionMismatch     = errors.New("task version does not match")
+	ErrTaskNotInTrash      = errors.New("task is not in the trash")
 )
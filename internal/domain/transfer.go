@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// TaskExportFilter selects exported tasks by status and by creation time in [CreatedFrom, CreatedTo), zero values select everything
+type TaskExportFilter struct {
+	Status      TaskStatus
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+func (f TaskExportFilter) Matches(task Task) bool {
+	if f.Status != "" && task.TaskState.Status != f.Status {
+		return false
+	}
+	if !f.CreatedFrom.IsZero() && task.CreatedAt.Before(f.CreatedFrom) {
+		return false
+	}
+	return f.CreatedTo.IsZero() || task.CreatedAt.Before(f.CreatedTo)
+}
+
+// ImportMode decides what happens to an imported task whose id is already stored
+type ImportMode string
+
+const (
+	ImportSkip      ImportMode = "skip"      // the stored task is kept and the imported one is rejected
+	ImportOverwrite ImportMode = "overwrite" // the stored task is replaced
+	ImportFail      ImportMode = "fail"      // the import stops at the first conflict, tasks imported before it are kept
+)
+
+var ImportModes = []ImportMode{ImportSkip, ImportOverwrite, ImportFail}
+
+func (m ImportMode) IsValid() bool {
+	return slices.Contains(ImportModes, m)
+}
+
+// ImportReport counts imported lines, Errors lists the first rejected ones
+type ImportReport struct {
+	Accepted int           `json:"accepted" example:"98"`
+	Rejected int           `json:"rejected" example:"2"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// ImportError is a rejected line, ID is empty when the line has no task id
+type ImportError struct {
+	Line   int    `json:"line" example:"7"`
+	ID     string `json:"id,omitempty" example:"1b4e28ba-2fa1-11d2-883f-0016d3cca427"`
+	Reason string `json:"reason" example:"task already exists"`
+}
+
+var (
+	ErrTaskExists        = errors.New("task already exists")
+	ErrInvalidImportMode = errors.New("invalid import mode")
+	ErrImportLineTooLong = errors.New("import line is too long")
+)
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscription receives lifecycle events of the tasks that pass its filters, empty filter matches everything
+type WebhookSubscription struct {
+	ID                  uuid.UUID       `json:"id"`
+	URL                 string          `json:"url" example:"https://example.com/hooks/tasks"`
+	Events              []TaskEventType `json:"events,omitempty"`
+	Types               []string        `json:"types,omitempty"`
+	Labels              []string        `json:"labels,omitempty"`
+	Active              bool            `json:"active"`
+	DisabledReason      string          `json:"disabled_reason,omitempty"`
+	ConsecutiveFailures int             `json:"consecutive_failures"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// Matches reports whether the event passes subscription filters, task must have at least one of the subscription labels
+func (s WebhookSubscription) Matches(event TaskEvent) bool {
+	if len(s.Events) > 0 && !slices.Contains(s.Events, event.Type) {
+		return false
+	}
+	if len(s.Types) > 0 && !slices.Contains(s.Types, event.Task.Type) {
+		return false
+	}
+	if len(s.Labels) > 0 && !slices.ContainsFunc(s.Labels, event.Task.HasLabel) {
+		return false
+	}
+	return true
+}
+
+// WebhookDelivery is a single attempt to deliver the event to the subscription. DeliveryID is sent to the receiver and is the same
+// for every attempt and replay of the event, so receivers can drop duplicates
+type WebhookDelivery struct {
+	ID             uuid.UUID     `json:"id"`
+	DeliveryID     uuid.UUID     `json:"delivery_id"`
+	SubscriptionID uuid.UUID     `json:"subscription_id"`
+	Event          TaskEvent     `json:"event"`
+	URL            string        `json:"url"`
+	Attempt        int           `json:"attempt"`
+	ReplayOf       *uuid.UUID    `json:"replay_of,omitempty"`
+	StatusCode     int           `json:"status_code,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Success        bool          `json:"success"`
+	Duration       time.Duration `json:"duration" example:"10"`
+	AttemptedAt    time.Time     `json:"attempted_at"`
+}
+
+var (
+	ErrSubscriptionNotFound     = errors.New("webhook subscription not found")
+	ErrInvalidWebhookURL        = errors.New("invalid webhook url")
+	ErrInvalidEventType         = errors.New("invalid event type")
+	ErrSubscriptionDisabled     = errors.New("webhook subscription is disabled")
+	ErrWebhookDeliveryNotFound  = errors.New("webhook delivery not found")
+	ErrWebhookDeliverySucceeded = errors.New("webhook delivery succeeded, only failed deliveries can be replayed")
+)
@@ -0,0 +1,95 @@
+package eventbus
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/Util787/task-manager/internal/domain"
+)
+
+// Handler must not block, long running work should be moved to handler's own goroutines
+type Handler interface {
+	HandleEvent(event domain.TaskEvent)
+}
+
+// Bus delivers published task events to every subscribed handler in process
+type Bus struct {
+	handlers []Handler
+	mu       sync.RWMutex
+}
+
+func New() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *Bus) Unsubscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = slices.DeleteFunc(b.handlers, func(h Handler) bool {
+		return h == handler
+	})
+}
+
+func (b *Bus) Publish(event domain.TaskEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, handler := range b.handlers {
+		handler.HandleEvent(event)
+	}
+}
+
+// SubscribeEvents subscribes a channel with the given buffer. Publishing never waits for the reader, if the buffer is full
+// the channel is closed instead, so a slow reader sees the end of the stream rather than a gap in it.
+// cancel unsubscribes the channel and must be called once the reader is done
+func (b *Bus) SubscribeEvents(buffer int) (events <-chan domain.TaskEvent, cancel func()) {
+	sub := &channelHandler{events: make(chan domain.TaskEvent, buffer)}
+	b.Subscribe(sub)
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			b.Unsubscribe(sub)
+			sub.close()
+		})
+	}
+}
+
+type channelHandler struct {
+	events chan domain.TaskEvent
+	closed bool
+	mu     sync.Mutex
+}
+
+func (h *channelHandler) HandleEvent(event domain.TaskEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	select {
+	case h.events <- event:
+	default:
+		h.closed = true
+		close(h.events)
+	}
+}
+
+func (h *channelHandler) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.closed {
+		h.closed = true
+		close(h.events)
+	}
+}
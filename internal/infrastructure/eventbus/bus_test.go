@@ -0,0 +1,39 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus_SubscribeEvents(t *testing.T) {
+	bus := New()
+	events, cancel := bus.SubscribeEvents(1)
+
+	bus.Publish(domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{}))
+	event, ok := <-events
+	assert.True(t, ok)
+	assert.Equal(t, domain.EventTaskCreated, event.Type)
+
+	cancel()
+	bus.Publish(domain.NewTaskEvent(domain.EventTaskStarted, domain.Task{}))
+	_, ok = <-events
+	assert.False(t, ok, "channel must be closed after cancel")
+	cancel()
+}
+
+func TestBus_SubscribeEventsClosesSlowReader(t *testing.T) {
+	bus := New()
+	events, cancel := bus.SubscribeEvents(1)
+	defer cancel()
+
+	// second event does not fit the buffer, publishing must not block on it
+	bus.Publish(domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{}))
+	bus.Publish(domain.NewTaskEvent(domain.EventTaskStarted, domain.Task{}))
+
+	_, ok := <-events
+	assert.True(t, ok)
+	_, ok = <-events
+	assert.False(t, ok, "channel must be closed once the reader falls behind")
+}
@@ -0,0 +1,11 @@
+package eventstream
+
+type Config struct {
+	Buffer             int    `env:"EVENT_STREAM_BUFFER" envDefault:"256"`                // events queued for a single stream consumer
+	SlowConsumerPolicy string `env:"EVENT_STREAM_SLOW_CONSUMER_POLICY" envDefault:"drop"` // drop or disconnect, applied when the queue of the consumer is full
+}
+
+const (
+	PolicyDrop       = "drop"
+	PolicyDisconnect = "disconnect"
+)
@@ -0,0 +1,112 @@
+package eventstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Util787/task-manager/internal/domain"
+)
+
+// Hub fans published events out to stream consumers. Every consumer has its own bounded queue, publishing never waits for a consumer:
+// when the queue is full the event is dropped and counted, or the consumer is disconnected, depending on the slow consumer policy
+type Hub struct {
+	cfg     Config
+	streams map[*stream]struct{}
+	mu      sync.RWMutex
+}
+
+type stream struct {
+	queue   chan queuedEvent
+	filter  atomic.Pointer[domain.TaskEventFilter]
+	dropped atomic.Int64  // dropped since the last queued event
+	slow    chan struct{} // closed once the queue overflows with disconnect policy
+	slowSet atomic.Bool
+}
+
+type queuedEvent struct {
+	event   domain.TaskEvent
+	dropped int // events dropped right before this one
+}
+
+func NewHub(cfg Config) (*Hub, error) {
+	if cfg.SlowConsumerPolicy != PolicyDrop && cfg.SlowConsumerPolicy != PolicyDisconnect {
+		return nil, fmt.Errorf("invalid slow consumer policy: %s, must be %s or %s", cfg.SlowConsumerPolicy, PolicyDrop, PolicyDisconnect)
+	}
+	if cfg.Buffer <= 0 {
+		return nil, fmt.Errorf("invalid event stream buffer: %d, must be positive", cfg.Buffer)
+	}
+
+	return &Hub{
+		cfg:     cfg,
+		streams: make(map[*stream]struct{}),
+	}, nil
+}
+
+// HandleEvent queues the event for every consumer whose filter matches it
+func (h *Hub) HandleEvent(event domain.TaskEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for s := range h.streams {
+		if !s.filter.Load().Matches(event) {
+			continue
+		}
+		dropped := s.dropped.Swap(0)
+		select {
+		case s.queue <- queuedEvent{event: event, dropped: int(dropped)}:
+		default:
+			if h.cfg.SlowConsumerPolicy == PolicyDisconnect {
+				if s.slowSet.CompareAndSwap(false, true) {
+					close(s.slow)
+				}
+				continue
+			}
+			s.dropped.Add(dropped + 1)
+		}
+	}
+}
+
+// StreamEvents passes events that match the latest filter from filters to emit, together with the number of events dropped right before it.
+// Empty filter is used until the first one is received. It returns when ctx is done, emit fails or the consumer is disconnected for being slow
+func (h *Hub) StreamEvents(ctx context.Context, filters <-chan domain.TaskEventFilter, emit func(event domain.TaskEvent, dropped int) error) error {
+	s := &stream{
+		queue: make(chan queuedEvent, h.cfg.Buffer),
+		slow:  make(chan struct{}),
+	}
+	s.filter.Store(&domain.TaskEventFilter{})
+
+	h.mu.Lock()
+	h.streams[s] = struct{}{}
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.streams, s)
+		h.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.slow:
+			return domain.ErrSlowConsumer
+		case filter, ok := <-filters:
+			if !ok {
+				filters = nil
+				continue
+			}
+			s.filter.Store(&filter)
+		case queued := <-s.queue:
+			// event could be queued before the filter was changed
+			if !s.filter.Load().Matches(queued.event) {
+				continue
+			}
+			if err := emit(queued.event, queued.dropped); err != nil {
+				return err
+			}
+		}
+	}
+}
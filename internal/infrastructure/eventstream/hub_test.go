@@ -0,0 +1,112 @@
+package eventstream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitStreams waits until the hub has n registered consumers
+func waitStreams(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		return len(hub.streams) == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestHub_DropsEventsOfSlowConsumer(t *testing.T) {
+	hub, err := NewHub(Config{Buffer: 2, SlowConsumerPolicy: PolicyDrop})
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	received := make(chan int, 10)
+	go func() {
+		_ = hub.StreamEvents(context.Background(), nil, func(event domain.TaskEvent, dropped int) error {
+			<-release
+			received <- dropped
+			return nil
+		})
+	}()
+	waitStreams(t, hub, 1)
+
+	// first event is taken by the blocked consumer, two fit the queue, the rest are dropped
+	for range 6 {
+		hub.HandleEvent(domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{ID: uuid.New()}))
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	assert.Equal(t, 0, <-received)
+	assert.Equal(t, 0, <-received)
+	assert.Equal(t, 0, <-received)
+
+	// drops are reported with the first event queued after them
+	hub.HandleEvent(domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{ID: uuid.New()}))
+	assert.Equal(t, 3, <-received)
+}
+
+func TestHub_DisconnectsSlowConsumer(t *testing.T) {
+	hub, err := NewHub(Config{Buffer: 1, SlowConsumerPolicy: PolicyDisconnect})
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- hub.StreamEvents(context.Background(), nil, func(event domain.TaskEvent, dropped int) error {
+			<-release
+			return nil
+		})
+	}()
+	waitStreams(t, hub, 1)
+
+	for range 4 {
+		hub.HandleEvent(domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{ID: uuid.New()}))
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, domain.ErrSlowConsumer)
+	case <-time.After(time.Second):
+		t.Fatal("slow consumer was not disconnected")
+	}
+	waitStreams(t, hub, 0)
+}
+
+func TestHub_Filter(t *testing.T) {
+	hub, err := NewHub(Config{Buffer: 8, SlowConsumerPolicy: PolicyDrop})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	filters := make(chan domain.TaskEventFilter)
+	received := make(chan domain.TaskEvent, 10)
+	go func() {
+		_ = hub.StreamEvents(ctx, filters, func(event domain.TaskEvent, dropped int) error {
+			received <- event
+			return nil
+		})
+	}()
+	filters <- domain.TaskEventFilter{Types: []string{"report"}}
+	waitStreams(t, hub, 1)
+
+	hub.HandleEvent(domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{Type: "import"}))
+	hub.HandleEvent(domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{Type: "report"}))
+
+	event := <-received
+	assert.Equal(t, "report", event.Task.Type)
+	assert.Empty(t, received)
+}
+
+func TestNewHub_InvalidPolicy(t *testing.T) {
+	_, err := NewHub(Config{Buffer: 1, SlowConsumerPolicy: "block"})
+	assert.Error(t, err)
+}
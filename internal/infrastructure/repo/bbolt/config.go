@@ -0,0 +1,8 @@
+package bbolt
+
+import "time"
+
+type Config struct {
+	Path        string        `env:"BBOLT_PATH" envDefault:"./data/tasks.bolt"`
+	LockTimeout time.Duration `env:"BBOLT_LOCK_TIMEOUT" envDefault:"1s"` // how long to wait for the file lock held by another process
+}
@@ -0,0 +1,626 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Tasks are stored as JSON under their id, index buckets hold keys only and point back to the task id:
+// status index key is status + 0x00 + id, creation, update and deletion index keys are big endian unix nanoseconds + id.
+// Only tasks in the trash have a deletion index entry. Outbox events are stored as JSON under the big endian sequence
+// of the bucket, so they are ordered as stored, and the event id index maps ids to those keys. History entries are stored
+// as JSON under task id + big endian sequence of their bucket, so the history of a task is a key range ordered as stored
+var (
+	tasksBucket      = []byte("tasks")
+	byStatusBucket   = []byte("tasks_by_status")
+	byCreatedBucket  = []byte("tasks_by_created")
+	byUpdatedBucket  = []byte("tasks_by_updated")
+	byDeletedBucket  = []byte("tasks_by_deleted")
+	outboxBucket     = []byte("outbox")
+	outboxByIDBucket = []byte("outbox_by_id")
+	historyBucket    = []byte("task_history")
+)
+
+// TaskRepository keeps tasks in an embedded bbolt file, the file is locked by the process for as long as it is open
+type TaskRepository struct {
+	db *bolt.DB
+}
+
+func NewTaskRepository(cfg Config) (*TaskRepository, error) {
+	const op = "bbolt.NewTaskRepository"
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := bolt.Open(cfg.Path, 0o600, &bolt.Options{Timeout: cfg.LockTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(byUpdatedBucket) == nil {
+			if err := indexUpdated(tx); err != nil {
+				return err
+			}
+		}
+		for _, name := range [][]byte{tasksBucket, byStatusBucket, byCreatedBucket, byUpdatedBucket, byDeletedBucket, outboxBucket, outboxByIDBucket, historyBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &TaskRepository{db: db}, nil
+}
+
+func (r *TaskRepository) Close() error {
+	return r.db.Close()
+}
+
+// Backup writes a consistent copy of the database to w without blocking writers, the copy stops once ctx is done
+func (r *TaskRepository) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	const op = "TaskRepository.Backup"
+
+	var written int64
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		written, err = tx.WriteTo(ctxWriter{ctx: ctx, w: w})
+		return err
+	})
+	if err != nil {
+		return written, fmt.Errorf("%s: %w", op, err)
+	}
+	return written, nil
+}
+
+// ctxWriter fails writes once ctx is done, so a copy to a client that left does not hold the read transaction open
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c ctxWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
+
+func (r *TaskRepository) CreateTask(ctx context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error) {
+	const op = "TaskRepository.CreateTask"
+
+	now := time.Now()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.Version = 1
+	task.ID = uuid.New()
+
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		// a write waits for the single writer first, the request may be gone by then
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := putTask(tx, *task); err != nil {
+			return err
+		}
+		return putEvents(tx, domain.EventsWithTask(events, *task))
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return task.ID, nil
+}
+
+func (r *TaskRepository) GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error) {
+	const op = "TaskRepository.GetTaskByID"
+
+	var task domain.Task
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		task, err = getTask(tx, id)
+		return err
+	})
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return task, nil
+}
+
+// UpdateTask applies update inside a write transaction that stores the events as well and bumps the version,
+// bbolt has a single writer so concurrent updates are serialized
+func (r *TaskRepository) UpdateTask(ctx context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error) {
+	const op = "TaskRepository.UpdateTask"
+
+	var updated domain.Task
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		task, err := getTask(tx, id)
+		if err != nil {
+			return err
+		}
+
+		updated = task
+		if err := update(&updated); err != nil {
+			return err
+		}
+		updated.ID = task.ID
+		updated.CreatedAt = task.CreatedAt
+		updated.UpdatedAt = time.Now()
+		updated.Version = task.Version + 1
+
+		if err := deleteIndexes(tx, task); err != nil {
+			return err
+		}
+		if err := putTask(tx, updated); err != nil {
+			return err
+		}
+		return putEvents(tx, domain.EventsWithTask(events, updated))
+	})
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return updated, nil
+}
+
+// DeleteTask deletes the task and its history if it still has the version, domain.AnyVersion deletes it unconditionally.
+// The events are stored in the same transaction
+func (r *TaskRepository) DeleteTask(ctx context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error {
+	const op = "TaskRepository.DeleteTask"
+
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		task, err := getTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if !task.MatchesVersion(version) {
+			return domain.ErrVersionMismatch
+		}
+		if err := deleteIndexes(tx, task); err != nil {
+			return err
+		}
+		if err := tx.Bucket(tasksBucket).Delete(task.ID[:]); err != nil {
+			return err
+		}
+		if err := putEvents(tx, events); err != nil {
+			return err
+		}
+		// after the events, so the history goes with the task entirely
+		return deleteHistory(tx, id)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ListTasks returns up to query.Limit tasks the query lists in its order. The creation or update index of the sort is walked
+// from the cursor and the walk stops once the page is full, with a status filter only tasks in the status index ranges are read
+func (r *TaskRepository) ListTasks(ctx context.Context, query domain.TaskListQuery) ([]domain.Task, error) {
+	const op = "TaskRepository.ListTasks"
+
+	var tasks []domain.Task
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var listed map[uuid.UUID]struct{}
+		if len(query.Filter.Statuses) > 0 {
+			listed = statusIDs(tx, query.Filter.Statuses)
+		}
+
+		return walkSorted(tx, query, func(id uuid.UUID) (bool, error) {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			if _, ok := listed[id]; listed != nil && !ok {
+				return true, nil
+			}
+			task, err := getTask(tx, id)
+			if err != nil {
+				return false, err
+			}
+			if query.Matches(task) {
+				tasks = append(tasks, task)
+			}
+			return len(tasks) < query.Limit, nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tasks, nil
+}
+
+// statusIDs returns ids of the tasks with one of the statuses, only keys of the status index ranges are read
+func statusIDs(tx *bolt.Tx, statuses []domain.TaskStatus) map[uuid.UUID]struct{} {
+	ids := make(map[uuid.UUID]struct{})
+	cursor := tx.Bucket(byStatusBucket).Cursor()
+	for _, status := range statuses {
+		prefix := append([]byte(status), 0)
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			ids[uuid.UUID(key[len(prefix):])] = struct{}{}
+		}
+	}
+	return ids
+}
+
+// walkSorted calls fn with ids of the creation or update index the query is sorted by in its order, from the cursor or the start
+// of the time range of the filter, until fn returns false or the range ends
+func walkSorted(tx *bolt.Tx, query domain.TaskListQuery, fn func(id uuid.UUID) (bool, error)) error {
+	from, to := query.Filter.Range(query.Sort)
+	index := byCreatedBucket
+	if query.Sort.ByUpdate() {
+		index = byUpdatedBucket
+	}
+	cursor := tx.Bucket(index).Cursor()
+
+	var start []byte
+	if query.After != nil {
+		start = append(timeKey(query.After.At), query.After.ID[:]...)
+	}
+	var key []byte
+	if !query.Sort.Desc() {
+		if !from.IsZero() && (start == nil || bytes.Compare(timeKey(from), start) > 0) {
+			start = timeKey(from)
+		}
+		if start == nil {
+			key, _ = cursor.First()
+		} else {
+			key, _ = cursor.Seek(start)
+		}
+	} else {
+		if !to.IsZero() && (start == nil || bytes.Compare(timeKey(to), start) < 0) {
+			start = timeKey(to)
+		}
+		// the walk starts right before the start key
+		if start == nil {
+			key, _ = cursor.Last()
+		} else if key, _ = cursor.Seek(start); key == nil {
+			key, _ = cursor.Last()
+		} else {
+			key, _ = cursor.Prev()
+		}
+	}
+
+	for ; key != nil; key = next(cursor, query.Sort.Desc()) {
+		if !query.Sort.Desc() && !to.IsZero() && bytes.Compare(key[:8], timeKey(to)) >= 0 {
+			return nil
+		}
+		if query.Sort.Desc() && !from.IsZero() && bytes.Compare(key[:8], timeKey(from)) < 0 {
+			return nil
+		}
+		more, err := fn(uuid.UUID(key[8:]))
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func next(cursor *bolt.Cursor, desc bool) []byte {
+	if desc {
+		key, _ := cursor.Prev()
+		return key
+	}
+	key, _ := cursor.Next()
+	return key
+}
+
+// ListDeletedTasks returns tasks in the trash ordered by deletion time, only the deletion index is read
+func (r *TaskRepository) ListDeletedTasks(ctx context.Context) ([]domain.Task, error) {
+	const op = "TaskRepository.ListDeletedTasks"
+
+	var tasks []domain.Task
+	err := r.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(byDeletedBucket).Cursor()
+		for key, _ := cursor.First(); key != nil; key, _ = cursor.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			task, err := getTask(tx, uuid.UUID(key[8:]))
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tasks, nil
+}
+
+// WalkTasks calls fn with every stored task ordered by creation time inside one read transaction, so the walk sees a consistent view.
+// It stops at the first error of fn or once ctx is done, fn must not write to the repository
+func (r *TaskRepository) WalkTasks(ctx context.Context, fn func(task domain.Task) error) error {
+	const op = "TaskRepository.WalkTasks"
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(byCreatedBucket).Cursor()
+		for key, _ := cursor.First(); key != nil; key, _ = cursor.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			task, err := getTask(tx, uuid.UUID(key[8:]))
+			if err != nil {
+				return err
+			}
+			if err := fn(task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ImportTask stores the task as it is, id, timestamps and version included. If the id is already stored the task
+// replaces it when overwrite is set, otherwise domain.ErrTaskExists is returned
+func (r *TaskRepository) ImportTask(ctx context.Context, task domain.Task, overwrite bool) error {
+	const op = "TaskRepository.ImportTask"
+
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		stored, err := getTask(tx, task.ID)
+		switch {
+		case err == nil && !overwrite:
+			return domain.ErrTaskExists
+		case err == nil:
+			if err := deleteIndexes(tx, stored); err != nil {
+				return err
+			}
+		case !errors.Is(err, domain.ErrTaskNotFound):
+			return err
+		}
+		return putTask(tx, task)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ListPendingEvents returns up to limit events of the outbox in the order they were stored
+func (r *TaskRepository) ListPendingEvents(ctx context.Context, limit int) ([]domain.TaskEvent, error) {
+	const op = "TaskRepository.ListPendingEvents"
+
+	var events []domain.TaskEvent
+	err := r.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(outboxBucket).Cursor()
+		for key, data := cursor.First(); key != nil && len(events) < limit; key, data = cursor.Next() {
+			var event domain.TaskEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// MarkEventsDelivered removes the events from the outbox, ids that are not pending are ignored
+func (r *TaskRepository) MarkEventsDelivered(ctx context.Context, ids []uuid.UUID) error {
+	const op = "TaskRepository.MarkEventsDelivered"
+
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		byID := tx.Bucket(outboxByIDBucket)
+		for _, id := range ids {
+			key := byID.Get(id[:])
+			if key == nil {
+				continue
+			}
+			if err := tx.Bucket(outboxBucket).Delete(key); err != nil {
+				return err
+			}
+			if err := byID.Delete(id[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ReadTaskHistory returns the history of the task oldest first, only the key range of the task is read
+func (r *TaskRepository) ReadTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error) {
+	const op = "TaskRepository.ReadTaskHistory"
+
+	var events []domain.TaskHistoryEvent
+	err := r.db.View(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cursor := tx.Bucket(historyBucket).Cursor()
+		for key, data := cursor.Seek(id[:]); key != nil && bytes.HasPrefix(key, id[:]); key, data = cursor.Next() {
+			var event domain.TaskHistoryEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			event.Seq = int64(len(events) + 1)
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// putEvents appends the events to the outbox and to the history of their task within the transaction of the change
+func putEvents(tx *bolt.Tx, events []domain.TaskEvent) error {
+	outbox := tx.Bucket(outboxBucket)
+	history := tx.Bucket(historyBucket)
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		seq, err := outbox.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := binary.BigEndian.AppendUint64(nil, seq)
+		if err := outbox.Put(key, data); err != nil {
+			return err
+		}
+		if err := tx.Bucket(outboxByIDBucket).Put(event.ID[:], key); err != nil {
+			return err
+		}
+
+		entry, err := json.Marshal(domain.NewTaskHistoryEvent(event))
+		if err != nil {
+			return err
+		}
+		seq, err = history.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := history.Put(binary.BigEndian.AppendUint64(event.Task.ID[:], seq), entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteHistory(tx *bolt.Tx, id uuid.UUID) error {
+	cursor := tx.Bucket(historyBucket).Cursor()
+	// the cursor seeks again after every delete, deleting while moving on with Next skips keys
+	for key, _ := cursor.Seek(id[:]); key != nil && bytes.HasPrefix(key, id[:]); key, _ = cursor.Seek(id[:]) {
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getTask(tx *bolt.Tx, id uuid.UUID) (domain.Task, error) {
+	data := tx.Bucket(tasksBucket).Get(id[:])
+	if data == nil {
+		return domain.Task{}, domain.ErrTaskNotFound
+	}
+
+	var task domain.Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return domain.Task{}, err
+	}
+	if task.Version == 0 {
+		task.Version = 1 // stored before tasks had versions
+	}
+	return task, nil
+}
+
+// putTask stores the task with its index entries, stale entries of the previous version must be deleted first
+func putTask(tx *bolt.Tx, task domain.Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(tasksBucket).Put(task.ID[:], data); err != nil {
+		return err
+	}
+	if err := tx.Bucket(byStatusBucket).Put(statusKey(task), nil); err != nil {
+		return err
+	}
+	if task.IsDeleted() {
+		if err := tx.Bucket(byDeletedBucket).Put(deletedKey(task), nil); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(byUpdatedBucket).Put(updatedKey(task), nil); err != nil {
+		return err
+	}
+	return tx.Bucket(byCreatedBucket).Put(createdKey(task), nil)
+}
+
+func deleteIndexes(tx *bolt.Tx, task domain.Task) error {
+	if err := tx.Bucket(byStatusBucket).Delete(statusKey(task)); err != nil {
+		return err
+	}
+	if task.IsDeleted() {
+		if err := tx.Bucket(byDeletedBucket).Delete(deletedKey(task)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(byUpdatedBucket).Delete(updatedKey(task)); err != nil {
+		return err
+	}
+	return tx.Bucket(byCreatedBucket).Delete(createdKey(task))
+}
+
+// indexUpdated creates the update index of a file written before it existed and fills it with every stored task
+func indexUpdated(tx *bolt.Tx) error {
+	index, err := tx.CreateBucket(byUpdatedBucket)
+	if err != nil {
+		return err
+	}
+	tasks := tx.Bucket(tasksBucket)
+	if tasks == nil {
+		return nil
+	}
+	return tasks.ForEach(func(key, _ []byte) error {
+		task, err := getTask(tx, uuid.UUID(key))
+		if err != nil {
+			return err
+		}
+		return index.Put(updatedKey(task), nil)
+	})
+}
+
+func statusKey(task domain.Task) []byte {
+	key := append([]byte(task.TaskState.Status), 0)
+	return append(key, task.ID[:]...)
+}
+
+func createdKey(task domain.Task) []byte {
+	return append(timeKey(task.CreatedAt), task.ID[:]...)
+}
+
+func updatedKey(task domain.Task) []byte {
+	return append(timeKey(task.UpdatedAt), task.ID[:]...)
+}
+
+func deletedKey(task domain.Task) []byte {
+	return append(timeKey(*task.DeletedAt), task.ID[:]...)
+}
+
+// timeKey encodes the time so that byte order matches time order, times before 1970 are clamped
+func timeKey(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(max(t.UnixNano(), 0)))
+}
@@ -0,0 +1,153 @@
+package bbolt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestRepository(t *testing.T, path string) *TaskRepository {
+	t.Helper()
+
+	repo, err := NewTaskRepository(Config{Path: path, LockTimeout: time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestTaskRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.TaskRepository {
+		return newTestRepository(t, filepath.Join(t.TempDir(), "tasks.bolt"))
+	})
+}
+
+func TestTaskRepository_RoundTrip(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.bolt"))
+
+	task := &domain.Task{
+		Title:       "Test Task",
+		Description: "Test Description",
+		Type:        "report",
+		Labels:      []string{"billing", "nightly"},
+		TaskState:   domain.TaskState{Status: domain.StatusInProgress},
+		Attempt:     1,
+		CallbackURL: "https://example.com/hook",
+	}
+	id, err := repo.CreateTask(t.Context(), task)
+	require.NoError(t, err)
+
+	got, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, task.Title, got.Title)
+	assert.Equal(t, task.Labels, got.Labels)
+	assert.Equal(t, task.CallbackURL, got.CallbackURL)
+	assert.True(t, task.CreatedAt.Equal(got.CreatedAt))
+	assert.Equal(t, domain.StatusInProgress, got.TaskState.Status)
+}
+
+// listByStatus lists tasks through the status index
+func listByStatus(t *testing.T, repo *TaskRepository, status domain.TaskStatus) []domain.Task {
+	t.Helper()
+
+	tasks, err := repo.ListTasks(t.Context(), domain.TaskListQuery{
+		Filter: domain.TaskListFilter{Statuses: []domain.TaskStatus{status}},
+		Sort:   domain.SortCreatedAsc,
+		Limit:  domain.MaxTaskPageSize,
+	})
+	require.NoError(t, err)
+	return tasks
+}
+
+func TestTaskRepository_UpdateMovesStatusIndex(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.bolt"))
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "report", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+	_, err = repo.CreateTask(t.Context(), &domain.Task{Title: "other", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+
+	updated, err := repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.TaskState.Status = domain.StatusCompleted
+		task.Result = []byte(`{"total":42}`)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusCompleted, updated.TaskState.Status)
+
+	inProgress := listByStatus(t, repo, domain.StatusInProgress)
+	require.Len(t, inProgress, 1)
+	assert.Equal(t, "other", inProgress[0].Title)
+
+	completed := listByStatus(t, repo, domain.StatusCompleted)
+	require.Len(t, completed, 1)
+	assert.Equal(t, id, completed[0].ID)
+	assert.JSONEq(t, `{"total":42}`, string(completed[0].Result))
+}
+
+func TestTaskRepository_IndexesUpdatedOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.bolt")
+	repo, err := NewTaskRepository(Config{Path: path, LockTimeout: time.Second})
+	require.NoError(t, err)
+
+	first, err := repo.CreateTask(t.Context(), &domain.Task{Title: "first"})
+	require.NoError(t, err)
+	second, err := repo.CreateTask(t.Context(), &domain.Task{Title: "second"})
+	require.NoError(t, err)
+	_, err = repo.UpdateTask(t.Context(), first, func(task *domain.Task) error { return nil })
+	require.NoError(t, err)
+
+	// a file written before the update index existed
+	require.NoError(t, repo.db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket(byUpdatedBucket) }))
+	require.NoError(t, repo.Close())
+
+	reopened := newTestRepository(t, path)
+	tasks, err := reopened.ListTasks(t.Context(), domain.TaskListQuery{Sort: domain.SortUpdatedDesc, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, first, tasks[0].ID)
+	assert.Equal(t, second, tasks[1].ID)
+}
+
+func TestTaskRepository_NotFound(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.bolt"))
+
+	_, err := repo.GetTaskByID(t.Context(), uuid.New())
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+
+	_, err = repo.UpdateTask(t.Context(), uuid.New(), func(task *domain.Task) error { return nil })
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), uuid.New(), domain.AnyVersion), domain.ErrTaskNotFound)
+}
+
+func TestTaskRepository_Backup(t *testing.T) {
+	dir := t.TempDir()
+	repo := newTestRepository(t, filepath.Join(dir, "tasks.bolt"))
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "backed up", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+
+	var backup bytes.Buffer
+	written, err := repo.Backup(t.Context(), &backup)
+	require.NoError(t, err)
+	assert.Equal(t, int64(backup.Len()), written)
+
+	backupPath := filepath.Join(dir, "backup.bolt")
+	require.NoError(t, os.WriteFile(backupPath, backup.Bytes(), 0o600))
+
+	restored := newTestRepository(t, backupPath)
+	got, err := restored.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "backed up", got.Title)
+
+	assert.Len(t, listByStatus(t, restored, domain.StatusInProgress), 1)
+}
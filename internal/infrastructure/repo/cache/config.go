@@ -0,0 +1,9 @@
+package cache
+
+import "time"
+
+// Config enables the read-through cache in front of the task storage, Size 0 disables it
+type Config struct {
+	Size int           `env:"TASK_CACHE_SIZE" envDefault:"0"` // how many tasks are cached, least recently read are evicted first
+	TTL  time.Duration `env:"TASK_CACHE_TTL" envDefault:"2s"` // bounds how long changes made by other replicas stay unseen
+}
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// Repository is the task storage behind the cache
+type Repository interface {
+	CreateTask(ctx context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error)
+	GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error)
+	UpdateTask(ctx context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error)
+	DeleteTask(ctx context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error
+	ListDeletedTasks(ctx context.Context) ([]domain.Task, error)
+	ListTasks(ctx context.Context, query domain.TaskListQuery) ([]domain.Task, error)
+	WalkTasks(ctx context.Context, fn func(task domain.Task) error) error
+	ImportTask(ctx context.Context, task domain.Task, overwrite bool) error
+	ListPendingEvents(ctx context.Context, limit int) ([]domain.TaskEvent, error)
+	MarkEventsDelivered(ctx context.Context, ids []uuid.UUID) error
+	ReadTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error)
+}
+
+// TaskRepository caches tasks read by id in a bounded LRU, entries expire after the TTL. Every write through the cache drops the entry
+// of the task, and a read that was loading the task while it was written is not cached, so a deleted or changed task is never served
+// from the cache afterwards. Concurrent misses of a task share one storage read. Writes made by other processes are seen once the entry expires
+type TaskRepository struct {
+	repo     Repository
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*list.Element
+	lru     *list.List // front is the most recently read
+	loads   map[uuid.UUID]*load
+
+	group  singleflight.Group
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type entry struct {
+	id        uuid.UUID
+	task      domain.Task
+	expiresAt time.Time
+}
+
+// load is a storage read in flight, it is marked stale when the task is written before the read returns
+type load struct {
+	stale bool
+}
+
+func NewTaskRepository(repo Repository, cfg Config) *TaskRepository {
+	return &TaskRepository{
+		repo:     repo,
+		capacity: max(cfg.Size, 1),
+		ttl:      cfg.TTL,
+		now:      time.Now,
+		entries:  make(map[uuid.UUID]*list.Element),
+		lru:      list.New(),
+		loads:    make(map[uuid.UUID]*load),
+	}
+}
+
+// Stats returns the counters of the cache
+func (r *TaskRepository) Stats() domain.CacheStats {
+	r.mu.Lock()
+	entries := r.lru.Len()
+	r.mu.Unlock()
+
+	return domain.CacheStats{
+		Hits:     r.hits.Load(),
+		Misses:   r.misses.Load(),
+		Entries:  entries,
+		Capacity: r.capacity,
+		TTL:      r.ttl.String(),
+	}
+}
+
+func (r *TaskRepository) CreateTask(ctx context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error) {
+	return r.repo.CreateTask(ctx, task, events...)
+}
+
+// GetTaskByID returns the cached task or reads it from the storage, misses of the same task share one read.
+// The read runs with the context of the caller that started it, the others read again if it ends first
+func (r *TaskRepository) GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error) {
+	const op = "TaskRepository.GetTaskByID"
+
+	if task, ok := r.get(id); ok {
+		r.hits.Add(1)
+		return task, nil
+	}
+	r.misses.Add(1)
+
+	value, err, shared := r.group.Do(id.String(), func() (any, error) {
+		l := r.startLoad(id)
+		task, err := r.repo.GetTaskByID(ctx, id)
+		r.finishLoad(id, l, task, err == nil)
+		return task, err
+	})
+	if shared && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		value, err = r.repo.GetTaskByID(ctx, id)
+	}
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// the task is shared by every caller of the read
+	return value.(domain.Task).Clone(), nil
+}
+
+func (r *TaskRepository) UpdateTask(ctx context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error) {
+	defer r.invalidate(id)
+	return r.repo.UpdateTask(ctx, id, update, events...)
+}
+
+func (r *TaskRepository) DeleteTask(ctx context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error {
+	defer r.invalidate(id)
+	return r.repo.DeleteTask(ctx, id, version, events...)
+}
+
+func (r *TaskRepository) ListDeletedTasks(ctx context.Context) ([]domain.Task, error) {
+	return r.repo.ListDeletedTasks(ctx)
+}
+
+// ListTasks reads the storage, a page is not cached
+func (r *TaskRepository) ListTasks(ctx context.Context, query domain.TaskListQuery) ([]domain.Task, error) {
+	return r.repo.ListTasks(ctx, query)
+}
+
+func (r *TaskRepository) WalkTasks(ctx context.Context, fn func(task domain.Task) error) error {
+	return r.repo.WalkTasks(ctx, fn)
+}
+
+func (r *TaskRepository) ImportTask(ctx context.Context, task domain.Task, overwrite bool) error {
+	defer r.invalidate(task.ID)
+	return r.repo.ImportTask(ctx, task, overwrite)
+}
+
+func (r *TaskRepository) ListPendingEvents(ctx context.Context, limit int) ([]domain.TaskEvent, error) {
+	return r.repo.ListPendingEvents(ctx, limit)
+}
+
+func (r *TaskRepository) MarkEventsDelivered(ctx context.Context, ids []uuid.UUID) error {
+	return r.repo.MarkEventsDelivered(ctx, ids)
+}
+
+func (r *TaskRepository) ReadTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error) {
+	return r.repo.ReadTaskHistory(ctx, id)
+}
+
+func (r *TaskRepository) get(id uuid.UUID) (domain.Task, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[id]
+	if !ok {
+		return domain.Task{}, false
+	}
+	e := elem.Value.(*entry)
+	if !r.now().Before(e.expiresAt) {
+		r.remove(elem)
+		return domain.Task{}, false
+	}
+	r.lru.MoveToFront(elem)
+	return e.task.Clone(), true
+}
+
+func (r *TaskRepository) startLoad(id uuid.UUID) *load {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l := &load{}
+	r.loads[id] = l
+	return l
+}
+
+// finishLoad caches the task that was read unless it was written in the meantime
+func (r *TaskRepository) finishLoad(id uuid.UUID, l *load, task domain.Task, found bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.loads[id] == l {
+		delete(r.loads, id)
+	}
+	if !found || l.stale {
+		return
+	}
+
+	if elem, ok := r.entries[id]; ok {
+		r.remove(elem)
+	}
+	r.entries[id] = r.lru.PushFront(&entry{id: id, task: task.Clone(), expiresAt: r.now().Add(r.ttl)})
+	for r.lru.Len() > r.capacity {
+		r.remove(r.lru.Back())
+	}
+}
+
+// invalidate drops the cached task and keeps reads in flight from caching it, it is called once the write is done
+func (r *TaskRepository) invalidate(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[id]; ok {
+		r.remove(elem)
+	}
+	if l, ok := r.loads[id]; ok {
+		l.stale = true
+		delete(r.loads, id)
+	}
+	// readers that come after the write must not join a read that started before it
+	r.group.Forget(id.String())
+}
+
+func (r *TaskRepository) remove(elem *list.Element) {
+	r.lru.Remove(elem)
+	delete(r.entries, elem.Value.(*entry).id)
+}
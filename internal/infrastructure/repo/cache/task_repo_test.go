@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/inmemory"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/repotest"
+	"github.com/Util787/task-manager/pkg/logger/handlers/slogdiscard"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepo counts storage reads, when release is set the first read returns only once it is closed
+type countingRepo struct {
+	*inmemory.TaskRepository
+	reads   atomic.Int64
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *countingRepo) GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error) {
+	task, err := r.TaskRepository.GetTaskByID(ctx, id)
+	if r.reads.Add(1) == 1 && r.release != nil {
+		r.started <- struct{}{}
+		<-r.release
+	}
+	return task, err
+}
+
+func newTestRepository(t *testing.T, size int) (*TaskRepository, *countingRepo) {
+	t.Helper()
+
+	storage := &countingRepo{TaskRepository: inmemory.NewTaskRepository(slogdiscard.NewDiscardLogger())}
+	return NewTaskRepository(storage, Config{Size: size, TTL: time.Minute}), storage
+}
+
+func createTask(t *testing.T, repo *TaskRepository, title string) domain.Task {
+	t.Helper()
+
+	task := domain.Task{Title: title, TaskState: domain.TaskState{Status: domain.StatusInProgress}, Attempt: 1}
+	_, err := repo.CreateTask(t.Context(), &task)
+	require.NoError(t, err)
+	return task
+}
+
+func TestTaskRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.TaskRepository {
+		// a small cache evicts while the suite runs
+		repo, _ := newTestRepository(t, 2)
+		return repo
+	})
+}
+
+func TestTaskRepository_HitsAndMisses(t *testing.T) {
+	repo, storage := newTestRepository(t, 10)
+	task := createTask(t, repo, "Cached")
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	got.Title = "changed by caller"
+	got.Labels = append(got.Labels, "leak")
+
+	got, err = repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Cached", got.Title, "callers get copies of the cached task")
+	assert.Empty(t, got.Labels)
+
+	_, err = repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, storage.reads.Load())
+	stats := repo.Stats()
+	assert.EqualValues(t, 2, stats.Hits)
+	assert.EqualValues(t, 1, stats.Misses)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, 10, stats.Capacity)
+	assert.Equal(t, "1m0s", stats.TTL)
+
+	// not found is not cached
+	_, err = repo.GetTaskByID(t.Context(), uuid.New())
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	assert.Equal(t, 1, repo.Stats().Entries)
+}
+
+func TestTaskRepository_TTL(t *testing.T) {
+	repo, storage := newTestRepository(t, 10)
+	now := time.Now()
+	repo.now = func() time.Time { return now }
+	task := createTask(t, repo, "Expiring")
+
+	_, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	now = now.Add(30 * time.Second)
+	_, err = repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, storage.reads.Load())
+
+	now = now.Add(30 * time.Second)
+	_, err = repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, storage.reads.Load(), "expired entry is read again")
+}
+
+func TestTaskRepository_EvictsLeastRecentlyRead(t *testing.T) {
+	repo, storage := newTestRepository(t, 2)
+	first := createTask(t, repo, "First")
+	second := createTask(t, repo, "Second")
+	third := createTask(t, repo, "Third")
+
+	for _, id := range []uuid.UUID{first.ID, second.ID, first.ID, third.ID} {
+		_, err := repo.GetTaskByID(t.Context(), id)
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 3, storage.reads.Load())
+	assert.Equal(t, 2, repo.Stats().Entries)
+
+	// second was read least recently and is evicted
+	_, err := repo.GetTaskByID(t.Context(), first.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, storage.reads.Load())
+	_, err = repo.GetTaskByID(t.Context(), second.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, storage.reads.Load())
+}
+
+func TestTaskRepository_WritesInvalidate(t *testing.T) {
+	repo, _ := newTestRepository(t, 10)
+	task := createTask(t, repo, "Written")
+
+	_, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	_, err = repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.TaskState.Status = domain.StatusCompleted
+		return nil
+	})
+	require.NoError(t, err)
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusCompleted, got.TaskState.Status)
+
+	require.NoError(t, repo.DeleteTask(t.Context(), task.ID, 0))
+	_, err = repo.GetTaskByID(t.Context(), task.ID)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound, "deleted task is not served from the cache")
+}
+
+func TestTaskRepository_CoalescesMisses(t *testing.T) {
+	repo, storage := newTestRepository(t, 10)
+	task := createTask(t, repo, "Popular")
+	storage.started = make(chan struct{}, 1)
+	storage.release = make(chan struct{})
+
+	const readers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, readers)
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.GetTaskByID(context.Background(), task.ID)
+			errs <- err
+		}()
+	}
+	<-storage.started
+	// let the other readers join the read in flight
+	require.Eventually(t, func() bool { return repo.Stats().Misses == readers }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(storage.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, storage.reads.Load())
+}
+
+func TestTaskRepository_ReadDuringDeleteIsNotCached(t *testing.T) {
+	repo, storage := newTestRepository(t, 10)
+	task := createTask(t, repo, "Deleted")
+	storage.started = make(chan struct{}, 1)
+	storage.release = make(chan struct{})
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := repo.GetTaskByID(context.Background(), task.ID)
+		read <- err
+	}()
+	<-storage.started
+
+	// the read saw the task before the delete and must not cache it
+	require.NoError(t, repo.DeleteTask(t.Context(), task.ID, 0))
+	_, err := repo.GetTaskByID(t.Context(), task.ID)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+
+	close(storage.release)
+	require.NoError(t, <-read)
+	_, err = repo.GetTaskByID(t.Context(), task.ID)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	assert.Equal(t, 0, repo.Stats().Entries)
+}
@@ -0,0 +1,9 @@
+package inmemory
+
+import "github.com/Util787/task-manager/internal/domain"
+
+// cloneTask deep copies the task, stored tasks are cloned on the way in and out so they share no memory with callers
+func cloneTask(task domain.Task) *domain.Task {
+	clone := task.Clone()
+	return &clone
+}
@@ -0,0 +1,172 @@
+package inmemory
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/repotest"
+	"github.com/Util787/task-manager/pkg/logger/handlers/slogdiscard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRepositories() map[string]func() repotest.TaskRepository {
+	return map[string]func() repotest.TaskRepository{
+		"single":  func() repotest.TaskRepository { return NewTaskRepository(slogdiscard.NewDiscardLogger()) },
+		"sharded": func() repotest.TaskRepository { return NewShardedTaskRepository(8) },
+	}
+}
+
+func TestTaskRepository_Isolation(t *testing.T) {
+	for name, newRepo := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			now := time.Now()
+			completedAt := now
+
+			task := &domain.Task{
+				Title:       "Test Task",
+				Labels:      []string{"a", "b"},
+				TaskState:   domain.TaskState{Status: domain.StatusCompleted},
+				Result:      json.RawMessage(`{"ok":true}`),
+				ResultBlob:  &domain.ResultBlob{Key: "key", Size: 1},
+				CompletedAt: &completedAt,
+			}
+			id, err := repo.CreateTask(t.Context(), task)
+			require.NoError(t, err)
+
+			// the caller keeps changing its task after it was stored
+			task.Title = "changed"
+			task.Labels[0] = "changed"
+			task.Result[2] = 'X'
+			task.ResultBlob.Key = "changed"
+			*task.CompletedAt = time.Time{}
+
+			stored, err := repo.GetTaskByID(t.Context(), id)
+			require.NoError(t, err)
+			assert.Equal(t, "Test Task", stored.Title)
+			assert.Equal(t, []string{"a", "b"}, stored.Labels)
+			assert.JSONEq(t, `{"ok":true}`, string(stored.Result))
+			assert.Equal(t, "key", stored.ResultBlob.Key)
+			assert.True(t, stored.CompletedAt.Equal(now))
+
+			// a read copy doesn't reach the stored task either
+			stored.Labels[0] = "changed"
+			stored.ResultBlob.Key = "changed"
+			stored.Result[2] = 'X'
+
+			// neither do the task given to update and the updated task returned
+			var leaked *domain.Task
+			updated, err := repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+				leaked = task
+				task.Labels = append(task.Labels[:1], "c")
+				return nil
+			})
+			require.NoError(t, err)
+			leaked.Labels[0] = "changed"
+			updated.Labels[1] = "changed"
+
+			stored, err = repo.GetTaskByID(t.Context(), id)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a", "c"}, stored.Labels)
+			assert.Equal(t, "key", stored.ResultBlob.Key)
+			assert.JSONEq(t, `{"ok":true}`, string(stored.Result))
+		})
+	}
+}
+
+// TestTaskRepository_ConcurrentReaders is meant to be run with -race: writers rewrite every field of the task
+// from the same counter, so a reader that sees fields from different writes has seen a half-written task
+func TestTaskRepository_ConcurrentReaders(t *testing.T) {
+	const (
+		writers = 4
+		readers = 8
+		writes  = 200
+	)
+
+	for name, newRepo := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			id, err := repo.CreateTask(t.Context(), &domain.Task{
+				Title:     "0",
+				Labels:    []string{"0"},
+				TaskState: domain.TaskState{Status: domain.StatusInProgress},
+				Result:    json.RawMessage(`0`),
+			})
+			require.NoError(t, err)
+
+			var (
+				wg   sync.WaitGroup
+				done = make(chan struct{})
+			)
+			for range writers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range writes {
+						_, err := repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+							n := strconv.FormatInt(task.Version, 10)
+							task.Title = n
+							task.Description = n
+							task.Labels = append(task.Labels, n)
+							task.Result = json.RawMessage(n)
+							task.TaskState.Progress = int(task.Version % 100)
+							return nil
+						})
+						assert.NoError(t, err)
+					}
+				}()
+			}
+
+			var readersWG sync.WaitGroup
+			for range readers {
+				readersWG.Add(1)
+				go func() {
+					defer readersWG.Done()
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+
+						task, err := repo.GetTaskByID(t.Context(), id)
+						if !assert.NoError(t, err) {
+							return
+						}
+						assertConsistent(t, task)
+					}
+				}()
+			}
+
+			wg.Wait()
+			close(done)
+			readersWG.Wait()
+
+			task, err := repo.GetTaskByID(t.Context(), id)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1+writers*writes), task.Version)
+			assertConsistent(t, task)
+		})
+	}
+}
+
+// assertConsistent checks that every field of the task was written by the same update
+func assertConsistent(t *testing.T, task domain.Task) {
+	t.Helper()
+
+	n := strconv.FormatInt(task.Version-1, 10)
+	assert.Equal(t, n, task.Title)
+	assert.Len(t, task.Labels, int(task.Version))
+	assert.Equal(t, n, task.Labels[len(task.Labels)-1])
+	assert.Equal(t, n, string(task.Result))
+	if task.Version > 1 {
+		assert.Equal(t, n, task.Description, fmt.Sprintf("version %d", task.Version))
+		assert.Equal(t, int((task.Version-1)%100), task.TaskState.Progress)
+	}
+}
@@ -0,0 +1,29 @@
+package inmemory
+
+import "time"
+
+// WALConfig enables the write-ahead log of the task repository, without it tasks live only in memory
+type WALConfig struct {
+	Enabled          bool          `env:"WAL_ENABLED" envDefault:"false"`
+	Dir              string        `env:"WAL_DIR" envDefault:"./data/wal"`
+	Fsync            string        `env:"WAL_FSYNC" envDefault:"batched"`        // always, batched or none
+	FsyncInterval    time.Duration `env:"WAL_FSYNC_INTERVAL" envDefault:"100ms"` // how often batched policy syncs the log
+	SnapshotInterval time.Duration `env:"WAL_SNAPSHOT_INTERVAL" envDefault:"5m"` // how often the log is compacted into a snapshot
+}
+
+// ShardConfig splits the task repository into independently locked shards, 1 keeps the whole map under one lock
+type ShardConfig struct {
+	Shards int `env:"MEMORY_SHARDS" envDefault:"1"`
+}
+
+// DeliveryLogConfig bounds the webhook delivery logs, they are kept in memory of the node that made the deliveries
+type DeliveryLogConfig struct {
+	MaxAttempts int           `env:"DELIVERY_LOG_MAX_ATTEMPTS" envDefault:"100"` // oldest attempts of a task or subscription are dropped when it has more
+	Retention   time.Duration `env:"DELIVERY_LOG_RETENTION" envDefault:"168h"`   // attempts are dropped after this long
+}
+
+const (
+	FsyncAlways  = "always"
+	FsyncBatched = "batched"
+	FsyncNone    = "none"
+)
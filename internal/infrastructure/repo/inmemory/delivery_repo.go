@@ -0,0 +1,68 @@
+package inmemory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+// DeliveryRepository keeps the latest cfg.MaxAttempts callback attempts of every task for cfg.Retention,
+// attempts live only in memory of this node
+type DeliveryRepository struct {
+	cfg        DeliveryLogConfig
+	deliveries map[uuid.UUID][]domain.Delivery
+	lastSweep  time.Time
+	mu         sync.RWMutex
+}
+
+func NewDeliveryRepository(cfg DeliveryLogConfig) *DeliveryRepository {
+	return &DeliveryRepository{
+		cfg:        cfg,
+		deliveries: make(map[uuid.UUID][]domain.Delivery),
+	}
+}
+
+func (r *DeliveryRepository) SaveDelivery(delivery domain.Delivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := append(r.deliveries[delivery.TaskID], delivery)
+	if over := len(deliveries) - r.cfg.MaxAttempts; over > 0 {
+		deliveries = append(deliveries[:0:0], deliveries[over:]...)
+	}
+	r.deliveries[delivery.TaskID] = deliveries
+
+	// tasks that are not delivered anymore are swept by later writes, ten times per retention at most
+	now := time.Now()
+	if now.Sub(r.lastSweep) >= r.cfg.Retention/10 {
+		r.lastSweep = now
+		before := now.Add(-r.cfg.Retention)
+		for taskID, deliveries := range r.deliveries {
+			if kept := unexpired(deliveries, before); len(kept) > 0 {
+				r.deliveries[taskID] = kept
+			} else {
+				delete(r.deliveries, taskID)
+			}
+		}
+	}
+}
+
+func (r *DeliveryRepository) GetDeliveriesByTaskID(taskID uuid.UUID) []domain.Delivery {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return unexpired(r.deliveries[taskID], time.Now().Add(-r.cfg.Retention))
+}
+
+// unexpired returns a copy of the attempts made after before, attempts are ordered by the time they were made
+func unexpired(deliveries []domain.Delivery, before time.Time) []domain.Delivery {
+	i := 0
+	for i < len(deliveries) && deliveries[i].AttemptedAt.Before(before) {
+		i++
+	}
+	kept := make([]domain.Delivery, len(deliveries)-i)
+	copy(kept, deliveries[i:])
+	return kept
+}
@@ -0,0 +1,37 @@
+package inmemory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryRepository_CapsAndExpires(t *testing.T) {
+	repo := NewDeliveryRepository(DeliveryLogConfig{MaxAttempts: 2, Retention: time.Hour})
+	now := time.Now()
+
+	taskID := uuid.New()
+	for attempt := 1; attempt <= 3; attempt++ {
+		repo.SaveDelivery(domain.Delivery{ID: uuid.New(), TaskID: taskID, Attempt: attempt, AttemptedAt: now})
+	}
+
+	// only the latest attempts are kept
+	deliveries := repo.GetDeliveriesByTaskID(taskID)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.Equal(t, 3, deliveries[1].Attempt)
+
+	// attempts older than the retention are not listed and are swept with their task
+	expiredID := uuid.New()
+	repo.SaveDelivery(domain.Delivery{ID: uuid.New(), TaskID: expiredID, Attempt: 1, AttemptedAt: now.Add(-2 * time.Hour)})
+	assert.Empty(t, repo.GetDeliveriesByTaskID(expiredID))
+
+	repo.lastSweep = time.Time{}
+	repo.SaveDelivery(domain.Delivery{ID: uuid.New(), TaskID: taskID, Attempt: 4, AttemptedAt: now})
+	assert.NotContains(t, repo.deliveries, expiredID)
+	assert.Len(t, repo.GetDeliveriesByTaskID(taskID), 2)
+}
@@ -0,0 +1,68 @@
+package inmemory
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+// taskHistory keeps the history of every task in the order its events were stored.
+// Like the outbox it is changed by writers that hold the lock of the task, so a change and its history are seen together
+type taskHistory struct {
+	mu      sync.Mutex
+	streams map[uuid.UUID][]domain.TaskHistoryEvent
+}
+
+func (h *taskHistory) add(events []domain.TaskEvent) {
+	if len(events) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.streams == nil {
+		h.streams = make(map[uuid.UUID][]domain.TaskHistoryEvent)
+	}
+	appendHistory(h.streams, events)
+}
+
+// drop removes the history of the purged task
+func (h *taskHistory) drop(id uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.streams, id)
+}
+
+// read returns a copy of the history of the task
+func (h *taskHistory) read(id uuid.UUID) []domain.TaskHistoryEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return slices.Clone(h.streams[id])
+}
+
+// all returns the histories without copying them, the caller must not change them.
+// Histories only grow by appends, so the returned slices stay as they are
+func (h *taskHistory) all() map[uuid.UUID][]domain.TaskHistoryEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	streams := make(map[uuid.UUID][]domain.TaskHistoryEvent, len(h.streams))
+	for id, stream := range h.streams {
+		streams[id] = stream[:len(stream):len(stream)]
+	}
+	return streams
+}
+
+// appendHistory appends the events to the histories of their tasks, numbering them from the end of each history
+func appendHistory(streams map[uuid.UUID][]domain.TaskHistoryEvent, events []domain.TaskEvent) {
+	for _, event := range events {
+		stream := streams[event.Task.ID]
+		entry := domain.NewTaskHistoryEvent(event)
+		entry.Seq = int64(len(stream) + 1)
+		streams[event.Task.ID] = append(stream, entry)
+	}
+}
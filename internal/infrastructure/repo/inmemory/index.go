@@ -0,0 +1,294 @@
+package inmemory
+
+import (
+	"bytes"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+// taskIndex orders stored tasks by creation and update time and groups them by status, type and label, so a list reads
+// about as many tasks as it returns instead of sorting all of them. It holds the pointers of the task map and is changed under its lock
+type taskIndex struct {
+	created  orderedIndex
+	updated  orderedIndex
+	byStatus map[domain.TaskStatus]taskSet
+	byType   map[string]taskSet
+	byLabel  map[string]taskSet
+	size     int
+}
+
+type taskSet map[uuid.UUID]*domain.Task
+
+func newTaskIndex(tasks map[uuid.UUID]*domain.Task) taskIndex {
+	x := taskIndex{
+		created:  orderedIndex{key: func(task *domain.Task) time.Time { return task.CreatedAt }},
+		updated:  orderedIndex{key: func(task *domain.Task) time.Time { return task.UpdatedAt }},
+		byStatus: make(map[domain.TaskStatus]taskSet),
+		byType:   make(map[string]taskSet),
+		byLabel:  make(map[string]taskSet),
+	}
+	// restored tasks come in no order, they are sorted once instead of inserted one by one
+	for _, task := range tasks {
+		x.created.entries = append(x.created.entries, x.created.entry(task))
+		x.updated.entries = append(x.updated.entries, x.updated.entry(task))
+		x.group(task)
+	}
+	slices.SortFunc(x.created.entries, compareEntries)
+	slices.SortFunc(x.updated.entries, compareEntries)
+	x.size = len(tasks)
+	return x
+}
+
+// replace moves the index from the old stored task to the new one, old is nil for a created task and task is nil for a deleted one
+func (x *taskIndex) replace(old, task *domain.Task) {
+	x.created.replace(old, task)
+	x.updated.replace(old, task)
+	if old != nil {
+		x.ungroup(old)
+		x.size--
+	}
+	if task != nil {
+		x.group(task)
+		x.size++
+	}
+}
+
+func (x *taskIndex) group(task *domain.Task) {
+	addToSet(x.byStatus, task.TaskState.Status, task)
+	addToSet(x.byType, task.Type, task)
+	for _, label := range task.Labels {
+		addToSet(x.byLabel, label, task)
+	}
+}
+
+func (x *taskIndex) ungroup(task *domain.Task) {
+	removeFromSet(x.byStatus, task.TaskState.Status, task.ID)
+	removeFromSet(x.byType, task.Type, task.ID)
+	for _, label := range task.Labels {
+		removeFromSet(x.byLabel, label, task.ID)
+	}
+}
+
+func addToSet[K comparable](sets map[K]taskSet, key K, task *domain.Task) {
+	set, ok := sets[key]
+	if !ok {
+		set = make(taskSet)
+		sets[key] = set
+	}
+	set[task.ID] = task
+}
+
+func removeFromSet[K comparable](sets map[K]taskSet, key K, id uuid.UUID) {
+	set := sets[key]
+	delete(set, id)
+	if len(set) == 0 {
+		delete(sets, key)
+	}
+}
+
+// list returns copies of up to query.Limit tasks the query lists in its order
+func (x *taskIndex) list(query domain.TaskListQuery) []domain.Task {
+	if query.Limit <= 0 {
+		return nil
+	}
+	// sorting k candidates costs about k*log(k), walking the order until limit of them turn up reads about size*limit/k tasks
+	if candidates, ok := x.candidates(query.Filter); ok && len(candidates)*len(candidates) <= x.size*query.Limit {
+		tasks := make([]domain.Task, 0, len(candidates))
+		for _, task := range candidates {
+			if query.Matches(*task) {
+				tasks = append(tasks, *task)
+			}
+		}
+		tasks = query.Select(tasks)
+		for i := range tasks {
+			tasks[i] = *cloneTask(tasks[i])
+		}
+		return tasks
+	}
+
+	ordered := &x.created
+	if query.Sort.ByUpdate() {
+		ordered = &x.updated
+	}
+	var tasks []domain.Task
+	ordered.walk(query, func(task *domain.Task) bool {
+		if query.Matches(*task) {
+			tasks = append(tasks, *cloneTask(*task))
+		}
+		return len(tasks) < query.Limit
+	})
+	return tasks
+}
+
+// candidates returns the tasks of the smallest group the filter selects by, ok is false when it selects by none
+func (x *taskIndex) candidates(filter domain.TaskListFilter) (tasks []*domain.Task, ok bool) {
+	var sets []taskSet
+	best := -1
+	consider := func(groups []taskSet) {
+		var size int
+		for _, set := range groups {
+			size += len(set)
+		}
+		if best < 0 || size < best {
+			sets, best = groups, size
+		}
+	}
+	if len(filter.Statuses) > 0 {
+		consider(groups(x.byStatus, filter.Statuses))
+	}
+	if len(filter.Types) > 0 {
+		consider(groups(x.byType, filter.Types))
+	}
+	if len(filter.Labels) > 0 {
+		consider(groups(x.byLabel, filter.Labels))
+	}
+	if best < 0 {
+		return nil, false
+	}
+
+	tasks = make([]*domain.Task, 0, best)
+	for i, set := range sets {
+		for id, task := range set {
+			// a task with several of the labels is in several sets
+			if !slices.ContainsFunc(sets[:i], func(seen taskSet) bool { _, ok := seen[id]; return ok }) {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	return tasks, true
+}
+
+func groups[K comparable](sets map[K]taskSet, keys []K) []taskSet {
+	var found []taskSet
+	for i, key := range keys {
+		// a key listed twice would count its tasks twice
+		if set, ok := sets[key]; ok && !slices.Contains(keys[:i], key) {
+			found = append(found, set)
+		}
+	}
+	return found
+}
+
+// orderedIndex keeps tasks ordered by a time and their id. Writes mostly append, since new times are the latest, and a replaced
+// task leaves a dead entry behind that is dropped once dead entries make up half of the index
+type orderedIndex struct {
+	key     func(task *domain.Task) time.Time
+	entries []indexEntry
+	dead    int
+}
+
+type indexEntry struct {
+	at   time.Time // wall clock only, so entries compare the same whether the task was restored or created by this process
+	id   uuid.UUID
+	task *domain.Task // nil once the entry is dead
+}
+
+func (x *orderedIndex) entry(task *domain.Task) indexEntry {
+	return indexEntry{at: x.key(task).Round(0), id: task.ID, task: task}
+}
+
+func compareEntries(a, b indexEntry) int {
+	return comparePosition(a, b.at, b.id)
+}
+
+func comparePosition(e indexEntry, at time.Time, id uuid.UUID) int {
+	if c := e.at.Compare(at); c != 0 {
+		return c
+	}
+	return bytes.Compare(e.id[:], id[:])
+}
+
+// search returns the index of the first entry at or after the position
+func (x *orderedIndex) search(at time.Time, id uuid.UUID) int {
+	return sort.Search(len(x.entries), func(i int) bool {
+		return comparePosition(x.entries[i], at, id) >= 0
+	})
+}
+
+func (x *orderedIndex) replace(old, task *domain.Task) {
+	// an old task without an entry was never indexed, there is nothing to drop
+	if i := x.find(old); i >= 0 {
+		if task != nil && x.key(old).Round(0).Equal(x.key(task).Round(0)) {
+			x.entries[i].task = task
+			return
+		}
+		x.entries[i].task = nil
+		x.dead++
+	}
+	if task != nil {
+		x.insert(x.entry(task))
+	}
+	if x.dead > len(x.entries)/2 {
+		x.entries = slices.DeleteFunc(x.entries, func(e indexEntry) bool { return e.task == nil })
+		x.dead = 0
+	}
+}
+
+func (x *orderedIndex) insert(e indexEntry) {
+	if n := len(x.entries); n == 0 || compareEntries(x.entries[n-1], e) < 0 {
+		x.entries = append(x.entries, e)
+		return
+	}
+	// the wall clock went back or the task was imported with its own times
+	x.entries = slices.Insert(x.entries, x.search(e.at, e.id), e)
+}
+
+// find returns the index of the live entry of the stored task, or -1 if task is nil or has no entry
+func (x *orderedIndex) find(task *domain.Task) int {
+	if task == nil {
+		return -1
+	}
+	e := x.entry(task)
+	// dead entries of the same position may come first
+	for i := x.search(e.at, e.id); i < len(x.entries) && compareEntries(x.entries[i], e) == 0; i++ {
+		if x.entries[i].task == task {
+			return i
+		}
+	}
+	return -1
+}
+
+// walk calls fn with live tasks in the order of the query, from the cursor or the start of the range of the filter,
+// until fn returns false or the range ends
+func (x *orderedIndex) walk(query domain.TaskListQuery, fn func(task *domain.Task) bool) {
+	from, to := query.Filter.Range(query.Sort)
+	from, to = from.Round(0), to.Round(0)
+
+	if !query.Sort.Desc() {
+		i := x.search(from, uuid.Nil)
+		if query.After != nil {
+			i = max(i, x.search(query.After.At.Round(0), query.After.ID))
+		}
+		for ; i < len(x.entries); i++ {
+			e := x.entries[i]
+			if !to.IsZero() && !e.at.Before(to) {
+				return
+			}
+			if e.task != nil && !fn(e.task) {
+				return
+			}
+		}
+		return
+	}
+
+	i := len(x.entries) - 1
+	if !to.IsZero() {
+		i = x.search(to, uuid.Nil) - 1
+	}
+	if query.After != nil {
+		i = min(i, x.search(query.After.At.Round(0), query.After.ID)-1)
+	}
+	for ; i >= 0; i-- {
+		e := x.entries[i]
+		if !from.IsZero() && e.at.Before(from) {
+			return
+		}
+		if e.task != nil && !fn(e.task) {
+			return
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package inmemory
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTaskRepository_ListMatchesScan checks lists read from the index against a scan of all tasks while tasks are
+// created, changed, imported with old times and deleted, so dead entries pile up and get compacted along the way
+func TestTaskRepository_ListMatchesScan(t *testing.T) {
+	statuses := []domain.TaskStatus{domain.StatusInProgress, domain.StatusCompleted, domain.StatusFailed}
+	types := []string{"", "report", "export"}
+	labels := []string{"billing", "nightly", "urgent"}
+	queries := []domain.TaskListFilter{
+		{},
+		{Statuses: []domain.TaskStatus{domain.StatusCompleted}},
+		{Statuses: []domain.TaskStatus{domain.StatusInProgress, domain.StatusFailed}, Types: []string{"report"}},
+		{Labels: []string{"urgent", "billing"}},
+		{Types: []string{"export"}, Labels: []string{"nightly"}},
+	}
+
+	for name, newRepo := range testRepositories() {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			rnd := rand.New(rand.NewPCG(1, 2))
+			var ids []uuid.UUID
+			start := time.Now()
+
+			for step := range 600 {
+				switch op := rnd.IntN(10); {
+				case op < 4 || len(ids) == 0:
+					task := domain.Task{
+						Title:     "task",
+						Type:      types[rnd.IntN(len(types))],
+						Labels:    []string{labels[rnd.IntN(len(labels))]},
+						TaskState: domain.TaskState{Status: statuses[rnd.IntN(len(statuses))]},
+					}
+					id, err := repo.CreateTask(t.Context(), &task)
+					require.NoError(t, err)
+					ids = append(ids, id)
+				case op < 8:
+					_, err := repo.UpdateTask(t.Context(), ids[rnd.IntN(len(ids))], func(task *domain.Task) error {
+						task.TaskState.Status = statuses[rnd.IntN(len(statuses))]
+						task.Labels = append(task.Labels, labels[rnd.IntN(len(labels))])
+						if rnd.IntN(5) == 0 {
+							now := time.Now()
+							task.DeletedAt = &now
+						}
+						return nil
+					})
+					require.NoError(t, err)
+				case op < 9:
+					// an import brings times from the past, its entries go into the middle of the order
+					at := start.Add(-time.Duration(rnd.IntN(1000)) * time.Millisecond)
+					task := domain.Task{ID: uuid.New(), Title: "imported", Type: "report", Version: 1, CreatedAt: at, UpdatedAt: at}
+					require.NoError(t, repo.ImportTask(t.Context(), task, false))
+					ids = append(ids, task.ID)
+				default:
+					i := rnd.IntN(len(ids))
+					require.NoError(t, repo.DeleteTask(t.Context(), ids[i], domain.AnyVersion))
+					ids = append(ids[:i], ids[i+1:]...)
+				}
+
+				if step%50 != 49 {
+					continue
+				}
+				var all []domain.Task
+				require.NoError(t, repo.WalkTasks(t.Context(), func(task domain.Task) error {
+					all = append(all, task)
+					return nil
+				}))
+				for _, filter := range queries {
+					for _, sort := range domain.TaskSorts {
+						query := domain.TaskListQuery{Filter: filter, Sort: sort, Limit: 7}
+						want := query.Select(append([]domain.Task(nil), all...))
+						got, err := repo.ListTasks(t.Context(), query)
+						require.NoError(t, err)
+						require.Equal(t, taskIDs(want), taskIDs(got), "step %d, sort %s, filter %+v", step, sort, filter)
+
+						if len(got) > 0 {
+							cursor := domain.NewTaskCursor(sort, got[len(got)-1])
+							query.After = &cursor
+							want = query.Select(append([]domain.Task(nil), all...))
+							got, err = repo.ListTasks(t.Context(), query)
+							require.NoError(t, err)
+							assert.Equal(t, taskIDs(want), taskIDs(got), "next page, step %d, sort %s, filter %+v", step, sort, filter)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func taskIDs(tasks []domain.Task) []uuid.UUID {
+	ids := make([]uuid.UUID, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+func TestOrderedIndex_FindMissing(t *testing.T) {
+	x := &orderedIndex{key: func(task *domain.Task) time.Time { return task.CreatedAt }}
+	now := time.Now()
+	indexed := &domain.Task{ID: uuid.New(), CreatedAt: now}
+	x.replace(nil, indexed)
+
+	assert.Equal(t, 0, x.find(indexed))
+	assert.Equal(t, -1, x.find(nil))
+	// a task after every entry and a copy of an indexed task have no entry
+	assert.Equal(t, -1, x.find(&domain.Task{ID: uuid.New(), CreatedAt: now.Add(time.Hour)}))
+	copied := *indexed
+	assert.Equal(t, -1, x.find(&copied))
+
+	// replacing a task that has no entry only indexes the new one
+	replacement := &domain.Task{ID: copied.ID, CreatedAt: now.Add(time.Minute)}
+	x.replace(&copied, replacement)
+	require.Len(t, x.entries, 2)
+	assert.Equal(t, 0, x.find(indexed))
+	assert.Equal(t, 1, x.find(replacement))
+	assert.Zero(t, x.dead)
+}
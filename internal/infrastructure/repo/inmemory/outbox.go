@@ -0,0 +1,61 @@
+package inmemory
+
+import (
+	"sync"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+// outbox keeps events of stored changes in the order they were stored until they are delivered.
+// Writers add events while they hold the lock of the task, so the change and its events are seen together
+type outbox struct {
+	mu     sync.Mutex
+	events []domain.TaskEvent
+}
+
+func (o *outbox) add(events []domain.TaskEvent) {
+	if len(events) == 0 {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.events = append(o.events, domain.CloneEvents(events)...)
+}
+
+// pending returns copies of up to limit oldest events
+func (o *outbox) pending(limit int) []domain.TaskEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return domain.CloneEvents(o.events[:min(max(limit, 0), len(o.events))])
+}
+
+// remove drops the events with the ids
+func (o *outbox) remove(ids []uuid.UUID) {
+	delivered := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		delivered[id] = struct{}{}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	kept := o.events[:0]
+	for _, event := range o.events {
+		if _, ok := delivered[event.ID]; !ok {
+			kept = append(kept, event)
+		}
+	}
+	clear(o.events[len(kept):])
+	o.events = kept
+}
+
+// all returns the pending events without copying them, the caller must not change them
+func (o *outbox) all() []domain.TaskEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.events[:len(o.events):len(o.events)]
+}
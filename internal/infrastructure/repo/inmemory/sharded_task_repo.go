@@ -0,0 +1,223 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+// ShardedTaskRepository spreads tasks over independently locked shards by the hash of their id,
+// so writers of different tasks rarely wait for each other. It keeps copies of tasks only in memory and never checks the request context
+type ShardedTaskRepository struct {
+	shards  []taskShard
+	seed    maphash.Seed
+	outbox  outbox      // shared by the shards, it is locked only by writes that store events
+	history taskHistory // shared like the outbox
+}
+
+type taskShard struct {
+	mu    sync.RWMutex
+	tasks map[uuid.UUID]*domain.Task
+	index *taskIndex
+	_     [24]byte // pads the shard to a cache line, so neighbouring locks don't share one
+}
+
+// NewShardedTaskRepository creates the repository with the given number of shards, values below 1 mean a single shard
+func NewShardedTaskRepository(shards int) *ShardedTaskRepository {
+	shards = max(shards, 1)
+
+	r := &ShardedTaskRepository{
+		shards: make([]taskShard, shards),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range r.shards {
+		tasks := make(map[uuid.UUID]*domain.Task)
+		index := newTaskIndex(tasks)
+		r.shards[i].tasks = tasks
+		r.shards[i].index = &index
+	}
+	return r
+}
+
+func (r *ShardedTaskRepository) shard(id uuid.UUID) *taskShard {
+	return &r.shards[maphash.Bytes(r.seed, id[:])%uint64(len(r.shards))]
+}
+
+func (r *ShardedTaskRepository) CreateTask(_ context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error) {
+	now := time.Now()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.Version = 1
+
+	id := uuid.New()
+	task.ID = id
+
+	shard := r.shard(id)
+	shard.mu.Lock()
+	stored := cloneTask(*task)
+	shard.tasks[id] = stored
+	shard.index.replace(nil, stored)
+	events = domain.EventsWithTask(events, *task)
+	r.outbox.add(events)
+	r.history.add(events)
+	shard.mu.Unlock()
+	return id, nil
+}
+
+func (r *ShardedTaskRepository) GetTaskByID(_ context.Context, id uuid.UUID) (domain.Task, error) {
+	const op = "ShardedTaskRepository.GetTaskByID"
+	shard := r.shard(id)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	task, exists := shard.tasks[id]
+	if !exists {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
+	}
+
+	return *cloneTask(*task), nil
+}
+
+// UpdateTask applies update to the stored task under the lock of its shard and bumps its version, if update returns an error the task is left untouched
+func (r *ShardedTaskRepository) UpdateTask(_ context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error) {
+	const op = "ShardedTaskRepository.UpdateTask"
+	shard := r.shard(id)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	task, exists := shard.tasks[id]
+	if !exists {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
+	}
+
+	updated := *cloneTask(*task)
+	if err := update(&updated); err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	updated.UpdatedAt = time.Now()
+	updated.Version = task.Version + 1
+
+	stored := cloneTask(updated)
+	shard.tasks[id] = stored
+	shard.index.replace(task, stored)
+	events = domain.EventsWithTask(events, updated)
+	r.outbox.add(events)
+	r.history.add(events)
+	return updated, nil
+}
+
+// DeleteTask deletes the task and its history if it still has the version, domain.AnyVersion deletes it unconditionally
+func (r *ShardedTaskRepository) DeleteTask(_ context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error {
+	const op = "ShardedTaskRepository.DeleteTask"
+	shard := r.shard(id)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	task, exists := shard.tasks[id]
+	if !exists {
+		return fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
+	}
+	if !task.MatchesVersion(version) {
+		return fmt.Errorf("%s: %w", op, domain.ErrVersionMismatch)
+	}
+
+	delete(shard.tasks, id)
+	shard.index.replace(task, nil)
+	r.outbox.add(events)
+	r.history.drop(id)
+	return nil
+}
+
+// ListPendingEvents returns up to limit events of the outbox in the order they were stored
+func (r *ShardedTaskRepository) ListPendingEvents(_ context.Context, limit int) ([]domain.TaskEvent, error) {
+	return r.outbox.pending(limit), nil
+}
+
+// MarkEventsDelivered removes the events from the outbox, ids that are not pending are ignored
+func (r *ShardedTaskRepository) MarkEventsDelivered(_ context.Context, ids []uuid.UUID) error {
+	r.outbox.remove(ids)
+	return nil
+}
+
+// ReadTaskHistory returns the history of the task oldest first
+func (r *ShardedTaskRepository) ReadTaskHistory(_ context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error) {
+	return r.history.read(id), nil
+}
+
+// ListDeletedTasks returns tasks in the trash ordered by deletion time, shards are read one after another
+func (r *ShardedTaskRepository) ListDeletedTasks(_ context.Context) ([]domain.Task, error) {
+	var tasks []domain.Task
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.RLock()
+		for _, task := range shard.tasks {
+			if task.IsDeleted() {
+				tasks = append(tasks, *cloneTask(*task))
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	sortByDeletedAt(tasks)
+	return tasks, nil
+}
+
+// ListTasks returns up to query.Limit tasks the query lists in its order, every shard lists a page from its index
+// and the pages are merged. Shards are read one after another, so the page is not a point-in-time view of all of them
+func (r *ShardedTaskRepository) ListTasks(_ context.Context, query domain.TaskListQuery) ([]domain.Task, error) {
+	var tasks []domain.Task
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.RLock()
+		tasks = append(tasks, shard.index.list(query)...)
+		shard.mu.RUnlock()
+	}
+	return query.Select(tasks), nil
+}
+
+// WalkTasks calls fn with every stored task ordered by creation time, it stops at the first error of fn.
+// Shards are copied one after another, so the walk is not a point-in-time view of all of them
+func (r *ShardedTaskRepository) WalkTasks(_ context.Context, fn func(task domain.Task) error) error {
+	const op = "ShardedTaskRepository.WalkTasks"
+
+	var tasks []domain.Task
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.RLock()
+		for _, task := range shard.tasks {
+			tasks = append(tasks, *cloneTask(*task))
+		}
+		shard.mu.RUnlock()
+	}
+
+	sortByCreatedAt(tasks)
+	for _, task := range tasks {
+		if err := fn(task); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+// ImportTask stores the task as it is, id, timestamps and version included. If the id is already stored the task
+// replaces it when overwrite is set, otherwise domain.ErrTaskExists is returned
+func (r *ShardedTaskRepository) ImportTask(_ context.Context, task domain.Task, overwrite bool) error {
+	const op = "ShardedTaskRepository.ImportTask"
+	shard := r.shard(task.ID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	old, exists := shard.tasks[task.ID]
+	if exists && !overwrite {
+		return fmt.Errorf("%s: %w", op, domain.ErrTaskExists)
+	}
+
+	stored := cloneTask(task)
+	shard.tasks[task.ID] = stored
+	shard.index.replace(old, stored)
+	return nil
+}
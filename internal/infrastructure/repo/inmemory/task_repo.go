@@ -1,74 +1,370 @@
 package inmemory
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/pkg/logger/sl"
 	"github.com/google/uuid"
 )
 
+// TaskRepository keeps tasks in a map under one lock, its operations never wait for I/O other than the write-ahead log,
+// so the request context is not checked. Tasks are copied on the way in and out, a stored task is never changed in place
 type TaskRepository struct {
-	tasks map[uuid.UUID]*domain.Task
-	mu    sync.RWMutex // in context of this task its better to use rwmutex than sync.map/mutex
+	tasks   map[uuid.UUID]*domain.Task
+	index   taskIndex
+	outbox  outbox
+	history taskHistory
+	mu      sync.RWMutex // in context of this task its better to use rwmutex than sync.map/mutex
+	log     *slog.Logger
+	journal *journal // nil when the repository is not durable
+
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
 func NewTaskRepository(log *slog.Logger) *TaskRepository {
+	tasks := make(map[uuid.UUID]*domain.Task)
 	return &TaskRepository{
-		tasks: make(map[uuid.UUID]*domain.Task),
+		tasks: tasks,
+		index: newTaskIndex(tasks),
+		log:   log,
+	}
+}
+
+// NewDurableTaskRepository restores tasks from the write-ahead log in cfg.Dir and records every change there,
+// the log is compacted into a snapshot every cfg.SnapshotInterval
+func NewDurableTaskRepository(log *slog.Logger, cfg WALConfig) (*TaskRepository, error) {
+	const op = "inmemory.NewDurableTaskRepository"
+
+	journal, state, err := openJournal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r := &TaskRepository{
+		tasks:   state.tasks,
+		index:   newTaskIndex(state.tasks),
+		outbox:  outbox{events: state.events},
+		history: taskHistory{streams: state.history},
+		log:     log,
+		journal: journal,
+		stop:    make(chan struct{}),
+	}
+	if cfg.Fsync == FsyncBatched {
+		r.wg.Add(1)
+		go r.syncLoop(cfg.FsyncInterval)
+	}
+	r.wg.Add(1)
+	go r.snapshotLoop(cfg.SnapshotInterval)
+
+	log.Info("task repository restored from wal", slog.Int("tasks", len(state.tasks)), slog.Int("pending_events", len(state.events)), slog.String("dir", cfg.Dir))
+	return r, nil
+}
+
+func (r *TaskRepository) syncLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.journal.sync(); err != nil {
+				r.log.Error("failed to sync wal", sl.Err(err))
+			}
+		}
+	}
+}
+
+func (r *TaskRepository) snapshotLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.Snapshot(); err != nil {
+				r.log.Error("failed to write task snapshot", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Snapshot compacts the write-ahead log, writes go on to a new log segment while the snapshot is saved.
+// Events still in the outbox are carried over to the start of the new segment, histories are saved with the snapshot
+func (r *TaskRepository) Snapshot() error {
+	const op = "TaskRepository.Snapshot"
+
+	if r.journal == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	seq, err := r.journal.rotate()
+	if err != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	if pending := r.outbox.all(); len(pending) > 0 {
+		if err := r.journal.append(walRecord{Op: walOutbox, Events: pending}); err != nil {
+			r.mu.Unlock()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	tasks := make([]domain.Task, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		tasks = append(tasks, *task)
+	}
+	history := r.history.all()
+	r.mu.Unlock()
+
+	if err := r.journal.writeSnapshot(seq, tasks, history); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Close writes a final snapshot and syncs the write-ahead log, it is a no-op for a repository without one
+func (r *TaskRepository) Close() error {
+	const op = "TaskRepository.Close"
+
+	if r.journal == nil {
+		return nil
+	}
+
+	close(r.stop)
+	r.wg.Wait()
+
+	snapshotErr := r.Snapshot()
+	if err := r.journal.close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if snapshotErr != nil {
+		return fmt.Errorf("%s: %w", op, snapshotErr)
+	}
+	return nil
+}
+
+// record appends the change to the write-ahead log, it must be called under the write lock before the change is applied
+func (r *TaskRepository) record(rec walRecord) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.append(rec)
 }
 
-func (r *TaskRepository) CreateTask(task *domain.Task) uuid.UUID {
+func (r *TaskRepository) CreateTask(_ context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error) {
+	const op = "TaskRepository.CreateTask"
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	now := time.Now()
 	task.CreatedAt = now
 	task.UpdatedAt = now
+	task.Version = 1
 
 	id := uuid.New()
-	r.tasks[id] = task
-	return id
+	task.ID = id
+	events = domain.EventsWithTask(events, *task)
+	if err := r.record(walRecord{Op: walCreate, Task: task, Events: events}); err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	stored := cloneTask(*task)
+	r.tasks[id] = stored
+	r.index.replace(nil, stored)
+	r.outbox.add(events)
+	r.history.add(events)
+	return id, nil
 }
 
-func (r *TaskRepository) GetTaskStateByID(id uuid.UUID) (domain.TaskState, time.Time, error) {
-	const op = "TaskRepository.GetTaskStateByID"
+func (r *TaskRepository) GetTaskByID(_ context.Context, id uuid.UUID) (domain.Task, error) {
+	const op = "TaskRepository.GetTaskByID"
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	task, exists := r.tasks[id]
 	if !exists {
-		return domain.TaskState{}, time.Time{}, fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
+		return domain.Task{}, fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
 	}
 
-	return task.TaskState, task.CreatedAt, nil
+	return *cloneTask(*task), nil
 }
 
-func (r *TaskRepository) GetTaskResultByID(id uuid.UUID) (string, error) {
-	const op = "TaskRepository.GetTaskResultByID"
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// UpdateTask applies update to the stored task under the write lock and bumps its version, if update returns an error the task is left untouched
+func (r *TaskRepository) UpdateTask(_ context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error) {
+	const op = "TaskRepository.UpdateTask"
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	task, exists := r.tasks[id]
 	if !exists {
-		return "", fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
+		return domain.Task{}, fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
+	}
+
+	updated := *cloneTask(*task)
+	if err := update(&updated); err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	updated.UpdatedAt = time.Now()
+	updated.Version = task.Version + 1
+	events = domain.EventsWithTask(events, updated)
+	if err := r.record(walRecord{Op: walUpdate, Task: &updated, Events: events}); err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return task.Result, nil
+	stored := cloneTask(updated)
+	r.tasks[id] = stored
+	r.index.replace(task, stored)
+	r.outbox.add(events)
+	r.history.add(events)
+	return updated, nil
 }
 
-func (r *TaskRepository) DeleteTask(id uuid.UUID) error {
+// DeleteTask deletes the task and its history if it still has the version, domain.AnyVersion deletes it unconditionally
+func (r *TaskRepository) DeleteTask(_ context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error {
 	const op = "TaskRepository.DeleteTask"
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.tasks[id]; !exists {
+	task, exists := r.tasks[id]
+	if !exists {
 		return fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
 	}
+	if !task.MatchesVersion(version) {
+		return fmt.Errorf("%s: %w", op, domain.ErrVersionMismatch)
+	}
+	if err := r.record(walRecord{Op: walDelete, ID: id, Events: events}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	delete(r.tasks, id)
+	r.index.replace(task, nil)
+	r.outbox.add(events)
+	r.history.drop(id)
+	return nil
+}
+
+// ListDeletedTasks returns tasks in the trash ordered by deletion time
+func (r *TaskRepository) ListDeletedTasks(_ context.Context) ([]domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tasks []domain.Task
+	for _, task := range r.tasks {
+		if task.IsDeleted() {
+			tasks = append(tasks, *cloneTask(*task))
+		}
+	}
+	sortByDeletedAt(tasks)
+	return tasks, nil
+}
+
+// ListTasks returns up to query.Limit tasks the query lists in its order, the index is read instead of every task
+func (r *TaskRepository) ListTasks(_ context.Context, query domain.TaskListQuery) ([]domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.index.list(query), nil
+}
+
+// WalkTasks calls fn with every stored task ordered by creation time, it stops at the first error of fn.
+// fn gets copies of the tasks as they were when the walk started and may call the repository
+func (r *TaskRepository) WalkTasks(_ context.Context, fn func(task domain.Task) error) error {
+	const op = "TaskRepository.WalkTasks"
+
+	r.mu.RLock()
+	tasks := make([]domain.Task, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		tasks = append(tasks, *cloneTask(*task))
+	}
+	r.mu.RUnlock()
+
+	sortByCreatedAt(tasks)
+	for _, task := range tasks {
+		if err := fn(task); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
 	return nil
 }
+
+// ImportTask stores the task as it is, id, timestamps and version included. If the id is already stored the task
+// replaces it when overwrite is set, otherwise domain.ErrTaskExists is returned
+func (r *TaskRepository) ImportTask(_ context.Context, task domain.Task, overwrite bool) error {
+	const op = "TaskRepository.ImportTask"
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	walOp := walCreate
+	old, exists := r.tasks[task.ID]
+	if exists {
+		if !overwrite {
+			return fmt.Errorf("%s: %w", op, domain.ErrTaskExists)
+		}
+		walOp = walUpdate
+	}
+	if err := r.record(walRecord{Op: walOp, Task: &task}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stored := cloneTask(task)
+	r.tasks[task.ID] = stored
+	r.index.replace(old, stored)
+	return nil
+}
+
+// ListPendingEvents returns up to limit events of the outbox in the order they were stored
+func (r *TaskRepository) ListPendingEvents(_ context.Context, limit int) ([]domain.TaskEvent, error) {
+	return r.outbox.pending(limit), nil
+}
+
+// MarkEventsDelivered removes the events from the outbox, ids that are not pending are ignored
+func (r *TaskRepository) MarkEventsDelivered(_ context.Context, ids []uuid.UUID) error {
+	const op = "TaskRepository.MarkEventsDelivered"
+	// under the write lock, so a snapshot can't carry the events over after they are marked
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.record(walRecord{Op: walDelivered, EventIDs: ids}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	r.outbox.remove(ids)
+	return nil
+}
+
+// ReadTaskHistory returns the history of the task oldest first
+func (r *TaskRepository) ReadTaskHistory(_ context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error) {
+	return r.history.read(id), nil
+}
+
+// sortByCreatedAt orders tasks from the earliest created, ties are broken by id so the order is stable
+func sortByCreatedAt(tasks []domain.Task) {
+	slices.SortFunc(tasks, func(a, b domain.Task) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+}
+
+// sortByDeletedAt orders deleted tasks from the earliest deleted, ties are broken by id so the order is stable
+func sortByDeletedAt(tasks []domain.Task) {
+	slices.SortFunc(tasks, func(a, b domain.Task) int {
+		if c := a.DeletedAt.Compare(*b.DeletedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+}
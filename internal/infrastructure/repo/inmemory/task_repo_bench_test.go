@@ -0,0 +1,129 @@
+package inmemory
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/repotest"
+	"github.com/Util787/task-manager/pkg/logger/handlers/slogdiscard"
+	"github.com/google/uuid"
+)
+
+// benchTasks is how many tasks are stored before a benchmark starts
+const benchTasks = 10_000
+
+// BenchmarkTaskRepository compares the single lock repository with the sharded one, every operation either reads a random task
+// or updates its progress, writes is the share of updates in percent. Run with -cpu to see how they scale, e.g. -cpu 1,4,16
+func BenchmarkTaskRepository(b *testing.B) {
+	repos := []struct {
+		name string
+		new  func() repotest.TaskRepository
+	}{
+		{"single", func() repotest.TaskRepository { return NewTaskRepository(slogdiscard.NewDiscardLogger()) }},
+		{"sharded-16", func() repotest.TaskRepository { return NewShardedTaskRepository(16) }},
+		{"sharded-64", func() repotest.TaskRepository { return NewShardedTaskRepository(64) }},
+	}
+
+	for _, writes := range []int{5, 20, 50} {
+		for _, repo := range repos {
+			b.Run(fmt.Sprintf("writes=%d%%/%s", writes, repo.name), func(b *testing.B) {
+				benchmarkMix(b, repo.new(), writes)
+			})
+		}
+	}
+}
+
+func benchmarkMix(b *testing.B, repo repotest.TaskRepository, writes int) {
+	ids := make([]uuid.UUID, benchTasks)
+	for i := range ids {
+		id, err := repo.CreateTask(b.Context(), &domain.Task{Title: "bench", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = id
+	}
+	progress := func(task *domain.Task) error {
+		task.TaskState.Progress = (task.TaskState.Progress + 1) % 100
+		return nil
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			id := ids[rand.IntN(len(ids))]
+			var err error
+			if rand.IntN(100) < writes {
+				_, err = repo.UpdateTask(b.Context(), id, progress)
+			} else {
+				_, err = repo.GetTaskByID(b.Context(), id)
+			}
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkTaskRepository_Create measures creates only, they always take the write lock
+func BenchmarkTaskRepository_Create(b *testing.B) {
+	b.Run("single", func(b *testing.B) { benchmarkCreate(b, NewTaskRepository(slogdiscard.NewDiscardLogger())) })
+	b.Run("sharded-16", func(b *testing.B) { benchmarkCreate(b, NewShardedTaskRepository(16)) })
+	b.Run("sharded-64", func(b *testing.B) { benchmarkCreate(b, NewShardedTaskRepository(64)) })
+}
+
+func benchmarkCreate(b *testing.B, repo repotest.TaskRepository) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := repo.CreateTask(b.Context(), &domain.Task{Title: "bench"}); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkTaskRepository_List measures pages of 50 tasks out of benchTasks, by the order alone and with a filter
+// that matches one task in a hundred, after every task has been updated once
+func BenchmarkTaskRepository_List(b *testing.B) {
+	queries := []struct {
+		name  string
+		query domain.TaskListQuery
+	}{
+		{"latest", domain.TaskListQuery{Sort: domain.SortCreatedDesc, Limit: 50}},
+		{"updated", domain.TaskListQuery{Sort: domain.SortUpdatedDesc, Limit: 50}},
+		{"rare-label", domain.TaskListQuery{Filter: domain.TaskListFilter{Labels: []string{"rare"}}, Sort: domain.SortCreatedAsc, Limit: 50}},
+	}
+
+	for _, q := range queries {
+		b.Run(q.name, func(b *testing.B) {
+			repo := NewTaskRepository(slogdiscard.NewDiscardLogger())
+			for i := range benchTasks {
+				task := &domain.Task{Title: "bench", TaskState: domain.TaskState{Status: domain.StatusInProgress}}
+				if i%100 == 0 {
+					task.Labels = []string{"rare"}
+				}
+				id, err := repo.CreateTask(b.Context(), task)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := repo.UpdateTask(b.Context(), id, func(task *domain.Task) error { return nil }); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for b.Loop() {
+				if _, err := repo.ListTasks(b.Context(), q.query); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/Util787/task-manager/internal/infrastructure/repo/repotest"
+	"github.com/Util787/task-manager/pkg/logger/handlers/slogdiscard"
+)
+
+func TestTaskRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.TaskRepository {
+		return NewTaskRepository(slogdiscard.NewDiscardLogger())
+	})
+}
+
+func TestDurableTaskRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.TaskRepository {
+		repo := openTestRepository(t, testWALConfig(t.TempDir()))
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestShardedTaskRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.TaskRepository {
+		return NewShardedTaskRepository(8)
+	})
+}
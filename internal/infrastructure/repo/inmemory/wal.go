@@ -0,0 +1,425 @@
+package inmemory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+// The log is split into segments wal-<seq>.log, snapshot-<seq>.json holds all tasks as of the start of segment seq
+// and history-<seq>.json their histories. State is the latest snapshot plus every segment from its seq on, older files are removed
+// once a snapshot is written. Events of the outbox are logged with the change that stored them and rebuild the histories on replay,
+// a segment started by a snapshot begins with the events still pending
+const (
+	walPrefix      = "wal-"
+	walSuffix      = ".log"
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".json"
+	historyPrefix  = "history-"
+	historySuffix  = ".json"
+)
+
+type walOp string
+
+const (
+	walCreate    walOp = "create"
+	walUpdate    walOp = "update"
+	walDelete    walOp = "delete"
+	walOutbox    walOp = "outbox"    // replaces the pending events
+	walDelivered walOp = "delivered" // removes delivered events
+)
+
+type walRecord struct {
+	Op       walOp              `json:"op"`
+	Task     *domain.Task       `json:"task,omitempty"`
+	ID       uuid.UUID          `json:"id,omitempty"`
+	Events   []domain.TaskEvent `json:"events,omitempty"`
+	EventIDs []uuid.UUID        `json:"event_ids,omitempty"`
+}
+
+// walState is what the log rebuilds, the tasks, the events still in the outbox and the histories of tasks
+type walState struct {
+	tasks   map[uuid.UUID]*domain.Task
+	events  []domain.TaskEvent
+	history map[uuid.UUID][]domain.TaskHistoryEvent
+}
+
+// historyRecord is a line of the history file, the history of one task
+type historyRecord struct {
+	TaskID uuid.UUID                 `json:"task_id"`
+	Events []domain.TaskHistoryEvent `json:"events"`
+}
+
+var errWALCorrupted = errors.New("wal is corrupted")
+
+// journal appends records to the current segment, every line is "<crc32 of json> <json>" so a torn last line is detected on replay
+type journal struct {
+	cfg   WALConfig
+	file  *os.File
+	seq   int64
+	dirty bool // written but not synced
+	mu    sync.Mutex
+}
+
+func (j *journal) append(rec walRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line := fmt.Appendf(nil, "%08x %s\n", crc32.ChecksumIEEE(data), data)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// the line goes to the OS right away, policy only decides when it reaches the disk
+	if _, err := j.file.Write(line); err != nil {
+		return err
+	}
+	switch {
+	// a lost delivery mark only means the events are published again, it is not worth a sync of its own
+	case j.cfg.Fsync == FsyncAlways && rec.Op != walDelivered:
+		return j.file.Sync()
+	case j.cfg.Fsync != FsyncNone:
+		j.dirty = true
+	}
+	return nil
+}
+
+func (j *journal) sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.dirty {
+		return nil
+	}
+	j.dirty = false
+	return j.file.Sync()
+}
+
+// rotate closes the current segment and starts the next one, returning its seq
+func (j *journal) rotate() (int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	next, err := openSegment(j.cfg.Dir, j.seq+1)
+	if err != nil {
+		return 0, err
+	}
+	if err := j.file.Sync(); err != nil {
+		next.Close()
+		return 0, err
+	}
+	j.file.Close()
+
+	j.file = next
+	j.seq++
+	j.dirty = false
+	return j.seq, nil
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+// writeSnapshot stores tasks and their histories as snapshot seq and removes files it makes obsolete.
+// The history is written first, a snapshot is only used once both files are there
+func (j *journal) writeSnapshot(seq int64, tasks []domain.Task, history map[uuid.UUID][]domain.TaskHistoryEvent) error {
+	err := writeLines(j.cfg.Dir, fileName(historyPrefix, seq, historySuffix), func(encoder *json.Encoder) error {
+		for id, events := range history {
+			if err := encoder.Encode(historyRecord{TaskID: id, Events: events}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = writeLines(j.cfg.Dir, fileName(snapshotPrefix, seq, snapshotSuffix), func(encoder *json.Encoder) error {
+		for i := range tasks {
+			if err := encoder.Encode(&tasks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshots, segments, err := listFiles(j.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for _, old := range snapshots {
+		if old < seq {
+			os.Remove(filepath.Join(j.cfg.Dir, fileName(snapshotPrefix, old, snapshotSuffix)))
+		}
+	}
+	for _, old := range segments {
+		if old < seq {
+			os.Remove(filepath.Join(j.cfg.Dir, fileName(walPrefix, old, walSuffix)))
+		}
+	}
+	// histories are removed by name, so the ones of snapshots that failed to be written go as well
+	entries, err := os.ReadDir(j.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if old, ok := parseFileName(entry.Name(), historyPrefix, historySuffix); ok && old < seq {
+			os.Remove(filepath.Join(j.cfg.Dir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// writeLines writes the file through a temporary one, so a crash never leaves it half written
+func writeLines(dir, name string, write func(encoder *json.Encoder) error) error {
+	tmp, err := os.CreateTemp(dir, name+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := write(json.NewEncoder(w)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// openJournal rebuilds tasks, pending events and histories from the latest snapshot and the log after it, then starts a new segment for writes
+func openJournal(cfg WALConfig) (*journal, *walState, error) {
+	if cfg.Fsync != FsyncAlways && cfg.Fsync != FsyncBatched && cfg.Fsync != FsyncNone {
+		return nil, nil, fmt.Errorf("invalid wal fsync policy: %s, must be %s, %s or %s", cfg.Fsync, FsyncAlways, FsyncBatched, FsyncNone)
+	}
+	if cfg.Fsync == FsyncBatched && cfg.FsyncInterval <= 0 {
+		return nil, nil, fmt.Errorf("invalid wal fsync interval: %s, must be positive", cfg.FsyncInterval)
+	}
+	if cfg.SnapshotInterval <= 0 {
+		return nil, nil, fmt.Errorf("invalid wal snapshot interval: %s, must be positive", cfg.SnapshotInterval)
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+
+	snapshots, segments, err := listFiles(cfg.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state := &walState{tasks: make(map[uuid.UUID]*domain.Task), history: make(map[uuid.UUID][]domain.TaskHistoryEvent)}
+	var base, last int64
+	if len(snapshots) > 0 {
+		base = snapshots[len(snapshots)-1]
+		last = base
+		if err := loadSnapshot(filepath.Join(cfg.Dir, fileName(snapshotPrefix, base, snapshotSuffix)), state.tasks); err != nil {
+			return nil, nil, err
+		}
+		if err := loadHistory(filepath.Join(cfg.Dir, fileName(historyPrefix, base, historySuffix)), state.history); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for i, seq := range segments {
+		last = max(last, seq)
+		if seq < base {
+			continue
+		}
+		isLast := i == len(segments)-1
+		if err := replaySegment(filepath.Join(cfg.Dir, fileName(walPrefix, seq, walSuffix)), state, isLast); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	file, err := openSegment(cfg.Dir, last+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &journal{cfg: cfg, file: file, seq: last + 1}, state, nil
+}
+
+func loadSnapshot(path string, tasks map[uuid.UUID]*domain.Task) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var task domain.Task
+		if err := decoder.Decode(&task); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("%w: snapshot %s: %v", errWALCorrupted, path, err)
+		}
+		if task.Version == 0 {
+			task.Version = 1 // written before tasks had versions
+		}
+		tasks[task.ID] = &task
+	}
+}
+
+// loadHistory reads the histories of the snapshot, a snapshot written before histories were kept has none
+func loadHistory(path string, history map[uuid.UUID][]domain.TaskHistoryEvent) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var rec historyRecord
+		if err := decoder.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("%w: history %s: %v", errWALCorrupted, path, err)
+		}
+		history[rec.TaskID] = rec.Events
+	}
+}
+
+// replaySegment applies records of the segment to tasks. A broken line at the end of the last segment is a write
+// torn by a crash, it is cut off, anywhere else it means the log is damaged
+func replaySegment(path string, state *walState, isLast bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var offset int
+	for offset < len(data) {
+		end := bytes.IndexByte(data[offset:], '\n')
+		var rec walRecord
+		var ok bool
+		if end >= 0 {
+			rec, ok = parseRecord(data[offset : offset+end])
+		}
+		if !ok {
+			if isLast {
+				return os.Truncate(path, int64(offset))
+			}
+			return fmt.Errorf("%w: segment %s at offset %d", errWALCorrupted, path, offset)
+		}
+		state.apply(rec)
+		offset += end + 1
+	}
+	return nil
+}
+
+func parseRecord(line []byte) (walRecord, bool) {
+	sum, data, found := bytes.Cut(line, []byte{' '})
+	if !found {
+		return walRecord{}, false
+	}
+	var expected uint32
+	if _, err := fmt.Sscanf(string(sum), "%08x", &expected); err != nil || crc32.ChecksumIEEE(data) != expected {
+		return walRecord{}, false
+	}
+	var rec walRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return walRecord{}, false
+	}
+	return rec, true
+}
+
+func (s *walState) apply(rec walRecord) {
+	switch rec.Op {
+	case walCreate, walUpdate:
+		if rec.Task.Version == 0 {
+			rec.Task.Version = 1 // written before tasks had versions
+		}
+		s.tasks[rec.Task.ID] = rec.Task
+		s.events = append(s.events, rec.Events...)
+		appendHistory(s.history, rec.Events)
+	case walDelete:
+		delete(s.tasks, rec.ID)
+		delete(s.history, rec.ID)
+		s.events = append(s.events, rec.Events...)
+	case walOutbox:
+		s.events = rec.Events
+	case walDelivered:
+		s.events = slices.DeleteFunc(s.events, func(event domain.TaskEvent) bool {
+			return slices.Contains(rec.EventIDs, event.ID)
+		})
+	}
+}
+
+func openSegment(dir string, seq int64) (*os.File, error) {
+	return os.OpenFile(filepath.Join(dir, fileName(walPrefix, seq, walSuffix)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// listFiles returns sorted seqs of snapshots and segments in dir
+func listFiles(dir string) (snapshots, segments []int64, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	for _, entry := range entries {
+		if seq, ok := parseFileName(entry.Name(), snapshotPrefix, snapshotSuffix); ok {
+			snapshots = append(snapshots, seq)
+		}
+		if seq, ok := parseFileName(entry.Name(), walPrefix, walSuffix); ok {
+			segments = append(segments, seq)
+		}
+	}
+	slices.Sort(snapshots)
+	slices.Sort(segments)
+	return snapshots, segments, nil
+}
+
+func fileName(prefix string, seq int64, suffix string) string {
+	// zero padded so names sort like seqs
+	return fmt.Sprintf("%s%020d%s", prefix, seq, suffix)
+}
+
+func parseFileName(name, prefix, suffix string) (int64, bool) {
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return 0, false
+	}
+	var seq int64
+	if _, err := fmt.Sscanf(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix), "%d", &seq); err != nil {
+		return 0, false
+	}
+	return seq, true
+}
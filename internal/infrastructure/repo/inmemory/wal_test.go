@@ -0,0 +1,238 @@
+package inmemory
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/pkg/logger/handlers/slogdiscard"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWALConfig(dir string) WALConfig {
+	return WALConfig{
+		Enabled:          true,
+		Dir:              dir,
+		Fsync:            FsyncAlways,
+		FsyncInterval:    10 * time.Millisecond,
+		SnapshotInterval: time.Hour,
+	}
+}
+
+func openTestRepository(t *testing.T, cfg WALConfig) *TaskRepository {
+	t.Helper()
+
+	repo, err := NewDurableTaskRepository(slogdiscard.NewDiscardLogger(), cfg)
+	require.NoError(t, err)
+	return repo
+}
+
+// crash stops the repository without the final snapshot
+func crash(t *testing.T, repo *TaskRepository) {
+	t.Helper()
+
+	close(repo.stop)
+	repo.wg.Wait()
+	require.NoError(t, repo.journal.close())
+}
+
+func TestDurableTaskRepository_RestoresAfterReopen(t *testing.T) {
+	cfg := testWALConfig(t.TempDir())
+	repo := openTestRepository(t, cfg)
+
+	kept, err := repo.CreateTask(t.Context(), &domain.Task{Title: "kept", Labels: []string{"billing"}, TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+	deleted, err := repo.CreateTask(t.Context(), &domain.Task{Title: "deleted"})
+	require.NoError(t, err)
+
+	_, err = repo.UpdateTask(t.Context(), kept, func(task *domain.Task) error {
+		task.TaskState.Status = domain.StatusCompleted
+		task.Result = []byte(`{"total":42}`)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTask(t.Context(), deleted, domain.AnyVersion))
+	want, err := repo.GetTaskByID(t.Context(), kept)
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	repo = openTestRepository(t, cfg)
+	defer repo.Close()
+
+	got, err := repo.GetTaskByID(t.Context(), kept)
+	require.NoError(t, err)
+	assert.Equal(t, want.Title, got.Title)
+	assert.Equal(t, want.Labels, got.Labels)
+	assert.Equal(t, domain.StatusCompleted, got.TaskState.Status)
+	assert.JSONEq(t, `{"total":42}`, string(got.Result))
+	assert.True(t, want.UpdatedAt.Equal(got.UpdatedAt))
+
+	_, err = repo.GetTaskByID(t.Context(), deleted)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+}
+
+func TestDurableTaskRepository_ReplaysLogAfterSnapshot(t *testing.T) {
+	cfg := testWALConfig(t.TempDir())
+	cfg.Fsync = FsyncBatched
+	repo := openTestRepository(t, cfg)
+
+	before, err := repo.CreateTask(t.Context(), &domain.Task{Title: "before snapshot"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Snapshot())
+	after, err := repo.CreateTask(t.Context(), &domain.Task{Title: "after snapshot"})
+	require.NoError(t, err)
+
+	// only the log knows about the second task
+	crash(t, repo)
+	snapshots, segments, err := listFiles(cfg.Dir)
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, snapshots[0], segments[0], "log before the snapshot is removed")
+
+	repo = openTestRepository(t, cfg)
+	defer repo.Close()
+
+	for _, id := range []uuid.UUID{before, after} {
+		_, err := repo.GetTaskByID(t.Context(), id)
+		assert.NoError(t, err)
+	}
+}
+
+func TestDurableTaskRepository_KeepsPendingEvents(t *testing.T) {
+	cfg := testWALConfig(t.TempDir())
+	repo := openTestRepository(t, cfg)
+
+	delivered := domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{})
+	pending := domain.NewTaskEvent(domain.EventTaskStarted, domain.Task{})
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "before snapshot"}, delivered, pending)
+	require.NoError(t, err)
+	require.NoError(t, repo.MarkEventsDelivered(t.Context(), []uuid.UUID{delivered.ID}))
+	// the snapshot holds no events, the new segment starts with the pending ones
+	require.NoError(t, repo.Snapshot())
+	progress := domain.NewTaskEvent(domain.EventTaskProgress, domain.Task{})
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.TaskState.Progress = 40
+		return nil
+	}, progress)
+	require.NoError(t, err)
+
+	crash(t, repo)
+	repo = openTestRepository(t, cfg)
+	defer repo.Close()
+
+	events, err := repo.ListPendingEvents(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, pending.ID, events[0].ID)
+	assert.Equal(t, id, events[0].Task.ID)
+	assert.Equal(t, progress.ID, events[1].ID)
+	assert.Equal(t, 40, events[1].Task.TaskState.Progress)
+}
+
+func TestDurableTaskRepository_KeepsHistory(t *testing.T) {
+	cfg := testWALConfig(t.TempDir())
+	repo := openTestRepository(t, cfg)
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "kept"}, domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{}))
+	require.NoError(t, err)
+	purged, err := repo.CreateTask(t.Context(), &domain.Task{Title: "purged"}, domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{}))
+	require.NoError(t, err)
+	// the history before the snapshot is read from the snapshot, the rest is replayed from the log
+	require.NoError(t, repo.Snapshot())
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.TaskState.Progress = 40
+		return nil
+	}, domain.NewTaskEvent(domain.EventTaskProgress, domain.Task{}))
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTask(t.Context(), purged, domain.AnyVersion, domain.NewTaskEvent(domain.EventTaskPurged, domain.Task{ID: purged})))
+
+	crash(t, repo)
+	repo = openTestRepository(t, cfg)
+
+	events, err := repo.ReadTaskHistory(t.Context(), id)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.EventTaskCreated, events[0].Type)
+	assert.Equal(t, domain.EventTaskProgress, events[1].Type)
+	assert.Equal(t, int64(2), events[1].Seq)
+	assert.Equal(t, 40, events[1].Progress)
+	events, err = repo.ReadTaskHistory(t.Context(), purged)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// the final snapshot holds the whole history
+	require.NoError(t, repo.Close())
+	repo = openTestRepository(t, cfg)
+	defer repo.Close()
+
+	events, err = repo.ReadTaskHistory(t.Context(), id)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestDurableTaskRepository_CutsTornTail(t *testing.T) {
+	cfg := testWALConfig(t.TempDir())
+	cfg.Fsync = FsyncNone
+	repo := openTestRepository(t, cfg)
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "complete"})
+	require.NoError(t, err)
+	path := repo.journal.file.Name()
+	crash(t, repo)
+
+	// a write cut in the middle by a crash
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`0badf00d {"op":"create","task":{"id":`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	repo = openTestRepository(t, cfg)
+	_, err = repo.GetTaskByID(t.Context(), id)
+	assert.NoError(t, err)
+	_, err = repo.CreateTask(t.Context(), &domain.Task{Title: "written after recovery"})
+	require.NoError(t, err)
+	crash(t, repo)
+
+	// the torn line is gone, so the segment is no longer the last one without breaking the next recovery
+	repo = openTestRepository(t, cfg)
+	defer repo.Close()
+	assert.Len(t, repo.tasks, 2)
+}
+
+func TestDurableTaskRepository_CorruptedSegment(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileName(walPrefix, 1, walSuffix)), []byte("garbage\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileName(walPrefix, 2, walSuffix)), nil, 0o644))
+
+	_, err := NewDurableTaskRepository(slogdiscard.NewDiscardLogger(), testWALConfig(dir))
+	assert.True(t, errors.Is(err, errWALCorrupted))
+}
+
+func TestDurableTaskRepository_InvalidFsyncPolicy(t *testing.T) {
+	cfg := testWALConfig(t.TempDir())
+	cfg.Fsync = "sometimes"
+
+	_, err := NewDurableTaskRepository(slogdiscard.NewDiscardLogger(), cfg)
+	assert.ErrorContains(t, err, "invalid wal fsync policy")
+}
+
+func TestDurableTaskRepository_InvalidIntervals(t *testing.T) {
+	cfg := testWALConfig(t.TempDir())
+	cfg.Fsync = FsyncBatched
+	cfg.FsyncInterval = 0
+
+	_, err := NewDurableTaskRepository(slogdiscard.NewDiscardLogger(), cfg)
+	assert.ErrorContains(t, err, "invalid wal fsync interval")
+
+	cfg = testWALConfig(t.TempDir())
+	cfg.SnapshotInterval = -time.Second
+
+	_, err = NewDurableTaskRepository(slogdiscard.NewDiscardLogger(), cfg)
+	assert.ErrorContains(t, err, "invalid wal snapshot interval")
+}
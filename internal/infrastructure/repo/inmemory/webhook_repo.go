@@ -0,0 +1,186 @@
+package inmemory
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+// SubscriptionRepository keeps subscriptions only in memory of this node, whatever the storage driver of tasks
+type SubscriptionRepository struct {
+	subscriptions map[uuid.UUID]*domain.WebhookSubscription
+	mu            sync.RWMutex
+}
+
+func NewSubscriptionRepository() *SubscriptionRepository {
+	return &SubscriptionRepository{
+		subscriptions: make(map[uuid.UUID]*domain.WebhookSubscription),
+	}
+}
+
+// CreateSubscription sets id and timestamps of sub and stores a copy of it, later changes of sub are not seen by the repository
+func (r *SubscriptionRepository) CreateSubscription(sub *domain.WebhookSubscription) uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+
+	id := uuid.New()
+	sub.ID = id
+
+	stored := *sub
+	stored.Events = slices.Clone(sub.Events)
+	stored.Types = slices.Clone(sub.Types)
+	stored.Labels = slices.Clone(sub.Labels)
+	r.subscriptions[id] = &stored
+	return id
+}
+
+func (r *SubscriptionRepository) GetSubscriptionByID(id uuid.UUID) (domain.WebhookSubscription, error) {
+	const op = "SubscriptionRepository.GetSubscriptionByID"
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, exists := r.subscriptions[id]
+	if !exists {
+		return domain.WebhookSubscription{}, fmt.Errorf("%s: %w", op, domain.ErrSubscriptionNotFound)
+	}
+
+	return *sub, nil
+}
+
+// ListSubscriptions returns subscriptions ordered by creation time
+func (r *SubscriptionRepository) ListSubscriptions() []domain.WebhookSubscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := make([]domain.WebhookSubscription, 0, len(r.subscriptions))
+	for _, sub := range r.subscriptions {
+		subs = append(subs, *sub)
+	}
+	slices.SortFunc(subs, func(a, b domain.WebhookSubscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return subs
+}
+
+// UpdateSubscription applies update to the stored subscription under the write lock, if update returns an error the subscription is left untouched
+func (r *SubscriptionRepository) UpdateSubscription(id uuid.UUID, update func(sub *domain.WebhookSubscription) error) (domain.WebhookSubscription, error) {
+	const op = "SubscriptionRepository.UpdateSubscription"
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, exists := r.subscriptions[id]
+	if !exists {
+		return domain.WebhookSubscription{}, fmt.Errorf("%s: %w", op, domain.ErrSubscriptionNotFound)
+	}
+
+	updated := *sub
+	if err := update(&updated); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+	updated.UpdatedAt = time.Now()
+
+	*sub = updated
+	return updated, nil
+}
+
+func (r *SubscriptionRepository) DeleteSubscription(id uuid.UUID) error {
+	const op = "SubscriptionRepository.DeleteSubscription"
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.subscriptions[id]; !exists {
+		return fmt.Errorf("%s: %w", op, domain.ErrSubscriptionNotFound)
+	}
+
+	delete(r.subscriptions, id)
+	return nil
+}
+
+// WebhookDeliveryRepository keeps the latest cfg.MaxAttempts delivery attempts of every subscription for cfg.Retention,
+// attempts live only in memory of this node
+type WebhookDeliveryRepository struct {
+	cfg            DeliveryLogConfig
+	deliveries     map[uuid.UUID]domain.WebhookDelivery
+	bySubscription map[uuid.UUID][]uuid.UUID
+	lastSweep      time.Time
+	mu             sync.RWMutex
+}
+
+func NewWebhookDeliveryRepository(cfg DeliveryLogConfig) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		cfg:            cfg,
+		deliveries:     make(map[uuid.UUID]domain.WebhookDelivery),
+		bySubscription: make(map[uuid.UUID][]uuid.UUID),
+	}
+}
+
+func (r *WebhookDeliveryRepository) SaveWebhookDelivery(delivery domain.WebhookDelivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries[delivery.ID] = delivery
+	ids := append(r.bySubscription[delivery.SubscriptionID], delivery.ID)
+	if over := len(ids) - r.cfg.MaxAttempts; over > 0 {
+		for _, id := range ids[:over] {
+			delete(r.deliveries, id)
+		}
+		ids = append(ids[:0:0], ids[over:]...)
+	}
+	r.bySubscription[delivery.SubscriptionID] = ids
+
+	// subscriptions that are not delivered to anymore are swept by later writes, ten times per retention at most
+	now := time.Now()
+	if now.Sub(r.lastSweep) >= r.cfg.Retention/10 {
+		r.lastSweep = now
+		before := now.Add(-r.cfg.Retention)
+		for subID, ids := range r.bySubscription {
+			i := 0
+			for i < len(ids) && r.deliveries[ids[i]].AttemptedAt.Before(before) {
+				delete(r.deliveries, ids[i])
+				i++
+			}
+			if i == len(ids) {
+				delete(r.bySubscription, subID)
+			} else if i > 0 {
+				r.bySubscription[subID] = append(ids[:0:0], ids[i:]...)
+			}
+		}
+	}
+}
+
+func (r *WebhookDeliveryRepository) GetWebhookDeliveryByID(id uuid.UUID) (domain.WebhookDelivery, error) {
+	const op = "WebhookDeliveryRepository.GetWebhookDeliveryByID"
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, exists := r.deliveries[id]
+	if !exists || delivery.AttemptedAt.Before(time.Now().Add(-r.cfg.Retention)) {
+		return domain.WebhookDelivery{}, fmt.Errorf("%s: %w", op, domain.ErrWebhookDeliveryNotFound)
+	}
+
+	return delivery, nil
+}
+
+// GetWebhookDeliveriesBySubscriptionID returns deliveries of the subscription in the order they were attempted
+func (r *WebhookDeliveryRepository) GetWebhookDeliveriesBySubscriptionID(subID uuid.UUID) []domain.WebhookDelivery {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	before := time.Now().Add(-r.cfg.Retention)
+	ids := r.bySubscription[subID]
+	deliveries := make([]domain.WebhookDelivery, 0, len(ids))
+	for _, id := range ids {
+		if delivery := r.deliveries[id]; !delivery.AttemptedAt.Before(before) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
@@ -0,0 +1,64 @@
+package inmemory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionRepository_CreateStoresCopy(t *testing.T) {
+	repo := NewSubscriptionRepository()
+
+	sub := &domain.WebhookSubscription{URL: "https://example.com/hook", Labels: []string{"billing"}, Active: true}
+	id := repo.CreateSubscription(sub)
+	assert.Equal(t, id, sub.ID)
+
+	// changes of the caller's value don't reach the stored subscription
+	sub.URL = "https://attacker.example.com/hook"
+	sub.Labels[0] = "other"
+	sub.Active = false
+
+	stored, err := repo.GetSubscriptionByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/hook", stored.URL)
+	assert.Equal(t, []string{"billing"}, stored.Labels)
+	assert.True(t, stored.Active)
+}
+
+func TestWebhookDeliveryRepository_CapsAndExpires(t *testing.T) {
+	repo := NewWebhookDeliveryRepository(DeliveryLogConfig{MaxAttempts: 2, Retention: time.Hour})
+	now := time.Now()
+
+	subID := uuid.New()
+	first := domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: subID, Attempt: 1, AttemptedAt: now}
+	repo.SaveWebhookDelivery(first)
+	for attempt := 2; attempt <= 3; attempt++ {
+		repo.SaveWebhookDelivery(domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: subID, Attempt: attempt, AttemptedAt: now})
+	}
+
+	// only the latest attempts are kept, dropped ones can't be replayed
+	deliveries := repo.GetWebhookDeliveriesBySubscriptionID(subID)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.Equal(t, 3, deliveries[1].Attempt)
+	_, err := repo.GetWebhookDeliveryByID(first.ID)
+	assert.ErrorIs(t, err, domain.ErrWebhookDeliveryNotFound)
+
+	// attempts older than the retention are not found and are swept with their subscription
+	expiredSubID := uuid.New()
+	expired := domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: expiredSubID, Attempt: 1, AttemptedAt: now.Add(-2 * time.Hour)}
+	repo.SaveWebhookDelivery(expired)
+	assert.Empty(t, repo.GetWebhookDeliveriesBySubscriptionID(expiredSubID))
+	_, err = repo.GetWebhookDeliveryByID(expired.ID)
+	assert.ErrorIs(t, err, domain.ErrWebhookDeliveryNotFound)
+
+	repo.lastSweep = time.Time{}
+	repo.SaveWebhookDelivery(domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: subID, Attempt: 4, AttemptedAt: now})
+	assert.NotContains(t, repo.bySubscription, expiredSubID)
+	assert.NotContains(t, repo.deliveries, expired.ID)
+	assert.Len(t, repo.deliveries, 2)
+}
@@ -0,0 +1,191 @@
+// Package migrate applies versioned schema migrations of SQL backends. Migrations are embedded in the binary as
+// <version>_<name>.up.sql and <version>_<name>.down.sql files, applied versions are recorded by the backend driver
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrSchemaBehind        = errors.New("database schema is behind, run migrate up or start with auto-migrate")
+	ErrNoMigrationsApplied = errors.New("no migrations applied")
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time // nil when not applied
+}
+
+// Driver records applied versions in the database, Apply and Revert run the script and change the record in one transaction
+type Driver interface {
+	Init(ctx context.Context) error
+	Applied(ctx context.Context) (map[int]time.Time, error)
+	Apply(ctx context.Context, version int, script string) error
+	Revert(ctx context.Context, version int, script string) error
+}
+
+type Migrator struct {
+	driver     Driver
+	migrations []Migration // ordered by version
+}
+
+// New loads migrations from the root of fsys, every migration must have both up and down scripts
+func New(driver Driver, fsys fs.FS) (*Migrator, error) {
+	const op = "migrate.New"
+
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &Migrator{driver: driver, migrations: migrations}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have up and down scripts", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// Up applies all pending migrations in version order and returns them
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	const op = "Migrator.Up"
+
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i, migration := range pending {
+		if err := m.driver.Apply(ctx, migration.Version, migration.Up); err != nil {
+			return pending[:i], fmt.Errorf("%s: migration %d_%s: %w", op, migration.Version, migration.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down reverts the latest applied migration and returns it
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	const op = "Migrator.Down"
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, status := range slices.Backward(statuses) {
+		if status.AppliedAt == nil {
+			continue
+		}
+		if err := m.driver.Revert(ctx, status.Version, status.Down); err != nil {
+			return Migration{}, fmt.Errorf("%s: migration %d_%s: %w", op, status.Version, status.Name, err)
+		}
+		return status.Migration, nil
+	}
+	return Migration{}, fmt.Errorf("%s: %w", op, ErrNoMigrationsApplied)
+}
+
+// Status returns every known migration with the time it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	const op = "Migrator.Status"
+
+	if err := m.driver.Init(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	applied, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind when some of the migrations are not applied yet
+func (m *Migrator) Check(ctx context.Context) error {
+	const op = "Migrator.Check"
+
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%s: %w: %d pending, latest is %d_%s", op, ErrSchemaBehind, len(pending), pending[len(pending)-1].Version, pending[len(pending)-1].Name)
+	}
+	return nil
+}
+
+func (m *Migrator) pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// driverStub keeps applied versions in memory and records executed scripts
+type driverStub struct {
+	applied map[int]time.Time
+	scripts []string
+}
+
+func (d *driverStub) Init(ctx context.Context) error { return nil }
+
+func (d *driverStub) Applied(ctx context.Context) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time, len(d.applied))
+	for version, appliedAt := range d.applied {
+		applied[version] = appliedAt
+	}
+	return applied, nil
+}
+
+func (d *driverStub) Apply(ctx context.Context, version int, script string) error {
+	d.applied[version] = time.Now()
+	d.scripts = append(d.scripts, script)
+	return nil
+}
+
+func (d *driverStub) Revert(ctx context.Context, version int, script string) error {
+	delete(d.applied, version)
+	d.scripts = append(d.scripts, script)
+	return nil
+}
+
+func TestMigrator_AppliesInVersionOrder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_add_index.up.sql":      {Data: []byte("up 10")},
+		"0010_add_index.down.sql":    {Data: []byte("down 10")},
+		"0002_create_tasks.up.sql":   {Data: []byte("up 2")},
+		"0002_create_tasks.down.sql": {Data: []byte("down 2")},
+		"README.md":                  {Data: []byte("not a migration")},
+	}
+	driver := &driverStub{applied: map[int]time.Time{2: time.Now()}}
+	migrator, err := New(driver, fsys)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaBehind)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, 10, applied[0].Version)
+	assert.Equal(t, "add_index", applied[0].Name)
+
+	reverted, err := migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 10, reverted.Version)
+	assert.Equal(t, []string{"up 10", "down 10"}, driver.scripts)
+}
+
+func TestNew_MissingDownScript(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_tasks.up.sql": {Data: []byte("up")},
+	}
+
+	_, err := New(&driverStub{}, fsys)
+	assert.ErrorContains(t, err, "must have up and down scripts")
+}
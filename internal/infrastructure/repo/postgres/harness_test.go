@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// testDSN is set by TestMain when a local postgres was started, tests are skipped otherwise
+var (
+	testDSN     string
+	skipReason  string
+	postgresCmd *exec.Cmd
+)
+
+// TestMain starts a throwaway postgres from the local binaries (PG_BIN dir or PATH) in a temp dir
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "task-manager-pg-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := startPostgres(dir); err != nil {
+		skipReason = err.Error()
+	}
+
+	code := m.Run()
+
+	if postgresCmd != nil {
+		_ = postgresCmd.Process.Signal(os.Interrupt)
+		_ = postgresCmd.Wait()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func startPostgres(dir string) error {
+	initdb, err := findBinary("initdb")
+	if err != nil {
+		return err
+	}
+	server, err := findBinary("postgres")
+	if err != nil {
+		return err
+	}
+	if os.Geteuid() == 0 {
+		return fmt.Errorf("postgres can not be run as root")
+	}
+
+	dataDir := filepath.Join(dir, "data")
+	out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "--auth=trust", "-E", "UTF8").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("initdb: %w: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return err
+	}
+	postgresCmd = exec.Command(server, "-D", dataDir, "-p", fmt.Sprint(port), "-k", dir,
+		"-c", "listen_addresses=127.0.0.1", "-c", "fsync=off", "-c", "synchronous_commit=off")
+	if err := postgresCmd.Start(); err != nil {
+		postgresCmd = nil
+		return fmt.Errorf("start postgres: %w", err)
+	}
+
+	dsn := fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		conn, err := pgx.Connect(ctx, dsn)
+		cancel()
+		if err == nil {
+			conn.Close(context.Background())
+			testDSN = dsn
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgres did not start: %w", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func findBinary(name string) (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	// debian and ubuntu keep server binaries out of PATH
+	if matches, _ := filepath.Glob("/usr/lib/postgresql/*/bin/" + name); len(matches) > 0 {
+		return matches[len(matches)-1], nil
+	}
+	return "", fmt.Errorf("%s not found, set PG_BIN to the postgres bin dir", name)
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// newTestRepository returns a repository on the test postgres with an empty tasks table
+func newTestRepository(t *testing.T) *TaskRepository {
+	t.Helper()
+	if testDSN == "" {
+		t.Skip("postgres is not available: " + skipReason)
+	}
+
+	repo, err := NewTaskRepository(Config{
+		DSN:               testDSN,
+		MaxConns:          4,
+		MinConns:          1,
+		MaxConnLifetime:   time.Hour,
+		MaxConnIdleTime:   time.Minute,
+		HealthCheckPeriod: time.Minute,
+		ConnectTimeout:    5 * time.Second,
+		StatementTimeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	if _, err := repo.Migrator().Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.pool.Exec(context.Background(), `TRUNCATE tasks, outbox, task_history`); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
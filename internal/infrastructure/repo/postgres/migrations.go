@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrations, _ = fs.Sub(migrationFiles, "migrations")
+
+// migrationLockID is the advisory lock key that serializes migrations of replicas started at the same time
+const migrationLockID = 7870301
+
+// migrationDriver records applied versions in schema_migrations
+type migrationDriver struct {
+	pool *pgxpool.Pool
+}
+
+func (d migrationDriver) Init(ctx context.Context) error {
+	_, err := d.pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL)`)
+	return err
+}
+
+func (d migrationDriver) Applied(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := d.pool.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time)
+	var version int
+	var appliedAt time.Time
+	_, err = pgx.ForEachRow(rows, []any{&version, &appliedAt}, func() error {
+		applied[version] = appliedAt
+		return nil
+	})
+	return applied, err
+}
+
+func (d migrationDriver) Apply(ctx context.Context, version int, script string) error {
+	return d.inTx(ctx, version, true, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, now())`, version)
+		return err
+	})
+}
+
+func (d migrationDriver) Revert(ctx context.Context, version int, script string) error {
+	return d.inTx(ctx, version, false, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version)
+		return err
+	})
+}
+
+// inTx runs fn under the migration lock without the statement timeout, skipping it when another replica
+// has already brought the version to the wanted state
+func (d migrationDriver) inTx(ctx context.Context, version int, wantApplied bool, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, d.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `SET LOCAL statement_timeout = 0`); err != nil {
+			return err
+		}
+
+		var applied bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied); err != nil {
+			return err
+		}
+		if applied == wantApplied {
+			return nil
+		}
+		return fn(tx)
+	})
+}
@@ -0,0 +1,7 @@
+-- events of every task, seq keeps the order they were stored in
+CREATE TABLE IF NOT EXISTS task_history (
+	seq     BIGSERIAL PRIMARY KEY,
+	task_id UUID NOT NULL,
+	event   JSON NOT NULL
+);
+CREATE INDEX IF NOT EXISTS task_history_task_id ON task_history (task_id, seq);
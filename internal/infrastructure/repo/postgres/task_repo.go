@@ -0,0 +1,519 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/migrate"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const taskColumns = `id, title, description, type, labels, status, work_duration, progress, attempt, result, result_content_type,
+	result_blob_key, result_blob_size, callback_url, created_at, updated_at, completed_at, version, deleted_at`
+
+// TaskRepository keeps tasks in PostgreSQL, result is stored as json so it is returned byte for byte.
+// Timestamps are truncated to microseconds, the precision of timestamptz, before they are written
+type TaskRepository struct {
+	pool     *pgxpool.Pool
+	timeout  time.Duration
+	migrator *migrate.Migrator
+}
+
+func NewTaskRepository(cfg Config) (*TaskRepository, error) {
+	const op = "postgres.NewTaskRepository"
+
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	poolCfg.MaxConns = cfg.MaxConns
+	poolCfg.MinConns = cfg.MinConns
+	poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	poolCfg.MaxConnLifetimeJitter = cfg.MaxConnLifetime / 10 // connections are not recycled all at once
+	poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	poolCfg.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	migrator, err := migrate.New(migrationDriver{pool: pool}, migrations)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &TaskRepository{pool: pool, timeout: cfg.StatementTimeout, migrator: migrator}, nil
+}
+
+// Migrator manages the schema, the repository does not check it, callers must apply or check migrations first
+func (r *TaskRepository) Migrator() *migrate.Migrator {
+	return r.migrator
+}
+
+func (r *TaskRepository) Close() error {
+	r.pool.Close()
+	return nil
+}
+
+// CreateTask inserts the task and its events in one transaction
+func (r *TaskRepository) CreateTask(ctx context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error) {
+	const op = "TaskRepository.CreateTask"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	now := time.Now().Truncate(time.Microsecond)
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.Version = 1
+	task.ID = uuid.New()
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`,
+			taskArgs(*task)...)
+		if err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, domain.EventsWithTask(events, *task))
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return task.ID, nil
+}
+
+func (r *TaskRepository) GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error) {
+	const op = "TaskRepository.GetTaskByID"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	task, err := scanTask(r.pool.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1`, id))
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return task, nil
+}
+
+// UpdateTask locks the task row, applies update and writes the task back with the next version and its events in one transaction,
+// the write is guarded by the version that was read. If update returns an error the task is left untouched
+func (r *TaskRepository) UpdateTask(ctx context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error) {
+	const op = "TaskRepository.UpdateTask"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var task domain.Task
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		task, err = scanTask(tx.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1 FOR UPDATE`, id))
+		if err != nil {
+			return err
+		}
+		previous := task.Version
+		if err := update(&task); err != nil {
+			return err
+		}
+		task.ID = id
+		task.UpdatedAt = time.Now().Truncate(time.Microsecond)
+		task.Version = previous + 1
+
+		args := append(taskArgs(task), previous)
+		tag, err := tx.Exec(ctx, `UPDATE tasks SET title = $2, description = $3, type = $4, labels = $5, status = $6, work_duration = $7,
+			progress = $8, attempt = $9, result = $10, result_content_type = $11, result_blob_key = $12, result_blob_size = $13,
+			callback_url = $14, created_at = $15, updated_at = $16, completed_at = $17, version = $18, deleted_at = $19 WHERE id = $1 AND version = $20`, args...)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrVersionMismatch
+		}
+		truncateTimes(&task)
+		return insertEvents(ctx, tx, domain.EventsWithTask(events, task))
+	})
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return task, nil
+}
+
+// truncateTimes truncates the times set by update to the precision they are stored with
+func truncateTimes(task *domain.Task) {
+	if task.CompletedAt != nil {
+		completedAt := task.CompletedAt.Truncate(time.Microsecond)
+		task.CompletedAt = &completedAt
+	}
+	if task.DeletedAt != nil {
+		deletedAt := task.DeletedAt.Truncate(time.Microsecond)
+		task.DeletedAt = &deletedAt
+	}
+}
+
+// DeleteTask deletes the task and its history if it still has the version, domain.AnyVersion deletes it unconditionally.
+// The events are stored in the same transaction
+func (r *TaskRepository) DeleteTask(ctx context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error {
+	const op = "TaskRepository.DeleteTask"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM tasks WHERE id = $1 AND ($2 = 0 OR version = $2)`, id, version)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			// tell a missing task from a changed one
+			var exists bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1)`, id).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return domain.ErrTaskNotFound
+			}
+			return domain.ErrVersionMismatch
+		}
+		if err := insertEvents(ctx, tx, events); err != nil {
+			return err
+		}
+		// after the events, so the history goes with the task entirely
+		_, err = tx.Exec(ctx, `DELETE FROM task_history WHERE task_id = $1`, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ListDeletedTasks returns tasks in the trash ordered by deletion time
+func (r *TaskRepository) ListDeletedTasks(ctx context.Context) ([]domain.Task, error) {
+	const op = "TaskRepository.ListDeletedTasks"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, `SELECT `+taskColumns+` FROM tasks WHERE deleted_at IS NOT NULL ORDER BY deleted_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var tasks []domain.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tasks, nil
+}
+
+// ListTasks returns up to query.Limit tasks the query lists in its order, rows are read by the index of the sort column from the cursor on
+func (r *TaskRepository) ListTasks(ctx context.Context, query domain.TaskListQuery) ([]domain.Task, error) {
+	const op = "TaskRepository.ListTasks"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	where, args := listConditions(query)
+	column, order := listOrder(query.Sort)
+	args = append(args, query.Limit)
+	rows, err := r.pool.Query(ctx, `SELECT `+taskColumns+` FROM tasks WHERE `+where+` ORDER BY `+column+` `+order+`, id `+order+
+		` LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var tasks []domain.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tasks, nil
+}
+
+// WalkTasks calls fn with every stored task ordered by creation time while the rows are read, it stops at the first error of fn.
+// The walk is bounded by ctx only, not by the storage timeout, since it lasts as long as fn takes
+func (r *TaskRepository) WalkTasks(ctx context.Context, fn func(task domain.Task) error) error {
+	const op = "TaskRepository.WalkTasks"
+
+	rows, err := r.pool.Query(ctx, `SELECT `+taskColumns+` FROM tasks ORDER BY created_at, id`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := fn(task); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ImportTask stores the task as it is, id, timestamps and version included. If the id is already stored the task
+// replaces it when overwrite is set, otherwise domain.ErrTaskExists is returned
+func (r *TaskRepository) ImportTask(ctx context.Context, task domain.Task, overwrite bool) error {
+	const op = "TaskRepository.ImportTask"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	onConflict := `DO NOTHING`
+	if overwrite {
+		onConflict = `DO UPDATE SET title = excluded.title, description = excluded.description, type = excluded.type, labels = excluded.labels,
+		status = excluded.status, work_duration = excluded.work_duration, progress = excluded.progress, attempt = excluded.attempt,
+		result = excluded.result, result_content_type = excluded.result_content_type, result_blob_key = excluded.result_blob_key,
+		result_blob_size = excluded.result_blob_size, callback_url = excluded.callback_url, created_at = excluded.created_at,
+		updated_at = excluded.updated_at, completed_at = excluded.completed_at, version = excluded.version, deleted_at = excluded.deleted_at`
+	}
+	tag, err := r.pool.Exec(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		ON CONFLICT (id) `+onConflict, taskArgs(task)...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, domain.ErrTaskExists)
+	}
+	return nil
+}
+
+// ListPendingEvents returns up to limit events of the outbox in the order they were stored
+func (r *TaskRepository) ListPendingEvents(ctx context.Context, limit int) ([]domain.TaskEvent, error) {
+	const op = "TaskRepository.ListPendingEvents"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, `SELECT event FROM outbox ORDER BY seq LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.TaskEvent, error) {
+		var raw []byte
+		if err := row.Scan(&raw); err != nil {
+			return domain.TaskEvent{}, err
+		}
+		var event domain.TaskEvent
+		err := json.Unmarshal(raw, &event)
+		return event, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// MarkEventsDelivered removes the events from the outbox, ids that are not pending are ignored
+func (r *TaskRepository) MarkEventsDelivered(ctx context.Context, ids []uuid.UUID) error {
+	const op = "TaskRepository.MarkEventsDelivered"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, err := r.pool.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ReadTaskHistory returns the history of the task oldest first
+func (r *TaskRepository) ReadTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error) {
+	const op = "TaskRepository.ReadTaskHistory"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, `SELECT event FROM task_history WHERE task_id = $1 ORDER BY seq`, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.TaskHistoryEvent, error) {
+		var raw []byte
+		if err := row.Scan(&raw); err != nil {
+			return domain.TaskHistoryEvent{}, err
+		}
+		var event domain.TaskHistoryEvent
+		err := json.Unmarshal(raw, &event)
+		return event, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for i := range events {
+		events[i].Seq = int64(i + 1)
+	}
+	return events, nil
+}
+
+// insertEvents appends the events to the outbox and to the history of their task within the transaction of the change
+func insertEvents(ctx context.Context, tx pgx.Tx, events []domain.TaskEvent) error {
+	for _, event := range events {
+		raw, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO outbox (id, event) VALUES ($1, $2)`, event.ID, string(raw)); err != nil {
+			return err
+		}
+		entry, err := json.Marshal(domain.NewTaskHistoryEvent(event))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO task_history (task_id, event) VALUES ($1, $2)`, event.Task.ID, string(entry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listConditions returns the WHERE clause of the query and its args
+func listConditions(query domain.TaskListQuery) (string, []any) {
+	conditions := []string{`deleted_at IS NULL`}
+	var args []any
+	add := func(condition string, values ...any) {
+		placeholders := make([]any, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			placeholders[i] = len(args)
+		}
+		conditions = append(conditions, fmt.Sprintf(condition, placeholders...))
+	}
+	bound := func(condition string, t time.Time) {
+		if !t.IsZero() {
+			add(condition, t)
+		}
+	}
+
+	filter := query.Filter
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		add(`status = ANY($%d)`, statuses)
+	}
+	if len(filter.Types) > 0 {
+		add(`type = ANY($%d)`, filter.Types)
+	}
+	if len(filter.Labels) > 0 {
+		add(`labels && $%d`, filter.Labels)
+	}
+	bound(`created_at >= $%d`, filter.CreatedFrom)
+	bound(`created_at < $%d`, filter.CreatedTo)
+	bound(`updated_at >= $%d`, filter.UpdatedFrom)
+	bound(`updated_at < $%d`, filter.UpdatedTo)
+
+	if query.After != nil {
+		column, _ := listOrder(query.Sort)
+		comparison := `>`
+		if query.Sort.Desc() {
+			comparison = `<`
+		}
+		// uuids compare by their bytes, like ids of the domain order
+		add(`(`+column+`, id) `+comparison+` ($%d, $%d)`, query.After.At, query.After.ID)
+	}
+	return strings.Join(conditions, ` AND `), args
+}
+
+func listOrder(sort domain.TaskSort) (column, order string) {
+	column, order = `created_at`, `ASC`
+	if sort.ByUpdate() {
+		column = `updated_at`
+	}
+	if sort.Desc() {
+		order = `DESC`
+	}
+	return column, order
+}
+
+func scanTask(row pgx.Row) (domain.Task, error) {
+	var (
+		task     domain.Task
+		result   []byte
+		blobKey  *string
+		blobSize *int64
+	)
+	err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Type, &task.Labels, &task.TaskState.Status, &task.TaskState.WorkDuration,
+		&task.TaskState.Progress, &task.Attempt, &result, &task.ResultContentType, &blobKey, &blobSize, &task.CallbackURL,
+		&task.CreatedAt, &task.UpdatedAt, &task.CompletedAt, &task.Version, &task.DeletedAt)
+	if err != nil {
+		return domain.Task{}, notFound(err)
+	}
+
+	if len(task.Labels) == 0 {
+		task.Labels = nil
+	}
+	if len(result) > 0 {
+		task.Result = result
+	}
+	if blobKey != nil && blobSize != nil {
+		task.ResultBlob = &domain.ResultBlob{Key: *blobKey, Size: *blobSize}
+	}
+	return task, nil
+}
+
+// taskArgs returns column values of the task in taskColumns order
+func taskArgs(task domain.Task) []any {
+	labels := task.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+
+	var result, blobKey, blobSize, completedAt, deletedAt any
+	if len(task.Result) > 0 {
+		result = string(task.Result)
+	}
+	if task.ResultBlob != nil {
+		blobKey = task.ResultBlob.Key
+		blobSize = task.ResultBlob.Size
+	}
+	if task.CompletedAt != nil {
+		completedAt = task.CompletedAt.Truncate(time.Microsecond)
+	}
+	if task.DeletedAt != nil {
+		deletedAt = task.DeletedAt.Truncate(time.Microsecond)
+	}
+
+	return []any{
+		task.ID, task.Title, task.Description, task.Type, labels, string(task.TaskState.Status),
+		int64(task.TaskState.WorkDuration), task.TaskState.Progress, task.Attempt, result, task.ResultContentType,
+		blobKey, blobSize, task.CallbackURL, task.CreatedAt.Truncate(time.Microsecond), task.UpdatedAt.Truncate(time.Microsecond), completedAt, task.Version, deletedAt,
+	}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrTaskNotFound
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.TaskRepository {
+		return newTestRepository(t)
+	})
+}
+
+func TestTaskRepository_RoundTrip(t *testing.T) {
+	repo := newTestRepository(t)
+
+	task := &domain.Task{
+		Title:       "Test Task",
+		Description: "Test Description",
+		Type:        "report",
+		Labels:      []string{"billing", "nightly"},
+		TaskState:   domain.TaskState{Status: domain.StatusInProgress},
+		Attempt:     1,
+		CallbackURL: "https://example.com/hook",
+	}
+	id, err := repo.CreateTask(t.Context(), task)
+	require.NoError(t, err)
+
+	got, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, id, got.ID)
+	assert.Equal(t, task.Title, got.Title)
+	assert.Equal(t, task.Labels, got.Labels)
+	assert.True(t, task.CreatedAt.Equal(got.CreatedAt))
+	assert.Nil(t, got.CompletedAt)
+	assert.Equal(t, domain.StatusInProgress, got.TaskState.Status)
+}
+
+func TestTaskRepository_UpdateTask(t *testing.T) {
+	repo := newTestRepository(t)
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+
+	completedAt := time.Now()
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.TaskState = domain.TaskState{Status: domain.StatusCompleted, WorkDuration: time.Second, Progress: 100}
+		task.Result = json.RawMessage(`{"rows": 3}`)
+		task.ResultBlob = &domain.ResultBlob{Key: "abc", Size: 10}
+		task.CompletedAt = &completedAt
+		return nil
+	})
+	require.NoError(t, err)
+
+	stored, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, `{"rows": 3}`, string(stored.Result))
+	assert.Equal(t, &domain.ResultBlob{Key: "abc", Size: 10}, stored.ResultBlob)
+	if assert.NotNil(t, stored.CompletedAt) {
+		assert.WithinDuration(t, completedAt, *stored.CompletedAt, time.Microsecond)
+	}
+
+	errRejected := errors.New("rejected")
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.Title = "Changed"
+		return errRejected
+	})
+	assert.ErrorIs(t, err, errRejected)
+
+	got, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Test Task", got.Title)
+}
+
+func TestTaskRepository_ImportTruncatesTimestamps(t *testing.T) {
+	repo := newTestRepository(t)
+
+	// postgres rounds nanoseconds, the stored time must not move past the imported one
+	at := time.Date(2024, 5, 1, 12, 0, 0, 999_999_999, time.UTC)
+	task := domain.Task{
+		ID:        uuid.New(),
+		Title:     "Test Task",
+		TaskState: domain.TaskState{Status: domain.StatusInProgress},
+		CreatedAt: at,
+		UpdatedAt: at,
+		Version:   1,
+	}
+	require.NoError(t, repo.ImportTask(t.Context(), task, false))
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.True(t, at.Truncate(time.Microsecond).Equal(got.CreatedAt))
+	assert.True(t, at.Truncate(time.Microsecond).Equal(got.UpdatedAt))
+}
+
+func TestTaskRepository_ConcurrentUpdates(t *testing.T) {
+	repo := newTestRepository(t)
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+
+	// row lock serializes read-modify-write, so no increment is lost
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+				task.Attempt++
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	got, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, 20, got.Attempt)
+}
+
+func TestTaskRepository_NotFound(t *testing.T) {
+	repo := newTestRepository(t)
+	id := uuid.New()
+
+	_, err := repo.GetTaskByID(t.Context(), id)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error { return nil })
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), id, domain.AnyVersion), domain.ErrTaskNotFound)
+}
+
+func TestTaskRepository_StatementTimeout(t *testing.T) {
+	repo := newTestRepository(t)
+
+	// every pooled connection gets the configured timeout
+	var timeout string
+	err := repo.pool.QueryRow(context.Background(), `SHOW statement_timeout`).Scan(&timeout)
+	require.NoError(t, err)
+	assert.Equal(t, "5s", timeout)
+}
@@ -0,0 +1,12 @@
+package redis
+
+import "time"
+
+type Config struct {
+	Addr      string        `env:"REDIS_ADDR" envDefault:"localhost:6379"`
+	Password  string        `env:"REDIS_PASSWORD"`
+	DB        int           `env:"REDIS_DB" envDefault:"0"`
+	KeyPrefix string        `env:"REDIS_KEY_PREFIX" envDefault:"task-manager:"` // lets several deployments share one redis
+	PoolSize  int           `env:"REDIS_POOL_SIZE" envDefault:"10"`
+	Timeout   time.Duration `env:"REDIS_TIMEOUT" envDefault:"3s"` // dial, read and write timeout, also limits every repository call
+}
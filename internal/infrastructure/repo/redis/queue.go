@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// TaskQueue hands tasks out to executors through two lists: ids wait in <prefix>queue:pending and
+// move atomically to <prefix>queue:processing when taken, so a task taken by an executor that died is not lost
+type TaskQueue struct {
+	client     *redis.Client
+	pending    string
+	processing string
+}
+
+func newTaskQueue(client *redis.Client, prefix string) *TaskQueue {
+	return &TaskQueue{
+		client:     client,
+		pending:    prefix + "queue:pending",
+		processing: prefix + "queue:processing",
+	}
+}
+
+// enqueue puts the task at the end of pending in the transaction that creates or restores it
+func (q *TaskQueue) enqueue(ctx context.Context, pipe redis.Pipeliner, id uuid.UUID) {
+	pipe.LPush(ctx, q.pending, id.String())
+}
+
+func (q *TaskQueue) remove(ctx context.Context, pipe redis.Pipeliner, id uuid.UUID) {
+	pipe.LRem(ctx, q.pending, 0, id.String())
+	pipe.LRem(ctx, q.processing, 0, id.String())
+}
+
+// Dequeue takes the oldest pending task, waiting up to wait (whole seconds) for one to appear
+func (q *TaskQueue) Dequeue(ctx context.Context, wait time.Duration) (uuid.UUID, error) {
+	const op = "TaskQueue.Dequeue"
+
+	raw, err := q.client.BLMove(ctx, q.pending, q.processing, "RIGHT", "LEFT", wait).Result()
+	if errors.Is(err, redis.Nil) {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, domain.ErrQueueEmpty)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// Ack removes the taken task from processing once the executor is done with it
+func (q *TaskQueue) Ack(ctx context.Context, id uuid.UUID) error {
+	const op = "TaskQueue.Ack"
+
+	removed, err := q.client.LRem(ctx, q.processing, 1, id.String()).Result()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("%s: %w", op, domain.ErrTaskNotFound)
+	}
+	return nil
+}
+
+// Requeue returns every taken but not acknowledged task to pending, it is meant for recovery after executors restart
+func (q *TaskQueue) Requeue(ctx context.Context) (int, error) {
+	const op = "TaskQueue.Requeue"
+
+	var moved int
+	for {
+		err := q.client.LMove(ctx, q.processing, q.pending, "RIGHT", "RIGHT").Err()
+		if errors.Is(err, redis.Nil) {
+			return moved, nil
+		}
+		if err != nil {
+			return moved, fmt.Errorf("%s: %w", op, err)
+		}
+		moved++
+	}
+}
+
+// Len returns the number of pending and taken tasks
+func (q *TaskQueue) Len(ctx context.Context) (pending, processing int64, err error) {
+	const op = "TaskQueue.Len"
+
+	pipe := q.client.Pipeline()
+	pendingCmd := pipe.LLen(ctx, q.pending)
+	processingCmd := pipe.LLen(ctx, q.processing)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return pendingCmd.Val(), processingCmd.Val(), nil
+}
@@ -0,0 +1,796 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// TaskRepository keeps every task in a hash <prefix>task:<id>, timestamps are stored as unix nanoseconds.
+// Ids of tasks in the trash are kept in the set <prefix>tasks:deleted. A task joins the queue in the transaction that creates, imports,
+// restores or retries it and leaves it in the one that finishes it or moves it to the trash, so replicas share both state and pending work.
+// Outbox events are stored as JSON in the hash <prefix>outbox:events by id, the list <prefix>outbox:order keeps their ids
+// in the order they were stored. The history of a task is the list <prefix>history:<id>. The sorted sets <prefix>tasks:by_created
+// and <prefix>tasks:by_updated index every task by creation and update time, see indexMember
+type TaskRepository struct {
+	client  *redis.Client
+	prefix  string
+	timeout time.Duration
+	queue   *TaskQueue
+}
+
+func NewTaskRepository(cfg Config) (*TaskRepository, error) {
+	const op = "redis.NewTaskRepository"
+
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		DialTimeout:  cfg.Timeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	repo := &TaskRepository{
+		client:  client,
+		prefix:  cfg.KeyPrefix,
+		timeout: cfg.Timeout,
+		queue:   newTaskQueue(client, cfg.KeyPrefix),
+	}
+	if err := repo.indexTasks(context.Background()); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return repo, nil
+}
+
+// indexTasks adds tasks stored before the time indexes existed to them, once per database: <prefix>tasks:indexed marks it done
+func (r *TaskRepository) indexTasks(ctx context.Context) error {
+	var done int64
+	err := r.withTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		done, err = r.client.Exists(ctx, r.indexedKey()).Result()
+		return err
+	})
+	if err != nil || done > 0 {
+		return err
+	}
+
+	var cursor uint64
+	for {
+		var keys []string
+		err := r.withTimeout(ctx, func(ctx context.Context) error {
+			var err error
+			keys, cursor, err = r.client.Scan(ctx, cursor, r.prefix+"task:*", walkBatch).Result()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			id, err := uuid.Parse(strings.TrimPrefix(key, r.prefix+"task:"))
+			if err != nil {
+				continue // not a task key that only shares the prefix
+			}
+			txf := func(tx *redis.Tx) error {
+				task, err := r.getTask(ctx, tx, id)
+				if err != nil {
+					return err
+				}
+				_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					r.addIndexes(ctx, pipe, task)
+					return nil
+				})
+				return err
+			}
+			err = r.withTimeout(ctx, func(ctx context.Context) error {
+				for {
+					err := r.client.Watch(ctx, txf, key)
+					if !errors.Is(err, redis.TxFailedErr) || ctx.Err() != nil {
+						return err
+					}
+				}
+			})
+			if err != nil && !errors.Is(err, domain.ErrTaskNotFound) {
+				return err
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return r.withTimeout(ctx, func(ctx context.Context) error {
+		return r.client.Set(ctx, r.indexedKey(), 1, 0).Err()
+	})
+}
+
+func (r *TaskRepository) Close() error {
+	return r.client.Close()
+}
+
+// Queue returns the queue of tasks waiting for an executor
+func (r *TaskRepository) Queue() *TaskQueue {
+	return r.queue
+}
+
+func (r *TaskRepository) CreateTask(ctx context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error) {
+	const op = "TaskRepository.CreateTask"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	now := time.Now()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.Version = 1
+	task.ID = uuid.New()
+
+	fields, err := taskFields(*task)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	encoded, err := encodeEvents(domain.EventsWithTask(events, *task))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.taskKey(task.ID), fields)
+		r.addIndexes(ctx, pipe, *task)
+		if !task.TaskState.Status.IsTerminal() {
+			r.queue.enqueue(ctx, pipe, task.ID)
+		}
+		r.addEvents(ctx, pipe, encoded)
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return task.ID, nil
+}
+
+func (r *TaskRepository) GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error) {
+	const op = "TaskRepository.GetTaskByID"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	task, err := r.getTask(ctx, r.client, id)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return task, nil
+}
+
+// UpdateTask applies update under WATCH of the task key and retries until the timeout when another client changes the task first,
+// so update may be called more than once and must not have side effects. The events are stored in the transaction of the write
+func (r *TaskRepository) UpdateTask(ctx context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error) {
+	const op = "TaskRepository.UpdateTask"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	key := r.taskKey(id)
+	var updated domain.Task
+	txf := func(tx *redis.Tx) error {
+		task, err := r.getTask(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		updated = task
+		if err := update(&updated); err != nil {
+			return err
+		}
+		updated.UpdatedAt = time.Now()
+		updated.Version = task.Version + 1
+
+		fields, err := taskFields(updated)
+		if err != nil {
+			return err
+		}
+		encoded, err := encodeEvents(domain.EventsWithTask(events, updated))
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			// fields of unset optional values must disappear, so the hash is rewritten as a whole
+			pipe.Del(ctx, key)
+			pipe.HSet(ctx, key, fields)
+			r.removeIndexes(ctx, pipe, task)
+			r.addIndexes(ctx, pipe, updated)
+			if updated.IsDeleted() {
+				pipe.SAdd(ctx, r.deletedKey(), id.String())
+			} else {
+				pipe.SRem(ctx, r.deletedKey(), id.String())
+			}
+			switch {
+			case updated.IsDeleted() || updated.TaskState.Status.IsTerminal():
+				r.queue.remove(ctx, pipe, id)
+			case task.IsDeleted() || task.TaskState.Status.IsTerminal():
+				// restored from the trash or retried, both took it off the queue
+				r.queue.enqueue(ctx, pipe, id)
+			}
+			r.addEvents(ctx, pipe, encoded)
+			return nil
+		})
+		return err
+	}
+
+	for {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+		}
+		return updated, nil
+	}
+}
+
+// DeleteTask deletes the task if it still has the version, domain.AnyVersion deletes it unconditionally.
+// The events are stored in the transaction of the delete
+func (r *TaskRepository) DeleteTask(ctx context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error {
+	const op = "TaskRepository.DeleteTask"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	encoded, err := encodeEvents(events)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	key := r.taskKey(id)
+	txf := func(tx *redis.Tx) error {
+		task, err := r.getTask(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !task.MatchesVersion(version) {
+			return domain.ErrVersionMismatch
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			r.removeIndexes(ctx, pipe, task)
+			pipe.SRem(ctx, r.deletedKey(), id.String())
+			r.queue.remove(ctx, pipe, id)
+			r.addEvents(ctx, pipe, encoded)
+			// after the events, so the history goes with the task entirely
+			pipe.Del(ctx, r.historyKey(id))
+			return nil
+		})
+		return err
+	}
+
+	for {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+}
+
+// ListDeletedTasks returns tasks in the trash ordered by deletion time
+func (r *TaskRepository) ListDeletedTasks(ctx context.Context) ([]domain.Task, error) {
+	const op = "TaskRepository.ListDeletedTasks"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	members, err := r.client.SMembers(ctx, r.deletedKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tasks := make([]domain.Task, 0, len(members))
+	for _, member := range members {
+		id, err := uuid.Parse(member)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid deleted task id %q: %w", op, member, err)
+		}
+		task, err := r.getTask(ctx, r.client, id)
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			continue // purged after the set was read
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if task.IsDeleted() {
+			tasks = append(tasks, task)
+		}
+	}
+	slices.SortFunc(tasks, func(a, b domain.Task) int {
+		if c := a.DeletedAt.Compare(*b.DeletedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	return tasks, nil
+}
+
+// ListTasks returns up to query.Limit tasks the query lists in its order. The creation or update index of the sort is read
+// from the cursor in batches and the read stops once the page is full
+func (r *TaskRepository) ListTasks(ctx context.Context, query domain.TaskListQuery) ([]domain.Task, error) {
+	const op = "TaskRepository.ListTasks"
+
+	lower, upper := "-", "+"
+	from, to := query.Filter.Range(query.Sort)
+	if !from.IsZero() {
+		lower = "[" + indexTime(from)
+	}
+	if !to.IsZero() {
+		upper = "(" + indexTime(to)
+	}
+	if query.After != nil {
+		after := indexMember(query.After.At, query.After.ID)
+		if query.Sort.Desc() && (upper == "+" || after < upper[1:]) {
+			upper = "(" + after
+		}
+		if !query.Sort.Desc() && (lower == "-" || after > lower[1:]) {
+			lower = "(" + after
+		}
+	}
+
+	var tasks []domain.Task
+	err := r.walkIndex(ctx, query.Sort, lower, upper, max(query.Limit, domain.DefaultTaskPageSize), func(task domain.Task) bool {
+		if query.Matches(task) {
+			tasks = append(tasks, task)
+		}
+		return len(tasks) < query.Limit
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tasks, nil
+}
+
+// walkBatch is how many index members a round trip of WalkTasks reads
+const walkBatch = 500
+
+// WalkTasks calls fn with every stored task ordered by creation time, it stops at the first error of fn.
+// The creation index is read in batches, every round trip has its own storage timeout
+func (r *TaskRepository) WalkTasks(ctx context.Context, fn func(task domain.Task) error) error {
+	const op = "TaskRepository.WalkTasks"
+
+	var fnErr error
+	err := r.walkIndex(ctx, domain.SortCreatedAsc, "-", "+", walkBatch, func(task domain.Task) bool {
+		fnErr = fn(task)
+		return fnErr == nil
+	})
+	if err == nil {
+		err = fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// walkIndex calls fn with tasks of the time index of the sort in its order, starting at the lower (upper for descending sorts)
+// ZRANGEBYLEX bound, until fn returns false or the bounds are reached. Tasks are read batch members at a time
+func (r *TaskRepository) walkIndex(ctx context.Context, sort domain.TaskSort, lower, upper string, batch int, fn func(task domain.Task) bool) error {
+	key := r.createdIndexKey()
+	if sort.ByUpdate() {
+		key = r.updatedIndexKey()
+	}
+
+	for {
+		var members []string
+		var cmds []*redis.MapStringStringCmd
+		err := r.withTimeout(ctx, func(ctx context.Context) error {
+			by := &redis.ZRangeBy{Min: lower, Max: upper, Count: int64(batch)}
+			var err error
+			if sort.Desc() {
+				members, err = r.client.ZRevRangeByLex(ctx, key, by).Result()
+			} else {
+				members, err = r.client.ZRangeByLex(ctx, key, by).Result()
+			}
+			if err != nil || len(members) == 0 {
+				return err
+			}
+			_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				cmds = make([]*redis.MapStringStringCmd, len(members))
+				for i, member := range members {
+					cmds[i] = pipe.HGetAll(ctx, r.prefix+"task:"+member[indexTimeLen+1:])
+				}
+				return nil
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, member := range members {
+			id, err := uuid.Parse(member[indexTimeLen+1:])
+			if err != nil {
+				return fmt.Errorf("invalid index member %q: %w", member, err)
+			}
+			fields := cmds[i].Val()
+			if len(fields) == 0 {
+				continue // deleted after the index was read
+			}
+			task, err := parseTask(id, fields)
+			if err != nil {
+				return err
+			}
+			if indexMember(sort.Key(task), id) != member {
+				continue // updated after the index was read, the task is listed at its new member
+			}
+			if !fn(task) {
+				return nil
+			}
+		}
+
+		if len(members) < batch {
+			return nil
+		}
+		if last := members[len(members)-1]; sort.Desc() {
+			upper = "(" + last
+		} else {
+			lower = "(" + last
+		}
+	}
+}
+
+// ImportTask stores the task as it is, id, timestamps and version included. If the id is already stored the task
+// replaces it when overwrite is set, otherwise domain.ErrTaskExists is returned. A finished or deleted task leaves the queue
+func (r *TaskRepository) ImportTask(ctx context.Context, task domain.Task, overwrite bool) error {
+	const op = "TaskRepository.ImportTask"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	fields, err := taskFields(task)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	key := r.taskKey(task.ID)
+	txf := func(tx *redis.Tx) error {
+		stored, err := r.getTask(ctx, tx, task.ID)
+		exists := err == nil
+		if err != nil && !errors.Is(err, domain.ErrTaskNotFound) {
+			return err
+		}
+		if exists && !overwrite {
+			return domain.ErrTaskExists
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			pipe.HSet(ctx, key, fields)
+			if exists {
+				r.removeIndexes(ctx, pipe, stored)
+			}
+			r.addIndexes(ctx, pipe, task)
+			if task.IsDeleted() {
+				pipe.SAdd(ctx, r.deletedKey(), task.ID.String())
+			} else {
+				pipe.SRem(ctx, r.deletedKey(), task.ID.String())
+			}
+			switch {
+			case task.IsDeleted() || task.TaskState.Status.IsTerminal():
+				r.queue.remove(ctx, pipe, task.ID)
+			case !exists:
+				r.queue.enqueue(ctx, pipe, task.ID)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+}
+
+// ListPendingEvents returns up to limit events of the outbox in the order they were stored
+func (r *TaskRepository) ListPendingEvents(ctx context.Context, limit int) ([]domain.TaskEvent, error) {
+	const op = "TaskRepository.ListPendingEvents"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	ids, err := r.client.LRange(ctx, r.outboxOrderKey(), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	values, err := r.client.HMGet(ctx, r.outboxEventsKey(), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events := make([]domain.TaskEvent, 0, len(values))
+	for _, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue // delivered after the order was read
+		}
+		var event domain.TaskEvent
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// ReadTaskHistory returns the history of the task oldest first
+func (r *TaskRepository) ReadTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error) {
+	const op = "TaskRepository.ReadTaskHistory"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	raws, err := r.client.LRange(ctx, r.historyKey(id), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	events := make([]domain.TaskHistoryEvent, len(raws))
+	for i, raw := range raws {
+		if err := json.Unmarshal([]byte(raw), &events[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events[i].Seq = int64(i + 1)
+	}
+	return events, nil
+}
+
+// MarkEventsDelivered removes the events from the outbox, ids that are not pending are ignored
+func (r *TaskRepository) MarkEventsDelivered(ctx context.Context, ids []uuid.UUID) error {
+	const op = "TaskRepository.MarkEventsDelivered"
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if len(ids) == 0 {
+		return nil
+	}
+	members := make([]string, len(ids))
+	for i, id := range ids {
+		members[i] = id.String()
+	}
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, r.outboxEventsKey(), members...)
+		for _, member := range members {
+			pipe.LRem(ctx, r.outboxOrderKey(), 1, member)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// addEvents appends the events made by encodeEvents to the outbox and to the history of their task within the transaction of the change
+func (r *TaskRepository) addEvents(ctx context.Context, pipe redis.Pipeliner, events []encodedEvent) {
+	if len(events) == 0 {
+		return
+	}
+	fields := make([]any, 0, 2*len(events))
+	ids := make([]any, 0, len(events))
+	for _, event := range events {
+		fields = append(fields, event.id, event.event)
+		ids = append(ids, event.id)
+		pipe.RPush(ctx, r.historyKey(event.taskID), event.history)
+	}
+	pipe.HSet(ctx, r.outboxEventsKey(), fields...)
+	pipe.RPush(ctx, r.outboxOrderKey(), ids...)
+}
+
+// encodedEvent is an event of a change as JSON for the outbox and for the history of its task
+type encodedEvent struct {
+	id      string
+	taskID  uuid.UUID
+	event   string
+	history string
+}
+
+func encodeEvents(events []domain.TaskEvent) ([]encodedEvent, error) {
+	encoded := make([]encodedEvent, 0, len(events))
+	for _, event := range events {
+		raw, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := json.Marshal(domain.NewTaskHistoryEvent(event))
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, encodedEvent{id: event.ID.String(), taskID: event.Task.ID, event: string(raw), history: string(entry)})
+	}
+	return encoded, nil
+}
+
+// withTimeout runs a single round trip with the storage timeout
+func (r *TaskRepository) withTimeout(ctx context.Context, call func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return call(ctx)
+}
+
+func (r *TaskRepository) taskKey(id uuid.UUID) string {
+	return r.prefix + "task:" + id.String()
+}
+
+func (r *TaskRepository) deletedKey() string {
+	return r.prefix + "tasks:deleted"
+}
+
+func (r *TaskRepository) createdIndexKey() string {
+	return r.prefix + "tasks:by_created"
+}
+
+func (r *TaskRepository) updatedIndexKey() string {
+	return r.prefix + "tasks:by_updated"
+}
+
+func (r *TaskRepository) indexedKey() string {
+	return r.prefix + "tasks:indexed"
+}
+
+func (r *TaskRepository) addIndexes(ctx context.Context, pipe redis.Pipeliner, task domain.Task) {
+	pipe.ZAdd(ctx, r.createdIndexKey(), redis.Z{Member: indexMember(task.CreatedAt, task.ID)})
+	pipe.ZAdd(ctx, r.updatedIndexKey(), redis.Z{Member: indexMember(task.UpdatedAt, task.ID)})
+}
+
+func (r *TaskRepository) removeIndexes(ctx context.Context, pipe redis.Pipeliner, task domain.Task) {
+	pipe.ZRem(ctx, r.createdIndexKey(), indexMember(task.CreatedAt, task.ID))
+	pipe.ZRem(ctx, r.updatedIndexKey(), indexMember(task.UpdatedAt, task.ID))
+}
+
+// indexTimeLen is the length of indexTime values
+const indexTimeLen = 16
+
+// indexMember is the member of the task in a time index: the hex encoded unix nanoseconds of the time, a colon and the id.
+// Every member has score 0, so the set is ordered by member, that is by time and then by id, and read with ZRANGEBYLEX.
+// Float scores can't hold unix nanoseconds, tasks a few hundred nanoseconds apart would share a score
+func indexMember(at time.Time, id uuid.UUID) string {
+	return indexTime(at) + ":" + id.String()
+}
+
+// indexTime encodes the time so that string order matches time order, times before 1970 are clamped
+func indexTime(at time.Time) string {
+	return fmt.Sprintf("%0*x", indexTimeLen, max(at.UnixNano(), 0))
+}
+
+func (r *TaskRepository) outboxEventsKey() string {
+	return r.prefix + "outbox:events"
+}
+
+func (r *TaskRepository) outboxOrderKey() string {
+	return r.prefix + "outbox:order"
+}
+
+func (r *TaskRepository) historyKey(id uuid.UUID) string {
+	return r.prefix + "history:" + id.String()
+}
+
+// getTask reads the task with c, which is the client or a transaction watching the task key
+func (r *TaskRepository) getTask(ctx context.Context, c redis.Cmdable, id uuid.UUID) (domain.Task, error) {
+	fields, err := c.HGetAll(ctx, r.taskKey(id)).Result()
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if len(fields) == 0 {
+		return domain.Task{}, domain.ErrTaskNotFound
+	}
+	return parseTask(id, fields)
+}
+
+func taskFields(task domain.Task) (map[string]any, error) {
+	labels, err := json.Marshal(task.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{
+		"title":               task.Title,
+		"description":         task.Description,
+		"type":                task.Type,
+		"labels":              labels,
+		"status":              string(task.TaskState.Status),
+		"work_duration":       int64(task.TaskState.WorkDuration),
+		"progress":            task.TaskState.Progress,
+		"attempt":             task.Attempt,
+		"version":             task.Version,
+		"result_content_type": task.ResultContentType,
+		"callback_url":        task.CallbackURL,
+		"created_at":          task.CreatedAt.UnixNano(),
+		"updated_at":          task.UpdatedAt.UnixNano(),
+	}
+	if task.Result != nil {
+		fields["result"] = []byte(task.Result)
+	}
+	if task.ResultBlob != nil {
+		fields["result_blob_key"] = task.ResultBlob.Key
+		fields["result_blob_size"] = task.ResultBlob.Size
+	}
+	if task.CompletedAt != nil {
+		fields["completed_at"] = task.CompletedAt.UnixNano()
+	}
+	if task.DeletedAt != nil {
+		fields["deleted_at"] = task.DeletedAt.UnixNano()
+	}
+	return fields, nil
+}
+
+func parseTask(id uuid.UUID, fields map[string]string) (domain.Task, error) {
+	task := domain.Task{
+		ID:                id,
+		Title:             fields["title"],
+		Description:       fields["description"],
+		Type:              fields["type"],
+		ResultContentType: fields["result_content_type"],
+		CallbackURL:       fields["callback_url"],
+	}
+	task.TaskState.Status = domain.TaskStatus(fields["status"])
+
+	if err := json.Unmarshal([]byte(fields["labels"]), &task.Labels); err != nil {
+		return domain.Task{}, fmt.Errorf("invalid labels of task %s: %w", id, err)
+	}
+
+	ints := make(map[string]int64)
+	for _, name := range []string{"work_duration", "progress", "attempt", "version", "created_at", "updated_at", "completed_at", "deleted_at", "result_blob_size"} {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return domain.Task{}, fmt.Errorf("invalid %s of task %s: %w", name, id, err)
+		}
+		ints[name] = value
+	}
+	task.TaskState.WorkDuration = time.Duration(ints["work_duration"])
+	task.TaskState.Progress = int(ints["progress"])
+	task.Attempt = int(ints["attempt"])
+	task.Version = max(ints["version"], 1) // tasks stored before versions were added have no version field
+	task.CreatedAt = time.Unix(0, ints["created_at"])
+	task.UpdatedAt = time.Unix(0, ints["updated_at"])
+
+	if result, ok := fields["result"]; ok {
+		task.Result = json.RawMessage(result)
+	}
+	if key, ok := fields["result_blob_key"]; ok {
+		task.ResultBlob = &domain.ResultBlob{Key: key, Size: ints["result_blob_size"]}
+	}
+	if _, ok := fields["completed_at"]; ok {
+		completedAt := time.Unix(0, ints["completed_at"])
+		task.CompletedAt = &completedAt
+	}
+	if _, ok := fields["deleted_at"]; ok {
+		deletedAt := time.Unix(0, ints["deleted_at"])
+		task.DeletedAt = &deletedAt
+	}
+	return task, nil
+}
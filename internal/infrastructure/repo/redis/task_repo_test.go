@@ -0,0 +1,303 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/repotest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) *TaskRepository {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	repo, err := NewTaskRepository(Config{Addr: server.Addr(), KeyPrefix: "test:", PoolSize: 10, Timeout: time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestTaskRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.TaskRepository {
+		return newTestRepository(t)
+	})
+}
+
+func TestTaskRepository_RoundTrip(t *testing.T) {
+	repo := newTestRepository(t)
+
+	task := &domain.Task{
+		Title:       "Test Task",
+		Description: "Test Description",
+		Type:        "report",
+		Labels:      []string{"billing", "nightly"},
+		TaskState:   domain.TaskState{Status: domain.StatusInProgress},
+		Attempt:     1,
+		CallbackURL: "https://example.com/hook",
+	}
+	id, err := repo.CreateTask(t.Context(), task)
+	require.NoError(t, err)
+
+	got, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, task.Title, got.Title)
+	assert.Equal(t, task.Description, got.Description)
+	assert.Equal(t, task.Labels, got.Labels)
+	assert.Equal(t, task.CallbackURL, got.CallbackURL)
+	assert.Equal(t, 1, got.Attempt)
+	assert.True(t, task.CreatedAt.Equal(got.CreatedAt))
+	assert.Nil(t, got.Result)
+	assert.Nil(t, got.CompletedAt)
+}
+
+func TestTaskRepository_Update(t *testing.T) {
+	repo := newTestRepository(t)
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "report", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+
+	completedAt := time.Now()
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.TaskState.Status = domain.StatusCompleted
+		task.TaskState.Progress = 100
+		task.Result = []byte(`{"total": 42}`)
+		task.ResultContentType = "application/json"
+		task.CompletedAt = &completedAt
+		return nil
+	})
+	require.NoError(t, err)
+
+	task, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, `{"total": 42}`, string(task.Result))
+	require.NotNil(t, task.CompletedAt)
+	assert.True(t, completedAt.Equal(*task.CompletedAt))
+
+	// the result moves to a blob, inline content must be gone
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.Result = nil
+		task.ResultBlob = &domain.ResultBlob{Key: "abc", Size: 1 << 20}
+		return nil
+	})
+	require.NoError(t, err)
+
+	task, err = repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Nil(t, task.Result)
+	assert.Equal(t, &domain.ResultBlob{Key: "abc", Size: 1 << 20}, task.ResultBlob)
+
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error { return domain.ErrTaskAlreadyFinished })
+	assert.ErrorIs(t, err, domain.ErrTaskAlreadyFinished)
+}
+
+func TestTaskRepository_ConcurrentUpdates(t *testing.T) {
+	repo := newTestRepository(t)
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "counter"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+				task.TaskState.Progress++
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	task, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, 5, task.TaskState.Progress)
+}
+
+func TestTaskRepository_NotFound(t *testing.T) {
+	repo := newTestRepository(t)
+
+	_, err := repo.GetTaskByID(t.Context(), uuid.New())
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+
+	_, err = repo.UpdateTask(t.Context(), uuid.New(), func(task *domain.Task) error { return nil })
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), uuid.New(), domain.AnyVersion), domain.ErrTaskNotFound)
+}
+
+func TestTaskQueue(t *testing.T) {
+	repo := newTestRepository(t)
+	queue := repo.Queue()
+	ctx := context.Background()
+
+	first, err := repo.CreateTask(t.Context(), &domain.Task{Title: "first"})
+	require.NoError(t, err)
+	second, err := repo.CreateTask(t.Context(), &domain.Task{Title: "second"})
+	require.NoError(t, err)
+	deleted, err := repo.CreateTask(t.Context(), &domain.Task{Title: "deleted"})
+	require.NoError(t, err)
+	trashed, err := repo.CreateTask(t.Context(), &domain.Task{Title: "trashed"})
+	require.NoError(t, err)
+	completed, err := repo.CreateTask(t.Context(), &domain.Task{Title: "completed"})
+	require.NoError(t, err)
+
+	// finished and deleted tasks leave the queue with the change
+	require.NoError(t, repo.DeleteTask(t.Context(), deleted, domain.AnyVersion))
+	_, err = repo.UpdateTask(t.Context(), trashed, func(task *domain.Task) error {
+		now := time.Now()
+		task.DeletedAt = &now
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = repo.UpdateTask(t.Context(), completed, func(task *domain.Task) error {
+		task.TaskState.Status = domain.StatusCompleted
+		return nil
+	})
+	require.NoError(t, err)
+
+	id, err := queue.Dequeue(ctx, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, first, id)
+	require.NoError(t, queue.Ack(ctx, id))
+
+	id, err = queue.Dequeue(ctx, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, second, id)
+
+	_, err = queue.Dequeue(ctx, time.Second)
+	assert.ErrorIs(t, err, domain.ErrQueueEmpty)
+
+	// the executor of the second task died before acknowledging it
+	requeued, err := queue.Requeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requeued)
+
+	pending, processing, err := queue.Len(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+	assert.Zero(t, processing)
+
+	id, err = queue.Dequeue(ctx, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, second, id)
+	assert.ErrorIs(t, queue.Ack(ctx, first), domain.ErrTaskNotFound)
+
+	// a taken task that fails leaves processing as well
+	_, err = repo.UpdateTask(t.Context(), second, func(task *domain.Task) error {
+		task.TaskState.Status = domain.StatusFailed
+		return nil
+	})
+	require.NoError(t, err)
+	pending, processing, err = queue.Len(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+	assert.Zero(t, processing)
+
+	// and joins the queue again once it is retried
+	_, err = repo.UpdateTask(t.Context(), second, func(task *domain.Task) error {
+		task.TaskState.Status = domain.StatusInProgress
+		task.Attempt++
+		return nil
+	})
+	require.NoError(t, err)
+	id, err = queue.Dequeue(ctx, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, second, id)
+}
+
+func TestTaskQueue_Restore(t *testing.T) {
+	repo := newTestRepository(t)
+	queue := repo.Queue()
+	ctx := context.Background()
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "report", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		now := time.Now()
+		task.DeletedAt = &now
+		return nil
+	})
+	require.NoError(t, err)
+	pending, _, err := queue.Len(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+
+	// restored task gets its place in the queue back
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.DeletedAt = nil
+		return nil
+	})
+	require.NoError(t, err)
+
+	taken, err := queue.Dequeue(ctx, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, id, taken)
+
+	_, err = queue.Dequeue(ctx, time.Second)
+	assert.ErrorIs(t, err, domain.ErrQueueEmpty)
+}
+
+func TestTaskRepository_IndexesTasksOnStart(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := Config{Addr: server.Addr(), KeyPrefix: "test:", PoolSize: 10, Timeout: time.Second}
+	repo, err := NewTaskRepository(cfg)
+	require.NoError(t, err)
+
+	first, err := repo.CreateTask(t.Context(), &domain.Task{Title: "first"})
+	require.NoError(t, err)
+	second, err := repo.CreateTask(t.Context(), &domain.Task{Title: "second"})
+	require.NoError(t, err)
+	_, err = repo.UpdateTask(t.Context(), first, func(task *domain.Task) error { return nil })
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	// a database written before the time indexes existed
+	server.Del("test:tasks:by_created")
+	server.Del("test:tasks:by_updated")
+	server.Del("test:tasks:indexed")
+
+	restarted, err := NewTaskRepository(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { restarted.Close() })
+
+	tasks, err := restarted.ListTasks(t.Context(), domain.TaskListQuery{Sort: domain.SortUpdatedDesc, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, first, tasks[0].ID)
+	assert.Equal(t, second, tasks[1].ID)
+
+	members, err := server.ZMembers("test:tasks:by_created")
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+}
+
+func TestTaskRepository_ListTasksAcrossBatches(t *testing.T) {
+	repo := newTestRepository(t)
+
+	labeled, err := repo.CreateTask(t.Context(), &domain.Task{Title: "labeled", Labels: []string{"billing"}})
+	require.NoError(t, err)
+	for range domain.DefaultTaskPageSize + 10 {
+		_, err := repo.CreateTask(t.Context(), &domain.Task{Title: "other"})
+		require.NoError(t, err)
+	}
+
+	// the newest batch of the index has no match, the read goes on to the next one
+	tasks, err := repo.ListTasks(t.Context(), domain.TaskListQuery{
+		Filter: domain.TaskListFilter{Labels: []string{"billing"}},
+		Sort:   domain.SortCreatedDesc,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, labeled, tasks[0].ID)
+}
@@ -0,0 +1,647 @@
+// Package repotest is a conformance suite for task repositories, every storage backend runs it from its own tests
+// so that all of them behave like the in-memory repository
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timePrecision is the coarsest timestamp precision among backends, postgres keeps microseconds
+const timePrecision = time.Microsecond
+
+type TaskRepository interface {
+	CreateTask(ctx context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error)
+	GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error)
+	UpdateTask(ctx context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error)
+	DeleteTask(ctx context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error
+	ListDeletedTasks(ctx context.Context) ([]domain.Task, error)
+	ListTasks(ctx context.Context, query domain.TaskListQuery) ([]domain.Task, error)
+	WalkTasks(ctx context.Context, fn func(task domain.Task) error) error
+	ImportTask(ctx context.Context, task domain.Task, overwrite bool) error
+	ListPendingEvents(ctx context.Context, limit int) ([]domain.TaskEvent, error)
+	MarkEventsDelivered(ctx context.Context, ids []uuid.UUID) error
+	ReadTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error)
+}
+
+// Factory returns an empty repository, it may skip the test when the backend is not available
+type Factory func(t *testing.T) TaskRepository
+
+// Run runs the whole suite, every subtest gets a new repository from newRepo
+func Run(t *testing.T, newRepo Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, newRepo(t)) })
+	t.Run("RoundTripAllFields", func(t *testing.T) { testRoundTripAllFields(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+	t.Run("UpdateError", func(t *testing.T) { testUpdateError(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo(t)) })
+	t.Run("Versions", func(t *testing.T) { testVersions(t, newRepo(t)) })
+	t.Run("DeleteVersion", func(t *testing.T) { testDeleteVersion(t, newRepo(t)) })
+	t.Run("Trash", func(t *testing.T) { testTrash(t, newRepo(t)) })
+	t.Run("WalkAndImport", func(t *testing.T) { testWalkAndImport(t, newRepo(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newRepo(t)) })
+	t.Run("ConcurrentCreates", func(t *testing.T) { testConcurrentCreates(t, newRepo(t)) })
+	t.Run("ConcurrentUpdates", func(t *testing.T) { testConcurrentUpdates(t, newRepo(t)) })
+	t.Run("Outbox", func(t *testing.T) { testOutbox(t, newRepo(t)) })
+	t.Run("History", func(t *testing.T) { testHistory(t, newRepo(t)) })
+	t.Run("ListTasks", func(t *testing.T) { testListTasks(t, newRepo(t)) })
+}
+
+func createTask(t *testing.T, repo TaskRepository, task domain.Task) domain.Task {
+	t.Helper()
+
+	id, err := repo.CreateTask(t.Context(), &task)
+	require.NoError(t, err)
+	require.Equal(t, task.ID, id)
+	return task
+}
+
+func testCreateAndGet(t *testing.T, repo TaskRepository) {
+	before := time.Now().Add(-timePrecision)
+	task := createTask(t, repo, domain.Task{
+		Title:     "Test Task",
+		TaskState: domain.TaskState{Status: domain.StatusInProgress},
+		Attempt:   1,
+	})
+
+	assert.NotEqual(t, uuid.Nil, task.ID)
+	assert.False(t, task.CreatedAt.Before(before), "created_at is set on create")
+	assert.True(t, task.CreatedAt.Equal(task.UpdatedAt), "updated_at equals created_at on create")
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, task.ID, got.ID)
+	assert.Equal(t, "Test Task", got.Title)
+	assert.Empty(t, got.Labels)
+	assert.WithinDuration(t, task.CreatedAt, got.CreatedAt, timePrecision)
+	assert.WithinDuration(t, task.UpdatedAt, got.UpdatedAt, timePrecision)
+	assert.Equal(t, domain.StatusInProgress, got.TaskState.Status)
+	assert.Nil(t, got.CompletedAt)
+
+	other := createTask(t, repo, domain.Task{Title: "Other Task"})
+	assert.NotEqual(t, task.ID, other.ID)
+}
+
+func testRoundTripAllFields(t *testing.T, repo TaskRepository) {
+	completedAt := time.Now()
+	task := createTask(t, repo, domain.Task{
+		Title:       "Report",
+		Description: "Monthly report",
+		Type:        "report",
+		Labels:      []string{"billing", "nightly"},
+		TaskState:   domain.TaskState{Status: domain.StatusInProgress},
+		Attempt:     2,
+		CallbackURL: "https://example.com/hook",
+	})
+
+	_, err := repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.TaskState = domain.TaskState{Status: domain.StatusCompleted, WorkDuration: 1500 * time.Millisecond, Progress: 100}
+		task.Result = json.RawMessage(`{"total":42,"rows":[1,2]}`)
+		task.ResultContentType = "application/json"
+		task.CompletedAt = &completedAt
+		return nil
+	})
+	require.NoError(t, err)
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Report", got.Title)
+	assert.Equal(t, "Monthly report", got.Description)
+	assert.Equal(t, "report", got.Type)
+	assert.Equal(t, []string{"billing", "nightly"}, got.Labels)
+	assert.Equal(t, domain.TaskState{Status: domain.StatusCompleted, WorkDuration: 1500 * time.Millisecond, Progress: 100}, got.TaskState)
+	assert.Equal(t, 2, got.Attempt)
+	assert.Equal(t, "https://example.com/hook", got.CallbackURL)
+	assert.Equal(t, `{"total":42,"rows":[1,2]}`, string(got.Result), "result is kept byte for byte")
+	assert.Equal(t, "application/json", got.ResultContentType)
+	assert.Nil(t, got.ResultBlob)
+	require.NotNil(t, got.CompletedAt)
+	assert.WithinDuration(t, completedAt, *got.CompletedAt, timePrecision)
+
+	// large results are moved to the result store, only the reference stays in the repository
+	_, err = repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.Result = nil
+		task.ResultContentType = "text/csv"
+		task.ResultBlob = &domain.ResultBlob{Key: "9f86d081", Size: 1 << 20}
+		return nil
+	})
+	require.NoError(t, err)
+
+	got, err = repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Result)
+	assert.Equal(t, "text/csv", got.ResultContentType)
+	assert.Equal(t, &domain.ResultBlob{Key: "9f86d081", Size: 1 << 20}, got.ResultBlob)
+}
+
+func testUpdate(t *testing.T, repo TaskRepository) {
+	task := createTask(t, repo, domain.Task{Title: "Report", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	time.Sleep(2 * timePrecision)
+
+	updated, err := repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.TaskState.Progress = 40
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 40, updated.TaskState.Progress)
+	assert.True(t, updated.UpdatedAt.After(task.UpdatedAt), "updated_at moves forward")
+	assert.WithinDuration(t, task.CreatedAt, updated.CreatedAt, timePrecision, "created_at does not change")
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 40, got.TaskState.Progress)
+	assert.Equal(t, domain.StatusInProgress, got.TaskState.Status)
+	assert.WithinDuration(t, updated.UpdatedAt, got.UpdatedAt, timePrecision)
+}
+
+func testUpdateError(t *testing.T, repo TaskRepository) {
+	task := createTask(t, repo, domain.Task{Title: "Report", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	_, err := repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.TaskState.Status = domain.StatusFailed
+		return domain.ErrTaskAlreadyFinished
+	})
+	assert.ErrorIs(t, err, domain.ErrTaskAlreadyFinished, "error of update is returned as is")
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusInProgress, got.TaskState.Status, "failed update leaves the task untouched")
+	assert.WithinDuration(t, task.UpdatedAt, got.UpdatedAt, timePrecision)
+}
+
+func testDelete(t *testing.T, repo TaskRepository) {
+	deleted := createTask(t, repo, domain.Task{Title: "Deleted"})
+	kept := createTask(t, repo, domain.Task{Title: "Kept"})
+
+	require.NoError(t, repo.DeleteTask(t.Context(), deleted.ID, domain.AnyVersion))
+
+	_, err := repo.GetTaskByID(t.Context(), deleted.ID)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), deleted.ID, domain.AnyVersion), domain.ErrTaskNotFound, "second delete reports not found")
+
+	_, err = repo.GetTaskByID(t.Context(), kept.ID)
+	assert.NoError(t, err)
+}
+
+func testNotFound(t *testing.T, repo TaskRepository) {
+	id := uuid.New()
+
+	_, err := repo.GetTaskByID(t.Context(), id)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+
+	called := false
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	assert.False(t, called, "update is not called for a missing task")
+
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), id, domain.AnyVersion), domain.ErrTaskNotFound)
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), id, 1), domain.ErrTaskNotFound, "missing task is not reported as a version mismatch")
+}
+
+func testOutbox(t *testing.T, repo TaskRepository) {
+	created := domain.NewTaskEvent(domain.EventTaskCreated, domain.Task{})
+	task := domain.Task{Title: "Report", TaskState: domain.TaskState{Status: domain.StatusInProgress}}
+	_, err := repo.CreateTask(t.Context(), &task, created)
+	require.NoError(t, err)
+
+	progress := domain.NewTaskEvent(domain.EventTaskProgress, domain.Task{})
+	_, err = repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.TaskState.Progress = 40
+		return nil
+	}, progress)
+	require.NoError(t, err)
+
+	_, err = repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		return domain.ErrTaskAlreadyFinished
+	}, domain.NewTaskEvent(domain.EventTaskFailed, domain.Task{}))
+	require.Error(t, err)
+
+	events, err := repo.ListPendingEvents(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2, "events of a failed update are not stored")
+	assert.Equal(t, created.ID, events[0].ID)
+	assert.Equal(t, domain.EventTaskCreated, events[0].Type)
+	assert.Equal(t, task.ID, events[0].Task.ID, "event carries the stored task")
+	assert.Equal(t, int64(1), events[0].Task.Version)
+	assert.Equal(t, progress.ID, events[1].ID)
+	assert.Equal(t, 40, events[1].Task.TaskState.Progress)
+	assert.Equal(t, int64(2), events[1].Task.Version)
+
+	events, err = repo.ListPendingEvents(t.Context(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, created.ID, events[0].ID, "oldest events come first")
+
+	require.NoError(t, repo.MarkEventsDelivered(t.Context(), []uuid.UUID{created.ID}))
+	require.NoError(t, repo.MarkEventsDelivered(t.Context(), []uuid.UUID{created.ID, uuid.New()}), "marking again is not an error")
+
+	purged := domain.NewTaskEvent(domain.EventTaskPurged, task)
+	require.NoError(t, repo.DeleteTask(t.Context(), task.ID, domain.AnyVersion, purged))
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), task.ID, domain.AnyVersion, domain.NewTaskEvent(domain.EventTaskPurged, task)), domain.ErrTaskNotFound)
+
+	events, err = repo.ListPendingEvents(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, progress.ID, events[0].ID)
+	assert.Equal(t, purged.ID, events[1].ID, "delete stores its events as given")
+	assert.Equal(t, task.ID, events[1].Task.ID)
+
+	require.NoError(t, repo.MarkEventsDelivered(t.Context(), []uuid.UUID{progress.ID, purged.ID}))
+	events, err = repo.ListPendingEvents(t.Context(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func testHistory(t *testing.T, repo TaskRepository) {
+	withActor := func(eventType domain.TaskEventType, actor string) domain.TaskEvent {
+		event := domain.NewTaskEvent(eventType, domain.Task{})
+		event.Actor = actor
+		return event
+	}
+	task := domain.Task{Title: "Report", TaskState: domain.TaskState{Status: domain.StatusInProgress}, Attempt: 1}
+	_, err := repo.CreateTask(t.Context(), &task, withActor(domain.EventTaskCreated, "alice"), withActor(domain.EventTaskStarted, "alice"))
+	require.NoError(t, err)
+	other := domain.Task{Title: "Other"}
+	_, err = repo.CreateTask(t.Context(), &other, withActor(domain.EventTaskCreated, "bob"))
+	require.NoError(t, err)
+
+	_, err = repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.TaskState.Progress = 40
+		return nil
+	}, withActor(domain.EventTaskProgress, "executor-7"))
+	require.NoError(t, err)
+	_, err = repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		return domain.ErrTaskAlreadyFinished
+	}, withActor(domain.EventTaskFailed, "executor-7"))
+	require.Error(t, err)
+	_, err = repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.TaskState = domain.TaskState{Status: domain.StatusCompleted, Progress: 100}
+		return nil
+	}, withActor(domain.EventTaskCompleted, "executor-7"))
+	require.NoError(t, err)
+	// delivered events stay in the history
+	pending, err := repo.ListPendingEvents(t.Context(), 10)
+	require.NoError(t, err)
+	for _, event := range pending {
+		require.NoError(t, repo.MarkEventsDelivered(t.Context(), []uuid.UUID{event.ID}))
+	}
+
+	events, err := repo.ReadTaskHistory(t.Context(), task.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 4, "events of a failed update are not stored")
+	var types []domain.TaskEventType
+	for i, event := range events {
+		assert.Equal(t, int64(i+1), event.Seq)
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []domain.TaskEventType{domain.EventTaskCreated, domain.EventTaskStarted, domain.EventTaskProgress, domain.EventTaskCompleted}, types)
+	assert.Equal(t, "executor-7", events[2].Actor)
+	assert.Equal(t, 40, events[2].Progress)
+	assert.Equal(t, int64(2), events[2].Version)
+	assert.Equal(t, domain.StatusCompleted, events[3].Status)
+
+	state, err := domain.ReplayTaskHistory(events)
+	require.NoError(t, err)
+	stored, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Empty(t, state.Mismatch(stored))
+
+	events, err = repo.ReadTaskHistory(t.Context(), uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	require.NoError(t, repo.DeleteTask(t.Context(), task.ID, domain.AnyVersion, domain.NewTaskEvent(domain.EventTaskPurged, stored)))
+	events, err = repo.ReadTaskHistory(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Empty(t, events, "history is deleted with the task")
+
+	events, err = repo.ReadTaskHistory(t.Context(), other.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "bob", events[0].Actor)
+}
+
+func testListTasks(t *testing.T, repo TaskRepository) {
+	base := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	deletedAt := at(6)
+	task := func(id string, created, updated int, status domain.TaskStatus, taskType string, labels ...string) domain.Task {
+		return domain.Task{
+			ID:        uuid.MustParse("00000000-0000-0000-0000-00000000000" + id),
+			Title:     "task " + id,
+			Type:      taskType,
+			Labels:    labels,
+			TaskState: domain.TaskState{Status: status},
+			Attempt:   1,
+			Version:   1,
+			CreatedAt: at(created),
+			UpdatedAt: at(updated),
+		}
+	}
+	a := task("a", 0, 5, domain.StatusCompleted, "report", "billing")
+	b := task("2", 1, 1, domain.StatusInProgress, "export", "nightly")
+	c := task("1", 1, 3, domain.StatusFailed, "report", "billing", "nightly") // created with b, its id orders it first
+	d := task("d", 2, 2, domain.StatusInProgress, "")
+	deleted := task("e", 3, 4, domain.StatusInProgress, "report", "billing")
+	deleted.DeletedAt = &deletedAt
+	for _, task := range []domain.Task{d, deleted, c, a, b} {
+		require.NoError(t, repo.ImportTask(t.Context(), task, false))
+	}
+
+	list := func(query domain.TaskListQuery) []uuid.UUID {
+		t.Helper()
+		if query.Limit == 0 {
+			query.Limit = 10
+		}
+		tasks, err := repo.ListTasks(t.Context(), query)
+		require.NoError(t, err)
+		ids := make([]uuid.UUID, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.ID
+		}
+		return ids
+	}
+	ids := func(tasks ...domain.Task) []uuid.UUID {
+		ids := make([]uuid.UUID, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.ID
+		}
+		return ids
+	}
+
+	assert.Equal(t, ids(a, c, b, d), list(domain.TaskListQuery{Sort: domain.SortCreatedAsc}), "tasks in the trash are not listed")
+	assert.Equal(t, ids(d, b, c, a), list(domain.TaskListQuery{Sort: domain.SortCreatedDesc}))
+	assert.Equal(t, ids(b, d, c, a), list(domain.TaskListQuery{Sort: domain.SortUpdatedAsc}))
+	assert.Equal(t, ids(a, c, d, b), list(domain.TaskListQuery{Sort: domain.SortUpdatedDesc}))
+	assert.Equal(t, ids(a, c), list(domain.TaskListQuery{Sort: domain.SortCreatedAsc, Limit: 2}))
+
+	filtered := []struct {
+		name   string
+		filter domain.TaskListFilter
+		want   []uuid.UUID
+	}{
+		{"statuses", domain.TaskListFilter{Statuses: []domain.TaskStatus{domain.StatusInProgress, domain.StatusFailed}}, ids(c, b, d)},
+		{"types", domain.TaskListFilter{Types: []string{"report", "missing"}}, ids(a, c)},
+		{"labels", domain.TaskListFilter{Labels: []string{"nightly", "billing"}}, ids(a, c, b)},
+		{"created range", domain.TaskListFilter{CreatedFrom: at(1), CreatedTo: at(2)}, ids(c, b)},
+		{"updated range", domain.TaskListFilter{UpdatedFrom: at(2), UpdatedTo: at(5)}, ids(c, d)},
+		{"all of them", domain.TaskListFilter{Types: []string{"report"}, Labels: []string{"nightly"}, UpdatedTo: at(5)}, ids(c)},
+		{"nothing", domain.TaskListFilter{Types: []string{"missing"}}, ids()},
+	}
+	for _, tc := range filtered {
+		assert.Equal(t, tc.want, list(domain.TaskListQuery{Filter: tc.filter, Sort: domain.SortCreatedAsc}), tc.name)
+	}
+
+	// paging with the cursor of the last task goes through the same tasks as one list
+	for _, sort := range domain.TaskSorts {
+		for _, filter := range []domain.TaskListFilter{{}, {Labels: []string{"billing"}}, {Statuses: []domain.TaskStatus{domain.StatusInProgress, domain.StatusFailed}}, {CreatedFrom: at(1), UpdatedTo: at(5)}} {
+			want := list(domain.TaskListQuery{Filter: filter, Sort: sort})
+			for _, limit := range []int{1, 2} {
+				var got []uuid.UUID
+				query := domain.TaskListQuery{Filter: filter, Sort: sort, Limit: limit}
+				for range 10 {
+					tasks, err := repo.ListTasks(t.Context(), query)
+					require.NoError(t, err)
+					if len(tasks) == 0 {
+						break
+					}
+					got = append(got, ids(tasks...)...)
+					cursor := domain.NewTaskCursor(sort, tasks[len(tasks)-1])
+					query.After = &cursor
+				}
+				assert.Equal(t, want, got, "sort %s, limit %d, filter %+v", sort, limit, filter)
+			}
+		}
+	}
+}
+
+func testConcurrentCreates(t *testing.T, repo TaskRepository) {
+	const workers = 8
+	const perWorker = 10
+
+	var mu sync.Mutex
+	ids := make(map[uuid.UUID]struct{})
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "concurrent"})
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				ids[id] = struct{}{}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, ids, workers*perWorker, "every create gets its own id")
+}
+
+func testConcurrentUpdates(t *testing.T, repo TaskRepository) {
+	const workers = 8
+	const perWorker = 5
+
+	task := createTask(t, repo, domain.Task{Title: "counter"})
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				_, err := repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+					task.TaskState.Progress++
+					return nil
+				})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	// readers run next to the writers and must never see a missing task
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				_, err := repo.GetTaskByID(t.Context(), task.ID)
+				assert.False(t, errors.Is(err, domain.ErrTaskNotFound))
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, workers*perWorker, got.TaskState.Progress, "no update is lost")
+	assert.Equal(t, int64(1+workers*perWorker), got.Version, "every update gets its own version")
+}
+
+func testVersions(t *testing.T, repo TaskRepository) {
+	task := createTask(t, repo, domain.Task{Title: "Report", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	assert.Equal(t, int64(1), task.Version, "version starts at 1")
+
+	updated, err := repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		task.TaskState.Progress = 40
+		task.Version = 100 // the stored version is bumped, whatever update does to it
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+
+	_, err = repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error {
+		return domain.ErrTaskAlreadyFinished
+	})
+	require.Error(t, err)
+
+	got, err := repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.Version, "failed update keeps the version")
+}
+
+func testDeleteVersion(t *testing.T, repo TaskRepository) {
+	task := createTask(t, repo, domain.Task{Title: "Report"})
+	_, err := repo.UpdateTask(t.Context(), task.ID, func(task *domain.Task) error { return nil })
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), task.ID, 1), domain.ErrVersionMismatch)
+	_, err = repo.GetTaskByID(t.Context(), task.ID)
+	require.NoError(t, err, "task with another version is kept")
+
+	require.NoError(t, repo.DeleteTask(t.Context(), task.ID, 2))
+	_, err = repo.GetTaskByID(t.Context(), task.ID)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+}
+
+func testTrash(t *testing.T, repo TaskRepository) {
+	kept := createTask(t, repo, domain.Task{Title: "kept"})
+	first := createTask(t, repo, domain.Task{Title: "first"})
+	second := createTask(t, repo, domain.Task{Title: "second"})
+
+	deletedAt := time.Now()
+	for i, id := range []uuid.UUID{first.ID, second.ID} {
+		at := deletedAt.Add(time.Duration(i) * time.Second)
+		_, err := repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+			task.DeletedAt = &at
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	deleted, err := repo.ListDeletedTasks(t.Context())
+	require.NoError(t, err)
+	require.Len(t, deleted, 2)
+	assert.Equal(t, first.ID, deleted[0].ID, "earliest deleted goes first")
+	assert.Equal(t, second.ID, deleted[1].ID)
+	require.NotNil(t, deleted[0].DeletedAt)
+	assert.WithinDuration(t, deletedAt, *deleted[0].DeletedAt, timePrecision)
+
+	got, err := repo.GetTaskByID(t.Context(), first.ID)
+	require.NoError(t, err, "deleted task is still stored")
+	assert.True(t, got.IsDeleted())
+	got, err = repo.GetTaskByID(t.Context(), kept.ID)
+	require.NoError(t, err)
+	assert.False(t, got.IsDeleted())
+
+	// restored and purged tasks leave the trash
+	_, err = repo.UpdateTask(t.Context(), first.ID, func(task *domain.Task) error {
+		task.DeletedAt = nil
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTask(t.Context(), second.ID, domain.AnyVersion))
+
+	deleted, err = repo.ListDeletedTasks(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	got, err = repo.GetTaskByID(t.Context(), first.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.DeletedAt)
+}
+
+func testWalkAndImport(t *testing.T, repo TaskRepository) {
+	first := createTask(t, repo, domain.Task{Title: "first", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	second := createTask(t, repo, domain.Task{Title: "second", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+
+	createdAt := first.CreatedAt.Add(-time.Hour).Truncate(timePrecision)
+	deletedAt := createdAt.Add(time.Minute)
+	imported := domain.Task{
+		ID:          uuid.New(),
+		Title:       "imported",
+		Labels:      []string{"moved"},
+		TaskState:   domain.TaskState{Status: domain.StatusCompleted, Progress: 100},
+		Attempt:     2,
+		Version:     7,
+		Result:      json.RawMessage(`{"rows":3}`),
+		CreatedAt:   createdAt,
+		UpdatedAt:   deletedAt,
+		CompletedAt: &createdAt,
+		DeletedAt:   &deletedAt,
+	}
+	require.NoError(t, repo.ImportTask(t.Context(), imported, false))
+
+	got, err := repo.GetTaskByID(t.Context(), imported.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "imported", got.Title)
+	assert.Equal(t, int64(7), got.Version, "version is kept")
+	assert.True(t, createdAt.Equal(got.CreatedAt), "created_at is kept")
+	assert.JSONEq(t, `{"rows":3}`, string(got.Result))
+	require.NotNil(t, got.DeletedAt)
+
+	deleted, err := repo.ListDeletedTasks(t.Context())
+	require.NoError(t, err)
+	require.Len(t, deleted, 1, "imported task in the trash is listed there")
+
+	var walked []uuid.UUID
+	err = repo.WalkTasks(t.Context(), func(task domain.Task) error {
+		walked = append(walked, task.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{imported.ID, first.ID, second.ID}, walked, "tasks are walked in creation order")
+
+	// conflicting ids
+	conflict := imported
+	conflict.Title = "conflict"
+	conflict.DeletedAt = nil
+	err = repo.ImportTask(t.Context(), conflict, false)
+	assert.ErrorIs(t, err, domain.ErrTaskExists)
+	got, err = repo.GetTaskByID(t.Context(), imported.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "imported", got.Title, "stored task is kept")
+
+	require.NoError(t, repo.ImportTask(t.Context(), conflict, true))
+	got, err = repo.GetTaskByID(t.Context(), imported.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "conflict", got.Title, "stored task is overwritten")
+	assert.Nil(t, got.DeletedAt)
+	deleted, err = repo.ListDeletedTasks(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, deleted, "overwritten task leaves the trash")
+
+	// fn error stops the walk
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.WalkTasks(t.Context(), func(task domain.Task) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,5 @@
+package sqlite
+
+type Config struct {
+	Path string `env:"SQLITE_PATH" envDefault:"./data/tasks.db"`
+}
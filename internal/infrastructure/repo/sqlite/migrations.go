@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrations, _ = fs.Sub(migrationFiles, "migrations")
+
+// migrationDriver records applied versions in schema_migrations, applied_at is stored as unix nanoseconds
+type migrationDriver struct {
+	db *sql.DB
+}
+
+func (d migrationDriver) Init(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at INTEGER NOT NULL)`)
+	return err
+}
+
+func (d migrationDriver) Applied(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = time.Unix(0, appliedAt)
+	}
+	return applied, rows.Err()
+}
+
+func (d migrationDriver) Apply(ctx context.Context, version int, script string) error {
+	return d.inTx(ctx, script, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().UnixNano())
+}
+
+func (d migrationDriver) Revert(ctx context.Context, version int, script string) error {
+	return d.inTx(ctx, script, `DELETE FROM schema_migrations WHERE version = ?`, version)
+}
+
+func (d migrationDriver) inTx(ctx context.Context, script, record string, args ...any) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,20 @@
+-- IF NOT EXISTS adopts databases created before migrations were introduced
+CREATE TABLE IF NOT EXISTS tasks (
+	id                  TEXT PRIMARY KEY,
+	title               TEXT NOT NULL,
+	description         TEXT NOT NULL,
+	type                TEXT NOT NULL DEFAULT '',
+	labels              TEXT NOT NULL DEFAULT '[]',
+	status              TEXT NOT NULL,
+	work_duration       INTEGER NOT NULL DEFAULT 0,
+	progress            INTEGER NOT NULL DEFAULT 0,
+	attempt             INTEGER NOT NULL DEFAULT 1,
+	result              BLOB,
+	result_content_type TEXT NOT NULL DEFAULT '',
+	result_blob_key     TEXT,
+	result_blob_size    INTEGER,
+	callback_url        TEXT NOT NULL DEFAULT '',
+	created_at          INTEGER NOT NULL,
+	updated_at          INTEGER NOT NULL,
+	completed_at        INTEGER
+);
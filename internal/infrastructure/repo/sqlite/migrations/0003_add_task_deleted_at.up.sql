@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN deleted_at INTEGER;
+CREATE INDEX IF NOT EXISTS tasks_deleted_at ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
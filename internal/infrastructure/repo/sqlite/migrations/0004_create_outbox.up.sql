@@ -0,0 +1,6 @@
+-- events of stored task changes waiting to be published, seq keeps the order they were stored in
+CREATE TABLE IF NOT EXISTS outbox (
+	seq   INTEGER PRIMARY KEY AUTOINCREMENT,
+	id    TEXT NOT NULL UNIQUE,
+	event BLOB NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS tasks_updated_at;
+DROP INDEX IF EXISTS tasks_created_at;
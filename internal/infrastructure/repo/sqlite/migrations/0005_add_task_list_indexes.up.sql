@@ -0,0 +1,3 @@
+-- lists of tasks are read in the order of creation or update time, ties are ordered by id
+CREATE INDEX IF NOT EXISTS tasks_created_at ON tasks (created_at, id);
+CREATE INDEX IF NOT EXISTS tasks_updated_at ON tasks (updated_at, id);
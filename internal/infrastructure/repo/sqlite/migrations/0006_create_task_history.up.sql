@@ -0,0 +1,7 @@
+-- events of every task, seq keeps the order they were stored in
+CREATE TABLE IF NOT EXISTS task_history (
+	seq     INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id TEXT NOT NULL,
+	event   BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS task_history_task_id ON task_history (task_id, seq);
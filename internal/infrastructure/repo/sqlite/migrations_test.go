@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations_UpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewTaskRepository(Config{Path: filepath.Join(t.TempDir(), "tasks.db")})
+	require.NoError(t, err)
+	defer repo.Close()
+	migrator := repo.Migrator()
+
+	assert.ErrorIs(t, migrator.Check(ctx), migrate.ErrSchemaBehind)
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+	for _, status := range statuses {
+		assert.Nil(t, status.AppliedAt)
+	}
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(statuses))
+	require.NoError(t, migrator.Check(ctx))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "up is a no-op on a current schema")
+
+	_, err = repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+	require.NoError(t, err)
+
+	for range statuses {
+		_, err := migrator.Down(ctx)
+		require.NoError(t, err)
+	}
+	_, err = migrator.Down(ctx)
+	assert.ErrorIs(t, err, migrate.ErrNoMigrationsApplied)
+
+	_, err = repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task"})
+	assert.Error(t, err, "tasks table is dropped by down")
+}
@@ -0,0 +1,583 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/migrate"
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+const taskColumns = `id, title, description, type, labels, status, work_duration, progress, attempt, result, result_content_type,
+	result_blob_key, result_blob_size, callback_url, created_at, updated_at, completed_at, version, deleted_at`
+
+// TaskRepository keeps tasks in a SQLite database file, timestamps are stored as unix nanoseconds
+type TaskRepository struct {
+	db       *sql.DB
+	migrator *migrate.Migrator
+}
+
+func NewTaskRepository(cfg Config) (*TaskRepository, error) {
+	const op = "sqlite.NewTaskRepository"
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+cfg.Path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// sqlite has a single writer, one connection serializes updates instead of failing them with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	migrator, err := migrate.New(migrationDriver{db: db}, migrations)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &TaskRepository{db: db, migrator: migrator}, nil
+}
+
+// Migrator manages the schema, the repository does not check it, callers must apply or check migrations first
+func (r *TaskRepository) Migrator() *migrate.Migrator {
+	return r.migrator
+}
+
+func (r *TaskRepository) Close() error {
+	return r.db.Close()
+}
+
+// CreateTask inserts the task and its events in one transaction
+func (r *TaskRepository) CreateTask(ctx context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error) {
+	const op = "TaskRepository.CreateTask"
+
+	now := time.Now()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.Version = 1
+	task.ID = uuid.New()
+
+	args, err := taskArgs(*task)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := insertEvents(ctx, tx, domain.EventsWithTask(events, *task)); err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return task.ID, nil
+}
+
+func (r *TaskRepository) GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error) {
+	const op = "TaskRepository.GetTaskByID"
+
+	task, err := r.getTask(ctx, r.db, id)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return task, nil
+}
+
+// UpdateTask reads the task and writes it back updated with the next version and its events in one transaction, the write is guarded
+// by the version that was read. If update returns an error the task is left untouched
+func (r *TaskRepository) UpdateTask(ctx context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error) {
+	const op = "TaskRepository.UpdateTask"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	task, err := r.getTask(ctx, tx, id)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	previous := task.Version
+	if err := update(&task); err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	task.ID = id
+	task.UpdatedAt = time.Now()
+	task.Version = previous + 1
+
+	args, err := taskArgs(task)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// id and the version that was read go last for the where clause
+	args = append(args[1:], args[0], previous)
+	res, err := tx.ExecContext(ctx, `UPDATE tasks SET title = ?, description = ?, type = ?, labels = ?, status = ?, work_duration = ?, progress = ?,
+		attempt = ?, result = ?, result_content_type = ?, result_blob_key = ?, result_blob_size = ?, callback_url = ?,
+		created_at = ?, updated_at = ?, completed_at = ?, version = ?, deleted_at = ? WHERE id = ? AND version = ?`, args...)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if updated, err := res.RowsAffected(); err != nil || updated == 0 {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, errors.Join(domain.ErrVersionMismatch, err))
+	}
+	if err := insertEvents(ctx, tx, domain.EventsWithTask(events, task)); err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return task, nil
+}
+
+// DeleteTask deletes the task if it still has the version, domain.AnyVersion deletes it unconditionally.
+// The events are stored in the same transaction
+func (r *TaskRepository) DeleteTask(ctx context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error {
+	const op = "TaskRepository.DeleteTask"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM tasks WHERE id = ? AND (? = 0 OR version = ?)`, id.String(), version, version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted == 0 {
+		// tell a missing task from a changed one
+		if _, err := r.getTask(ctx, tx, id); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return fmt.Errorf("%s: %w", op, domain.ErrVersionMismatch)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// after the events, so the history goes with the task entirely
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_history WHERE task_id = ?`, id.String()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ListDeletedTasks returns tasks in the trash ordered by deletion time
+func (r *TaskRepository) ListDeletedTasks(ctx context.Context) ([]domain.Task, error) {
+	const op = "TaskRepository.ListDeletedTasks"
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE deleted_at IS NOT NULL ORDER BY deleted_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var tasks []domain.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tasks, nil
+}
+
+// ListTasks returns up to query.Limit tasks the query lists in its order, rows are read by the index of the sort column from the cursor on
+func (r *TaskRepository) ListTasks(ctx context.Context, query domain.TaskListQuery) ([]domain.Task, error) {
+	const op = "TaskRepository.ListTasks"
+
+	where, args := listConditions(query)
+	column, order := listOrder(query.Sort)
+	rows, err := r.db.QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE `+where+` ORDER BY `+column+` `+order+`, id `+order+` LIMIT ?`,
+		append(args, query.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var tasks []domain.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tasks, nil
+}
+
+// walkBatch is how many tasks a query of WalkTasks reads
+const walkBatch = 500
+
+// WalkTasks calls fn with every stored task ordered by creation time, it stops at the first error of fn. Tasks are read in batches
+// from the position of the last one and fn is called between the queries, so a slow fn doesn't hold the single connection of the repository
+func (r *TaskRepository) WalkTasks(ctx context.Context, fn func(task domain.Task) error) error {
+	const op = "TaskRepository.WalkTasks"
+
+	var last *domain.Task
+	for {
+		where, args := `1 = 1`, []any{}
+		if last != nil {
+			where, args = `(created_at, id) > (?, ?)`, []any{last.CreatedAt.UnixNano(), last.ID.String()}
+		}
+		tasks, err := r.queryTasks(ctx, `SELECT `+taskColumns+` FROM tasks WHERE `+where+` ORDER BY created_at, id LIMIT ?`, append(args, walkBatch)...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, task := range tasks {
+			if err := fn(task); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if len(tasks) < walkBatch {
+			return nil
+		}
+		last = &tasks[len(tasks)-1]
+	}
+}
+
+func (r *TaskRepository) queryTasks(ctx context.Context, query string, args ...any) ([]domain.Task, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []domain.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// ImportTask stores the task as it is, id, timestamps and version included. If the id is already stored the task
+// replaces it when overwrite is set, otherwise domain.ErrTaskExists is returned
+func (r *TaskRepository) ImportTask(ctx context.Context, task domain.Task, overwrite bool) error {
+	const op = "TaskRepository.ImportTask"
+
+	args, err := taskArgs(task)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	onConflict := `DO NOTHING`
+	if overwrite {
+		onConflict = `DO UPDATE SET title = excluded.title, description = excluded.description, type = excluded.type, labels = excluded.labels,
+		status = excluded.status, work_duration = excluded.work_duration, progress = excluded.progress, attempt = excluded.attempt,
+		result = excluded.result, result_content_type = excluded.result_content_type, result_blob_key = excluded.result_blob_key,
+		result_blob_size = excluded.result_blob_size, callback_url = excluded.callback_url, created_at = excluded.created_at,
+		updated_at = excluded.updated_at, completed_at = excluded.completed_at, version = excluded.version, deleted_at = excluded.deleted_at`
+	}
+	res, err := r.db.ExecContext(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) `+onConflict, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	stored, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if stored == 0 {
+		return fmt.Errorf("%s: %w", op, domain.ErrTaskExists)
+	}
+	return nil
+}
+
+// ListPendingEvents returns up to limit events of the outbox in the order they were stored
+func (r *TaskRepository) ListPendingEvents(ctx context.Context, limit int) ([]domain.TaskEvent, error) {
+	const op = "TaskRepository.ListPendingEvents"
+
+	rows, err := r.db.QueryContext(ctx, `SELECT event FROM outbox ORDER BY seq LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []domain.TaskEvent
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		var event domain.TaskEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// MarkEventsDelivered removes the events from the outbox, ids that are not pending are ignored
+func (r *TaskRepository) MarkEventsDelivered(ctx context.Context, ids []uuid.UUID) error {
+	const op = "TaskRepository.MarkEventsDelivered"
+
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id.String()
+	}
+	_, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE id IN (?`+strings.Repeat(`, ?`, len(ids)-1)+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ReadTaskHistory returns the history of the task oldest first
+func (r *TaskRepository) ReadTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error) {
+	const op = "TaskRepository.ReadTaskHistory"
+
+	rows, err := r.db.QueryContext(ctx, `SELECT event FROM task_history WHERE task_id = ? ORDER BY seq`, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []domain.TaskHistoryEvent
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		var event domain.TaskHistoryEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		event.Seq = int64(len(events) + 1)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// insertEvents appends the events to the outbox and to the history of their task within the transaction of the change
+func insertEvents(ctx context.Context, tx *sql.Tx, events []domain.TaskEvent) error {
+	for _, event := range events {
+		raw, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO outbox (id, event) VALUES (?, ?)`, event.ID.String(), raw); err != nil {
+			return err
+		}
+		entry, err := json.Marshal(domain.NewTaskHistoryEvent(event))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_history (task_id, event) VALUES (?, ?)`, event.Task.ID.String(), entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (r *TaskRepository) getTask(ctx context.Context, q queryer, id uuid.UUID) (domain.Task, error) {
+	return scanTask(q.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id.String()))
+}
+
+// listConditions returns the WHERE clause of the query and its args
+func listConditions(query domain.TaskListQuery) (string, []any) {
+	conditions := []string{`deleted_at IS NULL`}
+	var args []any
+	in := func(values []string) string {
+		for _, value := range values {
+			args = append(args, value)
+		}
+		return `(?` + strings.Repeat(`, ?`, len(values)-1) + `)`
+	}
+	bound := func(condition string, t time.Time) {
+		if !t.IsZero() {
+			conditions = append(conditions, condition)
+			args = append(args, t.UnixNano())
+		}
+	}
+
+	filter := query.Filter
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		conditions = append(conditions, `status IN `+in(statuses))
+	}
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, `type IN `+in(filter.Types))
+	}
+	if len(filter.Labels) > 0 {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM json_each(tasks.labels) WHERE json_each.value IN `+in(filter.Labels)+`)`)
+	}
+	bound(`created_at >= ?`, filter.CreatedFrom)
+	bound(`created_at < ?`, filter.CreatedTo)
+	bound(`updated_at >= ?`, filter.UpdatedFrom)
+	bound(`updated_at < ?`, filter.UpdatedTo)
+
+	if query.After != nil {
+		column, _ := listOrder(query.Sort)
+		comparison := `>`
+		if query.Sort.Desc() {
+			comparison = `<`
+		}
+		// ids are stored as lowercase text, which sorts like their bytes
+		conditions = append(conditions, `(`+column+`, id) `+comparison+` (?, ?)`)
+		args = append(args, query.After.At.UnixNano(), query.After.ID.String())
+	}
+	return strings.Join(conditions, ` AND `), args
+}
+
+func listOrder(sort domain.TaskSort) (column, order string) {
+	column, order = `created_at`, `ASC`
+	if sort.ByUpdate() {
+		column = `updated_at`
+	}
+	if sort.Desc() {
+		order = `DESC`
+	}
+	return column, order
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask reads a row of taskColumns
+func scanTask(row rowScanner) (domain.Task, error) {
+	var (
+		task                 domain.Task
+		rawID, labels        string
+		result               []byte
+		blobKey              sql.NullString
+		blobSize             sql.NullInt64
+		createdAt, updatedAt int64
+		completedAt          sql.NullInt64
+		deletedAt            sql.NullInt64
+	)
+	err := row.Scan(&rawID, &task.Title, &task.Description, &task.Type, &labels, &task.TaskState.Status, &task.TaskState.WorkDuration,
+		&task.TaskState.Progress, &task.Attempt, &result, &task.ResultContentType, &blobKey, &blobSize, &task.CallbackURL,
+		&createdAt, &updatedAt, &completedAt, &task.Version, &deletedAt)
+	if err != nil {
+		return domain.Task{}, notFound(err)
+	}
+
+	if task.ID, err = uuid.Parse(rawID); err != nil {
+		return domain.Task{}, err
+	}
+	if err := json.Unmarshal([]byte(labels), &task.Labels); err != nil {
+		return domain.Task{}, err
+	}
+	if len(task.Labels) == 0 {
+		task.Labels = nil
+	}
+	if len(result) > 0 {
+		task.Result = result
+	}
+	if blobKey.Valid {
+		task.ResultBlob = &domain.ResultBlob{Key: blobKey.String, Size: blobSize.Int64}
+	}
+	task.CreatedAt = time.Unix(0, createdAt)
+	task.UpdatedAt = time.Unix(0, updatedAt)
+	if completedAt.Valid {
+		completed := time.Unix(0, completedAt.Int64)
+		task.CompletedAt = &completed
+	}
+	if deletedAt.Valid {
+		deleted := time.Unix(0, deletedAt.Int64)
+		task.DeletedAt = &deleted
+	}
+
+	return task, nil
+}
+
+// taskArgs returns column values of the task in taskColumns order
+func taskArgs(task domain.Task) ([]any, error) {
+	labels := task.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	rawLabels, err := json.Marshal(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var result, blobKey, blobSize, completedAt, deletedAt any
+	if len(task.Result) > 0 {
+		result = []byte(task.Result)
+	}
+	if task.ResultBlob != nil {
+		blobKey = task.ResultBlob.Key
+		blobSize = task.ResultBlob.Size
+	}
+	if task.CompletedAt != nil {
+		completedAt = task.CompletedAt.UnixNano()
+	}
+	if task.DeletedAt != nil {
+		deletedAt = task.DeletedAt.UnixNano()
+	}
+
+	return []any{
+		task.ID.String(), task.Title, task.Description, task.Type, string(rawLabels), string(task.TaskState.Status),
+		int64(task.TaskState.WorkDuration), task.TaskState.Progress, task.Attempt, result, task.ResultContentType,
+		blobKey, blobSize, task.CallbackURL, task.CreatedAt.UnixNano(), task.UpdatedAt.UnixNano(), completedAt, task.Version, deletedAt,
+	}, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrTaskNotFound
+	}
+	return err
+}
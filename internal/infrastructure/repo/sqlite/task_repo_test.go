@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T, path string) *TaskRepository {
+	t.Helper()
+
+	repo, err := NewTaskRepository(Config{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	_, err = repo.Migrator().Up(context.Background())
+	require.NoError(t, err)
+	return repo
+}
+
+func TestTaskRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.TaskRepository {
+		return newTestRepository(t, filepath.Join(t.TempDir(), "tasks.db"))
+	})
+}
+
+func TestTaskRepository_RoundTrip(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.db"))
+
+	task := &domain.Task{
+		Title:       "Test Task",
+		Description: "Test Description",
+		Type:        "report",
+		Labels:      []string{"billing", "nightly"},
+		TaskState:   domain.TaskState{Status: domain.StatusInProgress},
+		Attempt:     1,
+		CallbackURL: "https://example.com/hook",
+	}
+	id, err := repo.CreateTask(t.Context(), task)
+	require.NoError(t, err)
+
+	got, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, id, got.ID)
+	assert.Equal(t, task.Title, got.Title)
+	assert.Equal(t, task.Labels, got.Labels)
+	assert.Equal(t, task.CallbackURL, got.CallbackURL)
+	assert.True(t, task.CreatedAt.Equal(got.CreatedAt))
+	assert.Nil(t, got.CompletedAt)
+	assert.Nil(t, got.Result)
+	assert.Equal(t, domain.StatusInProgress, got.TaskState.Status)
+}
+
+func TestTaskRepository_UpdateTask(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.db"))
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+
+	completedAt := time.Now()
+	updated, err := repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.TaskState = domain.TaskState{Status: domain.StatusCompleted, WorkDuration: time.Second, Progress: 100}
+		task.Result = json.RawMessage(`{"rows":3}`)
+		task.ResultContentType = domain.DefaultResultContentType
+		task.ResultBlob = &domain.ResultBlob{Key: "abc", Size: 10}
+		task.CompletedAt = &completedAt
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusCompleted, updated.TaskState.Status)
+
+	stored, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"rows":3}`, string(stored.Result))
+	assert.Equal(t, &domain.ResultBlob{Key: "abc", Size: 10}, stored.ResultBlob)
+	if assert.NotNil(t, stored.CompletedAt) {
+		assert.True(t, completedAt.Equal(*stored.CompletedAt))
+	}
+
+	// failed update leaves the task untouched
+	errRejected := errors.New("rejected")
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error {
+		task.Title = "Changed"
+		return errRejected
+	})
+	assert.ErrorIs(t, err, errRejected)
+
+	got, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Test Task", got.Title)
+	assert.Equal(t, time.Second, got.TaskState.WorkDuration)
+}
+
+func TestTaskRepository_SurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	repo, err := NewTaskRepository(Config{Path: path})
+	require.NoError(t, err)
+	_, err = repo.Migrator().Up(context.Background())
+	require.NoError(t, err)
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	repo = newTestRepository(t, path)
+	got, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Test Task", got.Title)
+}
+
+func TestTaskRepository_NotFound(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.db"))
+	id := uuid.New()
+
+	_, err := repo.GetTaskByID(t.Context(), id)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	_, err = repo.UpdateTask(t.Context(), id, func(task *domain.Task) error { return nil })
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+	assert.ErrorIs(t, repo.DeleteTask(t.Context(), id, domain.AnyVersion), domain.ErrTaskNotFound)
+}
+
+func TestTaskRepository_DeleteTask(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.db"))
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTask(t.Context(), id, domain.AnyVersion))
+
+	_, err = repo.GetTaskByID(t.Context(), id)
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+}
+
+func TestTaskRepository_CancelledContext(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.db"))
+
+	id, err := repo.CreateTask(t.Context(), &domain.Task{Title: "Test Task", TaskState: domain.TaskState{Status: domain.StatusInProgress}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err = repo.GetTaskByID(ctx, id)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateTask(ctx, id, func(task *domain.Task) error {
+		task.TaskState.Progress = 50
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	task, err := repo.GetTaskByID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), task.Version)
+}
+
+func TestTaskRepository_WalkTasksInBatches(t *testing.T) {
+	repo := newTestRepository(t, filepath.Join(t.TempDir(), "tasks.db"))
+
+	// tasks created at the same time are told apart by id when the next batch starts
+	at := time.Now()
+	for range walkBatch + 1 {
+		task := domain.Task{ID: uuid.New(), Title: "Test Task", Version: 1, CreatedAt: at, UpdatedAt: at}
+		require.NoError(t, repo.ImportTask(t.Context(), task, false))
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	err := repo.WalkTasks(t.Context(), func(task domain.Task) error {
+		// the walk doesn't hold the connection while fn runs
+		_, err := repo.GetTaskByID(t.Context(), task.ID)
+		seen[task.ID] = true
+		return err
+	})
+	require.NoError(t, err)
+	assert.Len(t, seen, walkBatch+1)
+}
@@ -0,0 +1,87 @@
+package resultschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+type Config struct {
+	// every <type>.json file in the dir is the schema of results of tasks with that type, empty dir disables validation
+	Dir string `env:"RESULT_SCHEMAS_DIR"`
+}
+
+// Registry validates task results against JSON Schemas of their task types, results of types without schema are not checked
+type Registry struct {
+	schemas map[string]*jsonschema.Schema
+}
+
+func NewRegistry(cfg Config) (*Registry, error) {
+	const op = "resultschema.NewRegistry"
+
+	r := &Registry{schemas: make(map[string]*jsonschema.Schema)}
+	if cfg.Dir == "" {
+		return r, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cfg.Dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, path := range paths {
+		taskType := strings.TrimSuffix(filepath.Base(path), ".json")
+		if err := r.load(taskType, path); err != nil {
+			return nil, fmt.Errorf("%s: schema of type %s: %w", op, taskType, err)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Registry) load(taskType, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(path, doc); err != nil {
+		return err
+	}
+	schema, err := compiler.Compile(path)
+	if err != nil {
+		return err
+	}
+
+	r.schemas[taskType] = schema
+	return nil
+}
+
+func (r *Registry) ValidateResult(taskType string, result json.RawMessage) error {
+	schema, exists := r.schemas[taskType]
+	if !exists {
+		return nil
+	}
+
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(result))
+	if err != nil {
+		return fmt.Errorf("%w: %s", domain.ErrInvalidResult, err.Error())
+	}
+
+	if err := schema.Validate(value); err != nil {
+		return fmt.Errorf("%w: %s", domain.ErrResultSchemaViolation, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package resultschema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_ValidateResult(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{
+		"type": "object",
+		"required": ["rows"],
+		"properties": {"rows": {"type": "integer", "minimum": 0}}
+	}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte(schema), 0o644))
+
+	registry, err := NewRegistry(Config{Dir: dir})
+	require.NoError(t, err)
+
+	assert.NoError(t, registry.ValidateResult("report", json.RawMessage(`{"rows": 3}`)))
+	assert.ErrorIs(t, registry.ValidateResult("report", json.RawMessage(`{"rows": -1}`)), domain.ErrResultSchemaViolation)
+	assert.ErrorIs(t, registry.ValidateResult("report", json.RawMessage(`"done"`)), domain.ErrResultSchemaViolation)
+
+	// types without schema are not checked
+	assert.NoError(t, registry.ValidateResult("export", json.RawMessage(`"done"`)))
+}
+
+func TestNewRegistry_InvalidSchema(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte(`{"type": 42}`), 0o644))
+
+	_, err := NewRegistry(Config{Dir: dir})
+	assert.Error(t, err)
+}
@@ -0,0 +1,7 @@
+package resultstore
+
+type Config struct {
+	Dir string `env:"RESULT_STORE_DIR" envDefault:"./data/results"`
+	// results larger than this many bytes are moved from the task to the store
+	InlineLimit int `env:"RESULT_INLINE_LIMIT" envDefault:"65536"`
+}
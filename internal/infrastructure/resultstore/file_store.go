@@ -0,0 +1,169 @@
+package resultstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+)
+
+// FileStore is a content-addressed blob store in a local directory, blobs are named after the sha256 of their content
+// and spread over subdirectories by the first two hex digits, so equal results are stored once. The modification time
+// of a blob is the last time it was put
+type FileStore struct {
+	dir string
+	mu  sync.Mutex // orders puts of existing blobs and removals, so a blob that is put again is not removed
+}
+
+func NewFileStore(cfg Config) (*FileStore, error) {
+	const op = "resultstore.NewFileStore"
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &FileStore{dir: cfg.Dir}, nil
+}
+
+func (s *FileStore) Put(content []byte) (domain.ResultBlob, error) {
+	const op = "FileStore.Put"
+
+	sum := sha256.Sum256(content)
+	blob := domain.ResultBlob{
+		Key:  hex.EncodeToString(sum[:]),
+		Size: int64(len(content)),
+	}
+
+	path := s.path(blob.Key)
+	s.mu.Lock()
+	if _, err := os.Stat(path); err == nil {
+		err := os.Chtimes(path, time.Time{}, time.Now())
+		s.mu.Unlock()
+		if err != nil {
+			return domain.ResultBlob{}, fmt.Errorf("%s: %w", op, err)
+		}
+		return blob, nil
+	}
+	s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return domain.ResultBlob{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// write to a temp file first so readers never see a partially written blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), blob.Key+".tmp-*")
+	if err != nil {
+		return domain.ResultBlob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return domain.ResultBlob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return domain.ResultBlob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return domain.ResultBlob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	s.mu.Lock()
+	err = os.Rename(tmp.Name(), path)
+	s.mu.Unlock()
+	if err != nil {
+		return domain.ResultBlob{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return blob, nil
+}
+
+func (s *FileStore) Open(key string) (io.ReadSeekCloser, time.Time, error) {
+	const op = "FileStore.Open"
+
+	// keys are joined into the path, anything but a hex sha256 could point outside the store
+	if !(domain.ResultBlob{Key: key}).ValidKey() {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", op, domain.ErrResultBlobMissing)
+	}
+
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, time.Time{}, fmt.Errorf("%s: %w", op, domain.ErrResultBlobMissing)
+		}
+		return nil, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return f, info.ModTime(), nil
+}
+
+// WalkBlobs calls fn with the key, size and modification time of every stored blob, it stops at the first error of fn
+func (s *FileStore) WalkBlobs(fn func(key string, size int64, modTime time.Time) error) error {
+	const op = "FileStore.WalkBlobs"
+
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// temp files of puts in progress have a suffix after the key
+		if entry.IsDir() || !(domain.ResultBlob{Key: entry.Name()}).ValidKey() {
+			return nil
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // removed after the directory was read
+		}
+		if err != nil {
+			return err
+		}
+		return fn(entry.Name(), info.Size(), info.ModTime())
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RemoveBlob removes the blob if it was last put before the time and reports whether it was removed
+func (s *FileStore) RemoveBlob(key string, before time.Time) (bool, error) {
+	const op = "FileStore.RemoveBlob"
+
+	if !(domain.ResultBlob{Key: key}).ValidKey() {
+		return false, fmt.Errorf("%s: %w", op, domain.ErrResultBlobMissing)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if !info.ModTime().Before(before) {
+		return false, nil
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key)
+}
@@ -0,0 +1,102 @@
+package resultstore
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore_PutAndOpen(t *testing.T) {
+	store, err := NewFileStore(Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	blob, err := store.Put([]byte("large result"))
+	require.NoError(t, err)
+	assert.Len(t, blob.Key, 64)
+	assert.Equal(t, int64(len("large result")), blob.Size)
+
+	// equal content is stored once under the same key
+	again, err := store.Put([]byte("large result"))
+	require.NoError(t, err)
+	assert.Equal(t, blob, again)
+
+	f, _, err := store.Open(blob.Key)
+	require.NoError(t, err)
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "large result", string(content))
+}
+
+func TestFileStore_OpenMissing(t *testing.T) {
+	store, err := NewFileStore(Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	_, _, err = store.Open("9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+	assert.ErrorIs(t, err, domain.ErrResultBlobMissing)
+
+	_, _, err = store.Open("../../etc/passwd")
+	assert.ErrorIs(t, err, domain.ErrResultBlobMissing)
+
+	// as long as a real key
+	_, _, err = store.Open(strings.Repeat("../", 18) + "etc/passwd")
+	assert.ErrorIs(t, err, domain.ErrResultBlobMissing)
+}
+
+func TestFileStore_WalkAndRemoveBlobs(t *testing.T) {
+	store, err := NewFileStore(Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	blob, err := store.Put([]byte("large result"))
+	require.NoError(t, err)
+
+	var keys []string
+	err = store.WalkBlobs(func(key string, size int64, modTime time.Time) error {
+		keys = append(keys, key)
+		assert.Equal(t, blob.Size, size)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{blob.Key}, keys)
+
+	// put after the cutoff, kept
+	removed, err := store.RemoveBlob(blob.Key, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	removed, err = store.RemoveBlob(blob.Key, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	_, _, err = store.Open(blob.Key)
+	assert.ErrorIs(t, err, domain.ErrResultBlobMissing)
+
+	removed, err = store.RemoveBlob(blob.Key, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, removed)
+}
+
+func TestFileStore_PutRefreshesModTime(t *testing.T) {
+	store, err := NewFileStore(Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	blob, err := store.Put([]byte("large result"))
+	require.NoError(t, err)
+	old := time.Now().Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(store.path(blob.Key), old, old))
+
+	// a blob that is put again is in use, the sweep must not take it
+	_, err = store.Put([]byte("large result"))
+	require.NoError(t, err)
+
+	removed, err := store.RemoveBlob(blob.Key, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.False(t, removed)
+}
@@ -0,0 +1,9 @@
+package tasklog
+
+import "time"
+
+type Config struct {
+	MaxLines     int           `env:"TASK_LOG_MAX_LINES" envDefault:"1000"`      // oldest lines of the task are dropped when it has more lines
+	MaxLineBytes int           `env:"TASK_LOG_MAX_LINE_BYTES" envDefault:"4096"` // longer messages are truncated
+	Retention    time.Duration `env:"TASK_LOG_RETENTION" envDefault:"24h"`       // logs of the task are dropped after this long without writes
+}
@@ -0,0 +1,147 @@
+package tasklog
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+type buffer struct {
+	lines     []domain.TaskLogLine
+	nextSeq   int64
+	lastWrite time.Time
+	changed   chan struct{} // closed and replaced on every append to wake up followers
+}
+
+// Store keeps the latest lines of every task in memory, each task has its own bounded buffer that drops oldest lines first
+type Store struct {
+	cfg     Config
+	buffers map[uuid.UUID]*buffer
+	mu      sync.RWMutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewStore(cfg Config) *Store {
+	return &Store{
+		cfg:     cfg,
+		buffers: make(map[uuid.UUID]*buffer),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start runs the janitor that drops logs of tasks that were not written for longer than retention
+func (s *Store) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.cfg.Retention / 10)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.purge(time.Now().Add(-s.cfg.Retention))
+			}
+		}
+	}()
+}
+
+func (s *Store) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+}
+
+// Append assigns sequence numbers to the lines and stores them, stored lines are returned
+func (s *Store) Append(taskID uuid.UUID, lines []domain.TaskLogLine) []domain.TaskLogLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buf, exists := s.buffers[taskID]
+	if !exists {
+		buf = &buffer{nextSeq: 1, changed: make(chan struct{})}
+		s.buffers[taskID] = buf
+	}
+
+	stored := make([]domain.TaskLogLine, 0, len(lines))
+	for _, line := range lines {
+		line.Seq = buf.nextSeq
+		line.TaskID = taskID
+		line.Message = truncate(line.Message, s.cfg.MaxLineBytes)
+		buf.nextSeq++
+		stored = append(stored, line)
+	}
+
+	buf.lines = append(buf.lines, stored...)
+	if overflow := len(buf.lines) - s.cfg.MaxLines; overflow > 0 {
+		// copy instead of reslicing so the dropped lines can be collected
+		buf.lines = append([]domain.TaskLogLine(nil), buf.lines[overflow:]...)
+	}
+	buf.lastWrite = time.Now()
+
+	close(buf.changed)
+	buf.changed = make(chan struct{})
+
+	return stored
+}
+
+// Read returns the stored lines of the task that match the cursor and a channel that is closed when new lines are appended
+func (s *Store) Read(taskID uuid.UUID, cursor domain.TaskLogCursor) ([]domain.TaskLogLine, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buf, exists := s.buffers[taskID]
+	if !exists {
+		// create empty buffer so followers can wait for the first lines
+		buf = &buffer{nextSeq: 1, changed: make(chan struct{}), lastWrite: time.Now()}
+		s.buffers[taskID] = buf
+	}
+
+	// lines are sorted by seq, so skip everything up to the cursor at once
+	start := sort.Search(len(buf.lines), func(i int) bool {
+		return buf.lines[i].Seq > cursor.SinceSeq
+	})
+
+	lines := make([]domain.TaskLogLine, 0, len(buf.lines)-start)
+	for _, line := range buf.lines[start:] {
+		if cursor.Matches(line) {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, buf.changed
+}
+
+// truncate cuts the message to max bytes without splitting a multibyte rune
+func truncate(message string, max int) string {
+	if len(message) <= max {
+		return message
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut]
+}
+
+func (s *Store) purge(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for taskID, buf := range s.buffers {
+		if buf.lastWrite.Before(before) {
+			delete(s.buffers, taskID)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package tasklog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_DropsOldestLines(t *testing.T) {
+	store := NewStore(Config{MaxLines: 3, MaxLineBytes: 16, Retention: time.Hour})
+	taskID := uuid.New()
+
+	for _, message := range []string{"1", "2", "3", "4", "5"} {
+		store.Append(taskID, []domain.TaskLogLine{{Message: message}})
+	}
+
+	lines, _ := store.Read(taskID, domain.TaskLogCursor{})
+	require.Len(t, lines, 3)
+	assert.Equal(t, int64(3), lines[0].Seq)
+	assert.Equal(t, "5", lines[2].Message)
+
+	lines, _ = store.Read(taskID, domain.TaskLogCursor{SinceSeq: 4})
+	require.Len(t, lines, 1)
+	assert.Equal(t, int64(5), lines[0].Seq)
+}
+
+func TestStore_TruncatesLongLines(t *testing.T) {
+	store := NewStore(Config{MaxLines: 3, MaxLineBytes: 5, Retention: time.Hour})
+
+	stored := store.Append(uuid.New(), []domain.TaskLogLine{{Message: "ab" + strings.Repeat("ж", 3)}})
+	// "ж" is 2 bytes, cutting at 5 bytes would split the second one
+	assert.Equal(t, "abж", stored[0].Message)
+}
+
+func TestStore_ReadWakesOnAppend(t *testing.T) {
+	store := NewStore(Config{MaxLines: 3, MaxLineBytes: 16, Retention: time.Hour})
+	taskID := uuid.New()
+
+	_, changed := store.Read(taskID, domain.TaskLogCursor{})
+	store.Append(taskID, []domain.TaskLogLine{{Message: "hello"}})
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("reader was not woken up by append")
+	}
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/pkg/logger/sl"
+	"github.com/google/uuid"
+)
+
+type DeliveryRecorder interface {
+	SaveDelivery(delivery domain.Delivery)
+}
+
+// CallbackDispatcher posts finished tasks to their callback urls, failed deliveries are retried with exponential backoff
+type CallbackDispatcher struct {
+	log      *slog.Logger
+	pool     *workerPool
+	sender   *sender
+	recorder DeliveryRecorder
+}
+
+func NewCallbackDispatcher(cfg Config, log *slog.Logger, recorder DeliveryRecorder) *CallbackDispatcher {
+	if cfg.Secret == "" {
+		log.Warn("WEBHOOK_SECRET is not set, webhook payloads will be sent unsigned")
+	}
+
+	return &CallbackDispatcher{
+		log:      log,
+		pool:     newWorkerPool(cfg, log),
+		sender:   newSender(cfg),
+		recorder: recorder,
+	}
+}
+
+func (d *CallbackDispatcher) Start() {
+	d.pool.start()
+}
+
+// Stop waits for in-flight deliveries to finish, pending retries are dropped
+func (d *CallbackDispatcher) Stop() {
+	d.pool.shutdown()
+}
+
+// HandleEvent schedules delivery of the finished task to its callback url, tasks without callback url are ignored
+func (d *CallbackDispatcher) HandleEvent(event domain.TaskEvent) {
+	task := event.Task
+	// finished tasks are still deleted and restored, only the finish itself is reported
+	if !event.Type.IsFinish() || task.CallbackURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(task)
+	if err != nil {
+		d.log.Error("Failed to marshal webhook payload", slog.String("task_id", task.ID.String()), sl.Err(err))
+		return
+	}
+
+	// the same event published again by the outbox relay gets the same delivery id
+	deliveryID := uuid.NewSHA1(task.ID, event.ID[:])
+	d.pool.enqueue(job{
+		name: "callback." + task.ID.String(),
+		deliver: func(attempt int) bool {
+			return d.deliver(task, deliveryID, payload, attempt)
+		},
+		attempt: 1,
+	})
+}
+
+func (d *CallbackDispatcher) deliver(task domain.Task, deliveryID uuid.UUID, payload []byte, attempt int) (retry bool) {
+	delivery := domain.Delivery{
+		ID:          uuid.New(),
+		DeliveryID:  deliveryID,
+		TaskID:      task.ID,
+		URL:         task.CallbackURL,
+		Attempt:     attempt,
+		AttemptedAt: time.Now(),
+	}
+
+	result := d.sender.send(task.CallbackURL, deliveryID, attempt, nil, payload)
+	delivery.StatusCode = result.statusCode
+	delivery.Error = result.err
+	delivery.Success = result.success
+	delivery.Duration = result.duration
+
+	d.recorder.SaveDelivery(delivery)
+	return !delivery.Success
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/pkg/logger/handlers/slogdiscard"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorderStub struct {
+	mu         sync.Mutex
+	deliveries []domain.Delivery
+}
+
+func (r *recorderStub) SaveDelivery(delivery domain.Delivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, delivery)
+}
+
+func (r *recorderStub) get() []domain.Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.Delivery(nil), r.deliveries...)
+}
+
+func testConfig() Config {
+	return Config{
+		Secret:         "secret",
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+		Timeout:        time.Second,
+		Workers:        1,
+		QueueSize:      10,
+	}
+}
+
+func TestDispatcher_RetriesUntilSuccess(t *testing.T) {
+	var calls atomic.Int32
+	var signatureValid atomic.Bool
+	var mu sync.Mutex
+	var deliveryIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signatureValid.Store(Verify([]byte("secret"), r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader), time.Minute))
+		mu.Lock()
+		deliveryIDs = append(deliveryIDs, r.Header.Get(DeliveryHeader))
+		mu.Unlock()
+
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	recorder := &recorderStub{}
+	d := NewCallbackDispatcher(testConfig(), slogdiscard.NewDiscardLogger(), recorder)
+	d.Start()
+	defer d.Stop()
+
+	task := domain.Task{ID: uuid.New(), Title: "Test Task", CallbackURL: server.URL, TaskState: domain.TaskState{Status: domain.StatusCompleted}}
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskCompleted, task))
+
+	require.Eventually(t, func() bool { return len(recorder.get()) == 3 }, time.Second, 5*time.Millisecond)
+
+	deliveries := recorder.get()
+	for i, delivery := range deliveries {
+		assert.Equal(t, task.ID, delivery.TaskID)
+		assert.Equal(t, i+1, delivery.Attempt)
+		assert.Equal(t, deliveries[0].DeliveryID, delivery.DeliveryID, "attempts share the delivery id")
+		assert.NotEqual(t, deliveries[0].DeliveryID, delivery.ID)
+	}
+	assert.False(t, deliveries[0].Success)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].StatusCode)
+	assert.True(t, deliveries[2].Success)
+	assert.True(t, signatureValid.Load())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{deliveries[0].DeliveryID.String(), deliveries[0].DeliveryID.String(), deliveries[0].DeliveryID.String()}, deliveryIDs)
+}
+
+func TestDispatcher_RepublishedEventKeepsDeliveryID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	recorder := &recorderStub{}
+	d := NewCallbackDispatcher(testConfig(), slogdiscard.NewDiscardLogger(), recorder)
+	d.Start()
+	defer d.Stop()
+
+	// the outbox relay publishes the stored event again
+	task := domain.Task{ID: uuid.New(), CallbackURL: server.URL, TaskState: domain.TaskState{Status: domain.StatusCompleted}}
+	event := domain.NewTaskEvent(domain.EventTaskCompleted, task)
+	d.HandleEvent(event)
+	d.HandleEvent(event)
+
+	require.Eventually(t, func() bool { return len(recorder.get()) == 2 }, time.Second, 5*time.Millisecond)
+	deliveries := recorder.get()
+	assert.Equal(t, deliveries[0].DeliveryID, deliveries[1].DeliveryID)
+
+	// another finish of the task is another delivery
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskFailed, task))
+	require.Eventually(t, func() bool { return len(recorder.get()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.NotEqual(t, deliveries[0].DeliveryID, recorder.get()[2].DeliveryID)
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"id":1}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	assert.True(t, Verify(secret, now, body, Sign(secret, now, body), time.Minute))
+	assert.False(t, Verify(secret, now, []byte(`{"id":2}`), Sign(secret, now, body), time.Minute), "body is signed")
+	assert.False(t, Verify(secret, old, body, Sign(secret, now, body), time.Hour), "timestamp is signed")
+	assert.False(t, Verify(secret, old, body, Sign(secret, old, body), time.Minute), "old requests are rejected")
+	assert.False(t, Verify(secret, "soon", body, Sign(secret, "soon", body), time.Minute))
+}
+
+func TestDispatcher_StopsAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	recorder := &recorderStub{}
+	d := NewCallbackDispatcher(testConfig(), slogdiscard.NewDiscardLogger(), recorder)
+	d.Start()
+	defer d.Stop()
+
+	task := domain.Task{ID: uuid.New(), CallbackURL: server.URL, TaskState: domain.TaskState{Status: domain.StatusFailed}}
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskFailed, task))
+
+	require.Eventually(t, func() bool { return len(recorder.get()) == 3 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, recorder.get(), 3)
+}
+
+func TestDispatcher_IgnoresUnfinishedTasksAndTasksWithoutCallback(t *testing.T) {
+	recorder := &recorderStub{}
+	d := NewCallbackDispatcher(testConfig(), slogdiscard.NewDiscardLogger(), recorder)
+
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskCompleted, domain.Task{ID: uuid.New(), TaskState: domain.TaskState{Status: domain.StatusCompleted}}))
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskStarted, domain.Task{ID: uuid.New(), CallbackURL: "https://example.com", TaskState: domain.TaskState{Status: domain.StatusInProgress}}))
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskDeleted, domain.Task{ID: uuid.New(), CallbackURL: "https://example.com", TaskState: domain.TaskState{Status: domain.StatusCompleted}}))
+
+	assert.Len(t, d.pool.jobs, 0)
+}
+
+func TestBackoff(t *testing.T) {
+	d := &workerPool{cfg: Config{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 2*time.Second, d.backoff(2))
+	assert.Equal(t, 4*time.Second, d.backoff(3))
+	assert.Equal(t, 5*time.Second, d.backoff(4))
+}
@@ -0,0 +1,14 @@
+package webhook
+
+import "time"
+
+type Config struct {
+	Secret               string        `env:"WEBHOOK_SECRET"`
+	MaxAttempts          int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"5"`
+	InitialBackoff       time.Duration `env:"WEBHOOK_INITIAL_BACKOFF" envDefault:"1s"`
+	MaxBackoff           time.Duration `env:"WEBHOOK_MAX_BACKOFF" envDefault:"1m"`
+	Timeout              time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"5s"`
+	Workers              int           `env:"WEBHOOK_WORKERS" envDefault:"4"`
+	QueueSize            int           `env:"WEBHOOK_QUEUE_SIZE" envDefault:"1000"`
+	DisableAfterFailures int           `env:"WEBHOOK_DISABLE_AFTER_FAILURES" envDefault:"20"` // 0 never disables subscriptions
+}
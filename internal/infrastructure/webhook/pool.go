@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// deliveryFunc makes a single delivery attempt and reports whether it has to be retried
+type deliveryFunc func(attempt int) (retry bool)
+
+type job struct {
+	name    string
+	deliver deliveryFunc
+	attempt int
+}
+
+// workerPool runs deliveries on a fixed number of workers, retries are scheduled with exponential backoff
+type workerPool struct {
+	cfg Config
+	log *slog.Logger
+
+	jobs     chan job
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newWorkerPool(cfg Config, log *slog.Logger) *workerPool {
+	return &workerPool{
+		cfg:  cfg,
+		log:  log,
+		jobs: make(chan job, cfg.QueueSize),
+		stop: make(chan struct{}),
+	}
+}
+
+func (p *workerPool) start() {
+	for i := 0; i < p.cfg.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+}
+
+// shutdown waits for in-flight deliveries to finish, pending retries are dropped
+func (p *workerPool) shutdown() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+}
+
+func (p *workerPool) enqueue(j job) {
+	select {
+	case <-p.stop:
+		p.log.Warn("Dispatcher is stopped, webhook delivery dropped", slog.String("job", j.name), slog.Int("attempt", j.attempt))
+	case p.jobs <- j:
+	default:
+		p.log.Error("Webhook queue is full, delivery dropped", slog.String("job", j.name), slog.Int("attempt", j.attempt))
+	}
+}
+
+func (p *workerPool) worker() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case j := <-p.jobs:
+			p.process(j)
+		}
+	}
+}
+
+func (p *workerPool) process(j job) {
+	if !j.deliver(j.attempt) {
+		return
+	}
+
+	log := p.log.With(slog.String("job", j.name), slog.Int("attempt", j.attempt))
+	if j.attempt >= p.cfg.MaxAttempts {
+		log.Error("Webhook delivery failed, no attempts left")
+		return
+	}
+
+	backoff := p.backoff(j.attempt)
+	log.Warn("Webhook delivery failed, retrying", slog.Duration("backoff", backoff))
+
+	j.attempt++
+	time.AfterFunc(backoff, func() {
+		p.enqueue(j)
+	})
+}
+
+// backoff doubles the initial backoff for every failed attempt and caps it with max backoff
+func (p *workerPool) backoff(attempt int) time.Duration {
+	backoff := p.cfg.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if backoff >= p.cfg.MaxBackoff {
+			return p.cfg.MaxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type sendResult struct {
+	statusCode int
+	err        string
+	success    bool
+	duration   time.Duration
+}
+
+// sender posts signed payloads, any non 2xx response is treated as failure
+type sender struct {
+	client *http.Client
+	secret []byte
+}
+
+func newSender(cfg Config) *sender {
+	return &sender{
+		client: &http.Client{Timeout: cfg.Timeout},
+		secret: []byte(cfg.Secret),
+	}
+}
+
+func (s *sender) send(url string, deliveryID uuid.UUID, attempt int, headers map[string]string, payload []byte) (result sendResult) {
+	start := time.Now()
+	defer func() {
+		result.duration = time.Since(start)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		result.err = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, deliveryID.String())
+	req.Header.Set(AttemptHeader, strconv.Itoa(attempt))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if len(s.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(s.secret, timestamp, payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		result.err = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.statusCode = resp.StatusCode
+	result.success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !result.success {
+		result.err = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+	}
+	return result
+}
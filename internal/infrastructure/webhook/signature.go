@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	AttemptHeader   = "X-Webhook-Attempt"
+	EventHeader     = "X-Webhook-Event"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the value of SignatureHeader for the body sent at timestamp (TimestampHeader, unix seconds):
+// "sha256=" followed by hex encoded HMAC-SHA256 of the timestamp, a dot and the body
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid SignatureHeader value for the body and the timestamp is no further than
+// tolerance from now, receivers can use it to check payloads and reject replayed requests
+func Verify(secret []byte, timestamp string, body []byte, signature string, tolerance time.Duration) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/pkg/logger/sl"
+	"github.com/google/uuid"
+)
+
+type SubscriptionStore interface {
+	ListSubscriptions() []domain.WebhookSubscription
+	GetSubscriptionByID(id uuid.UUID) (domain.WebhookSubscription, error)
+	UpdateSubscription(id uuid.UUID, update func(sub *domain.WebhookSubscription) error) (domain.WebhookSubscription, error)
+}
+
+type WebhookDeliveryRecorder interface {
+	SaveWebhookDelivery(delivery domain.WebhookDelivery)
+}
+
+// SubscriptionDispatcher delivers task lifecycle events to every active subscription that matches them,
+// subscriptions that fail too many deliveries in a row are disabled
+type SubscriptionDispatcher struct {
+	cfg      Config
+	log      *slog.Logger
+	pool     *workerPool
+	sender   *sender
+	store    SubscriptionStore
+	recorder WebhookDeliveryRecorder
+}
+
+func NewSubscriptionDispatcher(cfg Config, log *slog.Logger, store SubscriptionStore, recorder WebhookDeliveryRecorder) *SubscriptionDispatcher {
+	return &SubscriptionDispatcher{
+		cfg:      cfg,
+		log:      log,
+		pool:     newWorkerPool(cfg, log),
+		sender:   newSender(cfg),
+		store:    store,
+		recorder: recorder,
+	}
+}
+
+func (d *SubscriptionDispatcher) Start() {
+	d.pool.start()
+}
+
+// Stop waits for in-flight deliveries to finish, pending retries are dropped
+func (d *SubscriptionDispatcher) Stop() {
+	d.pool.shutdown()
+}
+
+func (d *SubscriptionDispatcher) HandleEvent(event domain.TaskEvent) {
+	for _, sub := range d.store.ListSubscriptions() {
+		if sub.Active && sub.Matches(event) {
+			d.enqueue(sub.ID, event, nil)
+		}
+	}
+}
+
+// Replay sends the event of the delivery to the subscription again as a new delivery with the same delivery id
+func (d *SubscriptionDispatcher) Replay(delivery domain.WebhookDelivery) {
+	d.enqueue(delivery.SubscriptionID, delivery.Event, &delivery.ID)
+}
+
+func (d *SubscriptionDispatcher) enqueue(subID uuid.UUID, event domain.TaskEvent, replayOf *uuid.UUID) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		d.log.Error("Failed to marshal webhook payload", slog.String("event_id", event.ID.String()), sl.Err(err))
+		return
+	}
+
+	// the same event published again by the outbox relay or replayed gets the same delivery id
+	deliveryID := uuid.NewSHA1(subID, event.ID[:])
+	d.pool.enqueue(job{
+		name: "subscription." + subID.String() + "." + event.ID.String(),
+		deliver: func(attempt int) bool {
+			return d.deliver(subID, event, deliveryID, payload, attempt, replayOf)
+		},
+		attempt: 1,
+	})
+}
+
+func (d *SubscriptionDispatcher) deliver(subID uuid.UUID, event domain.TaskEvent, deliveryID uuid.UUID, payload []byte, attempt int, replayOf *uuid.UUID) (retry bool) {
+	// subscription could be changed, disabled or deleted while the delivery was waiting in the queue
+	sub, err := d.store.GetSubscriptionByID(subID)
+	if err != nil || !sub.Active {
+		return false
+	}
+
+	delivery := domain.WebhookDelivery{
+		ID:             uuid.New(),
+		DeliveryID:     deliveryID,
+		SubscriptionID: sub.ID,
+		Event:          event,
+		URL:            sub.URL,
+		Attempt:        attempt,
+		ReplayOf:       replayOf,
+		AttemptedAt:    time.Now(),
+	}
+
+	result := d.sender.send(sub.URL, deliveryID, attempt, map[string]string{EventHeader: string(event.Type)}, payload)
+	delivery.StatusCode = result.statusCode
+	delivery.Error = result.err
+	delivery.Success = result.success
+	delivery.Duration = result.duration
+
+	d.recorder.SaveWebhookDelivery(delivery)
+
+	sub, err = d.store.UpdateSubscription(sub.ID, func(sub *domain.WebhookSubscription) error {
+		if delivery.Success {
+			sub.ConsecutiveFailures = 0
+			return nil
+		}
+		sub.ConsecutiveFailures++
+		if d.cfg.DisableAfterFailures > 0 && sub.ConsecutiveFailures >= d.cfg.DisableAfterFailures {
+			sub.Active = false
+			sub.DisabledReason = fmt.Sprintf("disabled after %d consecutive failed deliveries, last error: %s", sub.ConsecutiveFailures, delivery.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		d.log.Error("Failed to update webhook subscription", slog.String("subscription_id", subID.String()), sl.Err(err))
+		return false
+	}
+	if !sub.Active {
+		d.log.Warn("Webhook subscription is disabled", slog.String("subscription_id", sub.ID.String()), slog.String("reason", sub.DisabledReason))
+	}
+
+	return !delivery.Success && sub.Active
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/Util787/task-manager/internal/infrastructure/repo/inmemory"
+	"github.com/Util787/task-manager/pkg/logger/handlers/slogdiscard"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionDispatcher_DeliversMatchingEvents(t *testing.T) {
+	var events atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events.Store(r.Header.Get(EventHeader))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	subRepo := inmemory.NewSubscriptionRepository()
+	deliveryRepo := inmemory.NewWebhookDeliveryRepository(inmemory.DeliveryLogConfig{MaxAttempts: 100, Retention: time.Hour})
+	subID := subRepo.CreateSubscription(&domain.WebhookSubscription{
+		URL:    server.URL,
+		Events: []domain.TaskEventType{domain.EventTaskCompleted},
+		Labels: []string{"billing"},
+		Active: true,
+	})
+
+	d := NewSubscriptionDispatcher(testConfig(), slogdiscard.NewDiscardLogger(), subRepo, deliveryRepo)
+	d.Start()
+	defer d.Stop()
+
+	task := domain.Task{ID: uuid.New(), Labels: []string{"nightly", "billing"}}
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskStarted, task))
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskCompleted, domain.Task{ID: uuid.New(), Labels: []string{"nightly"}}))
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskCompleted, task))
+
+	require.Eventually(t, func() bool { return len(deliveryRepo.GetWebhookDeliveriesBySubscriptionID(subID)) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	deliveries := deliveryRepo.GetWebhookDeliveriesBySubscriptionID(subID)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, task.ID, deliveries[0].Event.Task.ID)
+	assert.True(t, deliveries[0].Success)
+	assert.Equal(t, string(domain.EventTaskCompleted), events.Load())
+}
+
+func TestSubscriptionDispatcher_DisablesFailingSubscription(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	subRepo := inmemory.NewSubscriptionRepository()
+	deliveryRepo := inmemory.NewWebhookDeliveryRepository(inmemory.DeliveryLogConfig{MaxAttempts: 100, Retention: time.Hour})
+	subID := subRepo.CreateSubscription(&domain.WebhookSubscription{URL: server.URL, Active: true})
+
+	cfg := testConfig()
+	cfg.MaxAttempts = 10
+	cfg.DisableAfterFailures = 2
+	d := NewSubscriptionDispatcher(cfg, slogdiscard.NewDiscardLogger(), subRepo, deliveryRepo)
+	d.Start()
+	defer d.Stop()
+
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskFailed, domain.Task{ID: uuid.New()}))
+
+	require.Eventually(t, func() bool {
+		sub, _ := subRepo.GetSubscriptionByID(subID)
+		return !sub.Active
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	sub, err := subRepo.GetSubscriptionByID(subID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sub.ConsecutiveFailures)
+	assert.Contains(t, sub.DisabledReason, "2 consecutive failed deliveries")
+	assert.Len(t, deliveryRepo.GetWebhookDeliveriesBySubscriptionID(subID), 2)
+}
+
+func TestSubscriptionDispatcher_ReplayKeepsDeliveryID(t *testing.T) {
+	var deliveryIDs []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		deliveryIDs = append(deliveryIDs, r.Header.Get(DeliveryHeader))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	subRepo := inmemory.NewSubscriptionRepository()
+	deliveryRepo := inmemory.NewWebhookDeliveryRepository(inmemory.DeliveryLogConfig{MaxAttempts: 100, Retention: time.Hour})
+	subID := subRepo.CreateSubscription(&domain.WebhookSubscription{URL: server.URL, Active: true})
+
+	d := NewSubscriptionDispatcher(testConfig(), slogdiscard.NewDiscardLogger(), subRepo, deliveryRepo)
+	d.Start()
+	defer d.Stop()
+
+	d.HandleEvent(domain.NewTaskEvent(domain.EventTaskCompleted, domain.Task{ID: uuid.New()}))
+	require.Eventually(t, func() bool { return len(deliveryRepo.GetWebhookDeliveriesBySubscriptionID(subID)) == 1 }, time.Second, 5*time.Millisecond)
+
+	original := deliveryRepo.GetWebhookDeliveriesBySubscriptionID(subID)[0]
+	d.Replay(original)
+	require.Eventually(t, func() bool { return len(deliveryRepo.GetWebhookDeliveriesBySubscriptionID(subID)) == 2 }, time.Second, 5*time.Millisecond)
+
+	replayed := deliveryRepo.GetWebhookDeliveriesBySubscriptionID(subID)[1]
+	assert.NotEqual(t, original.ID, replayed.ID)
+	assert.Equal(t, original.DeliveryID, replayed.DeliveryID)
+	assert.Equal(t, &original.ID, replayed.ReplayOf)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{original.DeliveryID.String(), original.DeliveryID.String()}, deliveryIDs)
+}
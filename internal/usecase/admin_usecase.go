@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxImportLineBytes bounds a single imported task, results above the inline limit are exported as references and stay small
+	maxImportLineBytes = 16 << 20
+	// maxImportErrors is how many rejected lines the import report lists, all of them are counted
+	maxImportErrors = 100
+	// resultSweepGrace keeps recently put blobs, a finish stores its blob before the task that references it
+	resultSweepGrace = time.Hour
+)
+
+type AdminUsecase struct {
+	taskRepo    TaskRepository
+	backuper    StorageBackuper
+	taskCache   TaskCache
+	resultStore ResultBlobStore
+}
+
+// StorageBackuper writes a consistent copy of the task storage while it keeps serving requests
+type StorageBackuper interface {
+	Backup(ctx context.Context, w io.Writer) (int64, error)
+}
+
+// TaskCache is the cache in front of the task storage
+type TaskCache interface {
+	Stats() domain.CacheStats
+}
+
+// ResultBlobStore lists and removes the blobs of the result store
+type ResultBlobStore interface {
+	WalkBlobs(fn func(key string, size int64, modTime time.Time) error) error
+	// RemoveBlob removes the blob only if it was last put before the time, so a blob put again during a sweep is kept
+	RemoveBlob(key string, before time.Time) (bool, error)
+}
+
+// NewAdminUsecase creates the usecase, backuper is nil when the storage can't be backed up online and taskCache is nil when tasks aren't cached
+func NewAdminUsecase(taskRepo TaskRepository, backuper StorageBackuper, taskCache TaskCache, resultStore ResultBlobStore) *AdminUsecase {
+	return &AdminUsecase{taskRepo: taskRepo, backuper: backuper, taskCache: taskCache, resultStore: resultStore}
+}
+
+func (a *AdminUsecase) GetCacheStats() (domain.CacheStats, error) {
+	const op = "AdminUsecase.GetCacheStats"
+
+	if a.taskCache == nil {
+		return domain.CacheStats{}, fmt.Errorf("%s: %w", op, domain.ErrCacheDisabled)
+	}
+	return a.taskCache.Stats(), nil
+}
+
+// SweepResultBlobs removes the blobs of the result store that no stored task references, tasks in the trash included.
+// Blobs are left behind when the task update fails after a finish stored the blob, and by retries and purges. They can't be
+// removed right away since the store is content-addressed and other tasks may share the blob. Blobs put within
+// resultSweepGrace are kept, their tasks may not be stored yet
+func (a *AdminUsecase) SweepResultBlobs(ctx context.Context) (domain.ResultSweep, error) {
+	const op = "AdminUsecase.SweepResultBlobs"
+
+	// taken before the walk, a blob put again after it is kept whatever the walk saw
+	before := time.Now().Add(-resultSweepGrace)
+
+	referenced := make(map[string]struct{})
+	err := a.taskRepo.WalkTasks(ctx, func(task domain.Task) error {
+		if task.ResultBlob != nil {
+			referenced[task.ResultBlob.Key] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.ResultSweep{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var sweep domain.ResultSweep
+	err = a.resultStore.WalkBlobs(func(key string, size int64, modTime time.Time) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sweep.Blobs++
+		if _, ok := referenced[key]; ok || !modTime.Before(before) {
+			return nil
+		}
+		removed, err := a.resultStore.RemoveBlob(key, before)
+		if err != nil {
+			return err
+		}
+		if removed {
+			sweep.Removed++
+			sweep.RemovedBytes += size
+		}
+		return nil
+	})
+	if err != nil {
+		return sweep, fmt.Errorf("%s: %w", op, err)
+	}
+	return sweep, nil
+}
+
+func (a *AdminUsecase) BackupStorage(ctx context.Context, w io.Writer) (int64, error) {
+	const op = "AdminUsecase.BackupStorage"
+
+	if a.backuper == nil {
+		return 0, fmt.Errorf("%s: %w", op, domain.ErrBackupUnsupported)
+	}
+
+	written, err := a.backuper.Backup(ctx, w)
+	if err != nil {
+		return written, fmt.Errorf("%s: %w", op, err)
+	}
+	return written, nil
+}
+
+// ExportTasks passes every stored task that matches the filter to emit ordered by creation time, tasks in the trash included.
+// Export stops at the first error of emit
+func (a *AdminUsecase) ExportTasks(ctx context.Context, filter domain.TaskExportFilter, emit func(task domain.Task) error) error {
+	const op = "AdminUsecase.ExportTasks"
+
+	err := a.taskRepo.WalkTasks(ctx, func(task domain.Task) error {
+		if !filter.Matches(task) {
+			return nil
+		}
+		return emit(task)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ImportTasks stores tasks read from r, one JSON task per line as ExportTasks emits them. Tasks keep their ids, versions and timestamps,
+// invalid lines are rejected and the import goes on. mode decides what happens to tasks whose id is already stored,
+// with domain.ImportFail the import stops at the first of them with domain.ErrTaskExists. Imported tasks publish no events
+func (a *AdminUsecase) ImportTasks(ctx context.Context, r io.Reader, mode domain.ImportMode) (domain.ImportReport, error) {
+	const op = "AdminUsecase.ImportTasks"
+
+	report := domain.ImportReport{Errors: []domain.ImportError{}}
+	if !mode.IsValid() {
+		return report, fmt.Errorf("%s: %w, must be one of %v", op, domain.ErrInvalidImportMode, domain.ImportModes)
+	}
+
+	reject := func(line int, id uuid.UUID, reason string) {
+		report.Rejected++
+		if len(report.Errors) < maxImportErrors {
+			importErr := domain.ImportError{Line: line, Reason: reason}
+			if id != uuid.Nil {
+				importErr.ID = id.String()
+			}
+			report.Errors = append(report.Errors, importErr)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		task, err := parseImportedTask(raw)
+		if err != nil {
+			reject(line, task.ID, err.Error())
+			continue
+		}
+
+		err = a.taskRepo.ImportTask(ctx, task, mode == domain.ImportOverwrite)
+		if errors.Is(err, domain.ErrTaskExists) {
+			reject(line, task.ID, domain.ErrTaskExists.Error())
+			if mode == domain.ImportFail {
+				return report, fmt.Errorf("%s: line %d: %w", op, line, err)
+			}
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("%s: line %d: %w", op, line, err)
+		}
+		report.Accepted++
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("%w, maximum %d bytes", domain.ErrImportLineTooLong, maxImportLineBytes)
+		}
+		return report, fmt.Errorf("%s: line %d: %w", op, line+1, err)
+	}
+	return report, nil
+}
+
+// parseImportedTask decodes and checks a line of the import, the id is returned with the error whenever it could be read
+func parseImportedTask(raw []byte) (domain.Task, error) {
+	var task domain.Task
+	if err := json.Unmarshal(raw, &task); err != nil {
+		return domain.Task{ID: task.ID}, fmt.Errorf("invalid task: %w", err)
+	}
+	if task.ID == uuid.Nil {
+		return task, errors.New("id is missing")
+	}
+	if err := validateTask(&task); err != nil {
+		return task, err
+	}
+	if !task.TaskState.Status.IsValid() {
+		return task, fmt.Errorf("%w: %q", domain.ErrInvalidStatus, task.TaskState.Status)
+	}
+	if task.TaskState.Progress < 0 || task.TaskState.Progress > 100 {
+		return task, fmt.Errorf("%w, must be from 0 to 100", domain.ErrInvalidProgress)
+	}
+	if task.Version < 0 || task.Attempt < 0 {
+		return task, errors.New("version and attempt must not be negative")
+	}
+	if task.ResultBlob != nil && !task.ResultBlob.ValidKey() {
+		return task, fmt.Errorf("%w, must be a hex encoded sha256", domain.ErrInvalidResultBlob)
+	}
+
+	// tasks written by hand or exported before these fields existed
+	if task.Version == 0 {
+		task.Version = 1
+	}
+	if task.Attempt == 0 {
+		task.Attempt = 1
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	if task.UpdatedAt.IsZero() {
+		task.UpdatedAt = task.CreatedAt
+	}
+	return task, nil
+}
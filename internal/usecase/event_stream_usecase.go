@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Util787/task-manager/internal/domain"
+)
+
+type EventStreamUsecase struct {
+	streamer EventStreamer
+}
+
+// EventStreamer fans lifecycle events of all tasks out to consumers with bounded queues
+type EventStreamer interface {
+	StreamEvents(ctx context.Context, filters <-chan domain.TaskEventFilter, emit func(event domain.TaskEvent, dropped int) error) error
+}
+
+func NewEventStreamUsecase(streamer EventStreamer) *EventStreamUsecase {
+	return &EventStreamUsecase{streamer: streamer}
+}
+
+// StreamEvents passes events of all tasks that match the latest filter to emit, dropped is the number of events
+// that were skipped right before the event because the consumer was too slow
+func (e *EventStreamUsecase) StreamEvents(ctx context.Context, filters <-chan domain.TaskEventFilter, emit func(event domain.TaskEvent, dropped int) error) error {
+	const op = "EventStreamUsecase.StreamEvents"
+
+	if err := e.streamer.StreamEvents(ctx, filters, emit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Util787/task-manager/pkg/logger/sl"
+	"github.com/google/uuid"
+)
+
+// outboxBatch is how many outbox events a relay run reads at once
+const outboxBatch = 100
+
+// OutboxRelay publishes events left in the outbox, those whose change was stored but whose publish
+// was not marked delivered, for example because the process stopped in between. Events are published at least once
+type OutboxRelay struct {
+	log       *slog.Logger
+	taskRepo  TaskRepository
+	publisher EventPublisher
+	interval  time.Duration
+	delay     time.Duration // events younger than this are left to the usecase that stored them
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewOutboxRelay(log *slog.Logger, taskRepo TaskRepository, publisher EventPublisher, interval, delay time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		log:       log,
+		taskRepo:  taskRepo,
+		publisher: publisher,
+		interval:  interval,
+		delay:     delay,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start relays events left by a previous run right away and then every interval
+func (r *OutboxRelay) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		r.relay(r.delay)
+		for {
+			select {
+			case <-r.stop:
+				// nothing is left to publish the latest events once the writers stopped, so they don't wait for the delay
+				r.relay(0)
+				return
+			case <-ticker.C:
+				r.relay(r.delay)
+			}
+		}
+	}()
+}
+
+// Stop relays every event left in the outbox, the writers of the outbox must be stopped before
+func (r *OutboxRelay) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	r.wg.Wait()
+}
+
+// relay publishes outbox events older than delay
+func (r *OutboxRelay) relay(delay time.Duration) {
+	ctx := context.Background()
+	var relayed int
+	for {
+		events, err := r.taskRepo.ListPendingEvents(ctx, outboxBatch)
+		if err != nil {
+			r.log.Error("failed to list outbox events", sl.Err(err))
+			break
+		}
+
+		deadline := time.Now().Add(-delay)
+		ids := make([]uuid.UUID, 0, len(events))
+		for _, event := range events {
+			// events are in the order they were stored, the rest are younger still
+			if event.OccurredAt.After(deadline) {
+				break
+			}
+			r.publisher.Publish(event)
+			ids = append(ids, event.ID)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := r.taskRepo.MarkEventsDelivered(ctx, ids); err != nil {
+			r.log.Error("failed to mark outbox events delivered", slog.Int("events", len(ids)), sl.Err(err))
+			break
+		}
+		relayed += len(ids)
+		if len(ids) < outboxBatch {
+			break
+		}
+	}
+	if relayed > 0 {
+		r.log.Info("outbox events relayed", slog.Int("events", relayed))
+	}
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+type TaskHistoryUsecase struct {
+	taskRepo TaskRepository
+}
+
+func NewTaskHistoryUsecase(taskRepo TaskRepository) *TaskHistoryUsecase {
+	return &TaskHistoryUsecase{taskRepo: taskRepo}
+}
+
+// GetTaskHistory returns the events of the task oldest first, the history of a task in the trash is still shown
+func (h *TaskHistoryUsecase) GetTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error) {
+	const op = "TaskHistoryUsecase.GetTaskHistory"
+
+	// check that task exists so unknown ids are reported as not found instead of an empty history
+	if _, err := h.taskRepo.GetTaskByID(ctx, id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	events, err := h.taskRepo.ReadTaskHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// CheckTaskHistory rebuilds every task with a history from its events and compares it with the stored task,
+// it returns how many tasks were checked and the ones that differ. Imported tasks have no history and are not checked.
+// Tasks changed while the check runs may be reported as well
+func (h *TaskHistoryUsecase) CheckTaskHistory(ctx context.Context) (int, []domain.TaskHistoryMismatch, error) {
+	const op = "TaskHistoryUsecase.CheckTaskHistory"
+
+	// ids are collected first, so the walk doesn't wait for the history reads
+	var ids []uuid.UUID
+	err := h.taskRepo.WalkTasks(ctx, func(task domain.Task) error {
+		ids = append(ids, task.ID)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	checked := 0
+	var mismatches []domain.TaskHistoryMismatch
+	for _, id := range ids {
+		events, err := h.taskRepo.ReadTaskHistory(ctx, id)
+		if err != nil {
+			return checked, mismatches, fmt.Errorf("%s: %w", op, err)
+		}
+		if len(events) == 0 {
+			continue
+		}
+		// the task is read after its history, a change stored in between shows up as a mismatch
+		task, err := h.taskRepo.GetTaskByID(ctx, id)
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			// purged while the check runs
+			continue
+		}
+		if err != nil {
+			return checked, mismatches, fmt.Errorf("%s: %w", op, err)
+		}
+		checked++
+
+		state, err := domain.ReplayTaskHistory(events)
+		if err != nil {
+			mismatches = append(mismatches, domain.TaskHistoryMismatch{TaskID: id, Reason: err.Error()})
+			continue
+		}
+		if reason := state.Mismatch(task); reason != "" {
+			mismatches = append(mismatches, domain.TaskHistoryMismatch{TaskID: id, Reason: reason})
+		}
+	}
+	return checked, mismatches, nil
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+// followPollInterval is how often a follower checks whether the task is finished while no lines are written
+const followPollInterval = time.Second
+
+type TaskLogUsecase struct {
+	taskRepo TaskRepository
+	logStore TaskLogStore
+}
+
+type TaskLogStore interface {
+	Append(taskID uuid.UUID, lines []domain.TaskLogLine) []domain.TaskLogLine
+	Read(taskID uuid.UUID, cursor domain.TaskLogCursor) ([]domain.TaskLogLine, <-chan struct{})
+}
+
+func NewTaskLogUsecase(taskRepo TaskRepository, logStore TaskLogStore) *TaskLogUsecase {
+	return &TaskLogUsecase{taskRepo: taskRepo, logStore: logStore}
+}
+
+// AppendTaskLogs stores lines written by the executor, lines are tagged with the current attempt of the task if attempt is 0
+func (l *TaskLogUsecase) AppendTaskLogs(ctx context.Context, id uuid.UUID, attempt int, lines []domain.TaskLogLine) ([]domain.TaskLogLine, error) {
+	const op = "TaskLogUsecase.AppendTaskLogs"
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, domain.ErrLogLinesEmpty)
+	}
+
+	task, err := getTask(ctx, l.taskRepo, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if attempt == 0 {
+		attempt = task.Attempt
+	}
+	if attempt < 0 || attempt > task.Attempt {
+		return nil, fmt.Errorf("%s: %w, task is on attempt %d", op, domain.ErrInvalidAttempt, task.Attempt)
+	}
+
+	now := time.Now()
+	for i := range lines {
+		lines[i].Attempt = attempt
+		if lines[i].Time.IsZero() {
+			lines[i].Time = now
+		}
+	}
+
+	return l.logStore.Append(id, lines), nil
+}
+
+func (l *TaskLogUsecase) GetTaskLogs(ctx context.Context, id uuid.UUID, cursor domain.TaskLogCursor) ([]domain.TaskLogLine, error) {
+	const op = "TaskLogUsecase.GetTaskLogs"
+
+	if _, err := getTask(ctx, l.taskRepo, id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lines, _ := l.logStore.Read(id, cursor)
+	return lines, nil
+}
+
+// FollowTaskLogs passes stored and newly written lines to emit until the task is finished and all its lines are emitted,
+// ctx cancellation or emit error stops following
+func (l *TaskLogUsecase) FollowTaskLogs(ctx context.Context, id uuid.UUID, cursor domain.TaskLogCursor, emit func(lines []domain.TaskLogLine) error) error {
+	const op = "TaskLogUsecase.FollowTaskLogs"
+
+	ticker := time.NewTicker(followPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// state is read before lines, so lines written right before the task was finished are not lost
+		task, err := getTask(ctx, l.taskRepo, id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		lines, changed := l.logStore.Read(id, cursor)
+		if len(lines) > 0 {
+			if err := emit(lines); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			cursor.SinceSeq = lines[len(lines)-1].Seq
+		}
+
+		if task.TaskState.Status.IsTerminal() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", op, ctx.Err())
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
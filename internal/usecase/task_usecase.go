@@ -1,7 +1,13 @@
 package usecase
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"time"
 	"unicode/utf8"
 
@@ -10,24 +16,80 @@ import (
 )
 
 type TaskUsecase struct {
-	taskRepo TaskRepository
+	taskRepo          TaskRepository
+	deliveryRepo      DeliveryRepository
+	resultStore       ResultStore
+	resultInlineLimit int
+	resultValidator   ResultValidator
+	publisher         EventPublisher
+	subscriber        EventSubscriber
 }
 
+// TaskRepository stores tasks, tasks in the trash are stored and read like any other task, reads of the usecases hide them.
+// WalkTasks visits every stored task ordered by creation time, ImportTask stores a task as it is, id and version included.
+// Events passed to CreateTask, UpdateTask and DeleteTask are stored in the outbox by the same operation as the change and only if it
+// is stored, create and update set their task to the stored one. ListPendingEvents returns outbox events in the order they were stored
+// until MarkEventsDelivered removes them. The same events are appended to the history of their task, ReadTaskHistory returns it
+// oldest first and it is empty for unknown tasks, DeleteTask drops it. ListTasks returns up to query.Limit tasks the query lists in its order,
+// tasks in the trash are skipped
 type TaskRepository interface {
-	CreateTask(task *domain.Task) uuid.UUID
-	GetTaskStateByID(id uuid.UUID) (domain.TaskState, time.Time, error)
-	GetTaskResultByID(id uuid.UUID) (string, error)
-	DeleteTask(id uuid.UUID) error
+	CreateTask(ctx context.Context, task *domain.Task, events ...domain.TaskEvent) (uuid.UUID, error)
+	GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error)
+	UpdateTask(ctx context.Context, id uuid.UUID, update func(task *domain.Task) error, events ...domain.TaskEvent) (domain.Task, error)
+	DeleteTask(ctx context.Context, id uuid.UUID, version int64, events ...domain.TaskEvent) error
+	ListDeletedTasks(ctx context.Context) ([]domain.Task, error)
+	ListTasks(ctx context.Context, query domain.TaskListQuery) ([]domain.Task, error)
+	WalkTasks(ctx context.Context, fn func(task domain.Task) error) error
+	ImportTask(ctx context.Context, task domain.Task, overwrite bool) error
+	ListPendingEvents(ctx context.Context, limit int) ([]domain.TaskEvent, error)
+	MarkEventsDelivered(ctx context.Context, ids []uuid.UUID) error
+	ReadTaskHistory(ctx context.Context, id uuid.UUID) ([]domain.TaskHistoryEvent, error)
 }
 
-func NewTaskUsecase(taskRepo TaskRepository) *TaskUsecase {
-	return &TaskUsecase{taskRepo: taskRepo}
+type DeliveryRepository interface {
+	GetDeliveriesByTaskID(taskID uuid.UUID) []domain.Delivery
 }
 
-func (t *TaskUsecase) CreateTask(task *domain.Task) (uuid.UUID, error) {
+// ResultStore keeps results that are too large to be stored with the task
+type ResultStore interface {
+	Put(content []byte) (domain.ResultBlob, error)
+	Open(key string) (io.ReadSeekCloser, time.Time, error)
+}
+
+// ResultValidator checks the result against the result schema of the task type
+type ResultValidator interface {
+	ValidateResult(taskType string, result json.RawMessage) error
+}
+
+type EventPublisher interface {
+	Publish(event domain.TaskEvent)
+}
+
+// EventSubscriber streams published events into a buffered channel that is closed if the reader falls behind
+type EventSubscriber interface {
+	SubscribeEvents(buffer int) (events <-chan domain.TaskEvent, cancel func())
+}
+
+// watchBuffer is how many events of all tasks a watcher may fall behind before its stream is closed
+const watchBuffer = 64
+
+// NewTaskUsecase creates task usecase, results longer than resultInlineLimit bytes are moved to the result store
+func NewTaskUsecase(taskRepo TaskRepository, deliveryRepo DeliveryRepository, resultStore ResultStore, resultInlineLimit int, resultValidator ResultValidator, publisher EventPublisher, subscriber EventSubscriber) *TaskUsecase {
+	return &TaskUsecase{
+		taskRepo:          taskRepo,
+		deliveryRepo:      deliveryRepo,
+		resultStore:       resultStore,
+		resultInlineLimit: resultInlineLimit,
+		resultValidator:   resultValidator,
+		publisher:         publisher,
+		subscriber:        subscriber,
+	}
+}
+
+func (t *TaskUsecase) CreateTask(ctx context.Context, task *domain.Task) (uuid.UUID, error) {
 	const op = "TaskUsecase.CreateTask"
 
-	if err := t.validateTask(task); err != nil {
+	if err := validateTask(task); err != nil {
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -36,12 +98,43 @@ func (t *TaskUsecase) CreateTask(task *domain.Task) (uuid.UUID, error) {
 		Status:       domain.StatusInProgress,
 		WorkDuration: 0,
 	}
+	task.Attempt = 1
 
-	id := t.taskRepo.CreateTask(task)
+	// task starts right after creation, so both events are stored and published at once
+	events := newEvents(ctx, domain.EventTaskCreated, domain.EventTaskStarted)
+	id, err := t.taskRepo.CreateTask(ctx, task, events...)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.publish(ctx, domain.EventsWithTask(events, *task))
 	return id, nil
 }
 
-func (t *TaskUsecase) validateTask(task *domain.Task) error {
+// newEvents makes the events of a change on behalf of the actor of ctx, the repository stores them with the change
+func newEvents(ctx context.Context, eventTypes ...domain.TaskEventType) []domain.TaskEvent {
+	actor := domain.ActorFromContext(ctx)
+	events := make([]domain.TaskEvent, len(eventTypes))
+	for i, eventType := range eventTypes {
+		events[i] = domain.NewTaskEvent(eventType, domain.Task{})
+		events[i].Actor = actor
+	}
+	return events
+}
+
+// publish publishes the events of a stored change right away and marks them delivered,
+// events that can't be marked stay in the outbox and the outbox relay publishes them again
+func (t *TaskUsecase) publish(ctx context.Context, events []domain.TaskEvent) {
+	ids := make([]uuid.UUID, len(events))
+	for i, event := range events {
+		t.publisher.Publish(event)
+		ids[i] = event.ID
+	}
+	// the change is stored, a client that is gone must not leave its events to the relay
+	_ = t.taskRepo.MarkEventsDelivered(context.WithoutCancel(ctx), ids)
+}
+
+func validateTask(task *domain.Task) error {
 	if task.Title == "" {
 		return domain.ErrTitleEmpty
 	}
@@ -51,35 +144,491 @@ func (t *TaskUsecase) validateTask(task *domain.Task) error {
 	if utf8.RuneCountInString(task.Description) > 1000 {
 		return fmt.Errorf("%w, maximum 1000 characters", domain.ErrDescriptionTooLong)
 	}
+	if utf8.RuneCountInString(task.Type) > 64 {
+		return fmt.Errorf("%w, maximum 64 characters", domain.ErrTypeTooLong)
+	}
+	if len(task.Labels) > 20 {
+		return fmt.Errorf("%w, maximum 20 labels", domain.ErrInvalidLabel)
+	}
+	for _, label := range task.Labels {
+		if label == "" || utf8.RuneCountInString(label) > 64 {
+			return fmt.Errorf("%w, labels must be from 1 to 64 characters", domain.ErrInvalidLabel)
+		}
+	}
+	if task.CallbackURL != "" && !isHTTPURL(task.CallbackURL) {
+		return fmt.Errorf("%w, must be an absolute http(s) url", domain.ErrInvalidCallbackURL)
+	}
 	return nil
 }
 
-func (t *TaskUsecase) GetTaskStateByID(id uuid.UUID) (domain.TaskState, time.Time, error) {
-	const op = "TaskUsecase.GetTaskStateByID"
+func (t *TaskUsecase) GetTaskByID(ctx context.Context, id uuid.UUID) (domain.Task, error) {
+	const op = "TaskUsecase.GetTaskByID"
 
-	state, createdAt, err := t.taskRepo.GetTaskStateByID(id)
+	task, err := getTask(ctx, t.taskRepo, id)
 	if err != nil {
-		return domain.TaskState{}, time.Time{}, fmt.Errorf("%s: %w", op, err)
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return task, nil
+}
+
+// WaitTaskState waits up to wait until the task reaches one of the until statuses and returns the task, current task is returned
+// when the wait expires or the task is finished with another status. Error is returned only if ctx is done first
+func (t *TaskUsecase) WaitTaskState(ctx context.Context, id uuid.UUID, until []domain.TaskStatus, wait time.Duration) (domain.Task, error) {
+	const op = "TaskUsecase.WaitTaskState"
+
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	for {
+		events, err := t.WatchTask(waitCtx, id)
+		if err != nil {
+			return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+		}
+		for event := range events {
+			if slices.Contains(until, event.Task.TaskState.Status) {
+				return event.Task, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return domain.Task{}, fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+
+		task, err := getTask(ctx, t.taskRepo, id)
+		if err != nil {
+			return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+		}
+		// otherwise the watcher fell behind the events and the task is watched again
+		if waitCtx.Err() != nil || task.TaskState.Status.IsTerminal() || slices.Contains(until, task.TaskState.Status) {
+			return task, nil
+		}
 	}
-	return state, createdAt, nil
 }
 
-func (t *TaskUsecase) GetTaskResultByID(id uuid.UUID) (string, error) {
+func (t *TaskUsecase) GetTaskResultByID(ctx context.Context, id uuid.UUID) (domain.TaskResult, error) {
 	const op = "TaskUsecase.GetTaskResultByID"
 
-	result, err := t.taskRepo.GetTaskResultByID(id)
+	task, err := getTask(ctx, t.taskRepo, id)
+	if err != nil {
+		return domain.TaskResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return domain.TaskResult{
+		Content:     task.Result,
+		ContentType: task.ResultContentType,
+		Blob:        task.ResultBlob,
+		CompletedAt: task.CompletedAt,
+		Version:     task.Version,
+	}, nil
+}
+
+// OpenTaskResult opens the result of the finished task for streaming, no matter whether it is stored with the task or in the result store
+func (t *TaskUsecase) OpenTaskResult(ctx context.Context, id uuid.UUID) (domain.ResultContent, error) {
+	const op = "TaskUsecase.OpenTaskResult"
+
+	task, err := getTask(ctx, t.taskRepo, id)
+	if err != nil {
+		return domain.ResultContent{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !task.TaskState.Status.IsTerminal() {
+		return domain.ResultContent{}, fmt.Errorf("%s: %w", op, domain.ErrTaskNotFinished)
+	}
+
+	content := domain.ResultContent{
+		ContentType: task.ResultContentType,
+		ModTime:     task.UpdatedAt,
+		Version:     task.Version,
+	}
+	if content.ContentType == "" {
+		content.ContentType = domain.DefaultResultContentType
+	}
+
+	if task.ResultBlob == nil {
+		content.ReadSeekCloser = nopCloser{bytes.NewReader(task.Result)}
+		content.Size = int64(len(task.Result))
+		return content, nil
+	}
+
+	blob, modTime, err := t.resultStore.Open(task.ResultBlob.Key)
+	if err != nil {
+		return domain.ResultContent{}, fmt.Errorf("%s: %w", op, err)
+	}
+	content.ReadSeekCloser = blob
+	content.Size = task.ResultBlob.Size
+	content.ModTime = modTime
+	return content, nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+// FinishTask moves the task to a terminal status and publishes the matching event. Result must be JSON and match the result schema
+// of the task type if there is one, results longer than the inline limit are written to the result store and only referenced by the task.
+// Unless version is domain.AnyVersion the task is finished only if it still has the version
+func (t *TaskUsecase) FinishTask(ctx context.Context, id uuid.UUID, status domain.TaskStatus, result json.RawMessage, contentType string, version int64) (domain.Task, error) {
+	const op = "TaskUsecase.FinishTask"
+
+	if !status.IsTerminal() {
+		return domain.Task{}, fmt.Errorf("%s: %w, must be %s, %s or %s", op, domain.ErrInvalidStatus, domain.StatusCompleted, domain.StatusFailed, domain.StatusCancelled)
+	}
+	if len(result) > 0 && !json.Valid(result) {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, domain.ErrInvalidResult)
+	}
+	if contentType == "" {
+		contentType = domain.DefaultResultContentType
+	}
+
+	current, err := getTask(ctx, t.taskRepo, id)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// checked up front as well, so a stale request does not leave its result in the result store
+	if !current.MatchesVersion(version) {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, domain.ErrVersionMismatch)
+	}
+	if len(result) > 0 {
+		if err := t.resultValidator.ValidateResult(current.Type, result); err != nil {
+			return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// the blob is stored first and stays if the update fails, other tasks may share it, AdminUsecase.SweepResultBlobs removes it later
+	var blob *domain.ResultBlob
+	if len(result) > t.resultInlineLimit {
+		stored, err := t.resultStore.Put(result)
+		if err != nil {
+			return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+		}
+		blob = &stored
+		result = nil
+	}
+
+	events := newEvents(ctx, domain.FinishEventType(status))
+	task, err := t.taskRepo.UpdateTask(ctx, id, func(task *domain.Task) error {
+		if task.IsDeleted() {
+			return domain.ErrTaskNotFound
+		}
+		if !task.MatchesVersion(version) {
+			return domain.ErrVersionMismatch
+		}
+		if task.TaskState.Status.IsTerminal() {
+			return domain.ErrTaskAlreadyFinished
+		}
+		now := time.Now()
+		progress := task.TaskState.Progress
+		if status == domain.StatusCompleted {
+			progress = 100
+		}
+		task.TaskState = domain.TaskState{
+			Status:       status,
+			WorkDuration: now.Sub(task.CreatedAt),
+			Progress:     progress,
+		}
+		task.CompletedAt = &now
+		task.Result = result
+		task.ResultContentType = contentType
+		task.ResultBlob = blob
+		return nil
+	}, events...)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.publish(ctx, domain.EventsWithTask(events, task))
+	return task, nil
+}
+
+// ReportProgress stores the progress percent of the running task and publishes the progress event,
+// unless version is domain.AnyVersion the progress is stored only if the task still has the version
+func (t *TaskUsecase) ReportProgress(ctx context.Context, id uuid.UUID, progress int, version int64) (domain.Task, error) {
+	const op = "TaskUsecase.ReportProgress"
+
+	if progress < 0 || progress > 100 {
+		return domain.Task{}, fmt.Errorf("%s: %w, must be from 0 to 100", op, domain.ErrInvalidProgress)
+	}
+
+	events := newEvents(ctx, domain.EventTaskProgress)
+	task, err := t.taskRepo.UpdateTask(ctx, id, func(task *domain.Task) error {
+		if task.IsDeleted() {
+			return domain.ErrTaskNotFound
+		}
+		if !task.MatchesVersion(version) {
+			return domain.ErrVersionMismatch
+		}
+		if task.TaskState.Status.IsTerminal() {
+			return domain.ErrTaskAlreadyFinished
+		}
+		task.TaskState.Progress = progress
+		task.TaskState.WorkDuration = time.Since(task.CreatedAt)
+		return nil
+	}, events...)
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.publish(ctx, domain.EventsWithTask(events, task))
+	return task, nil
+}
+
+// WatchTask returns a channel that gets the current task as a snapshot event followed by its later events.
+// The channel is closed after the terminal event or once the task is deleted, when ctx is done or when the watcher falls behind the published events
+func (t *TaskUsecase) WatchTask(ctx context.Context, id uuid.UUID) (<-chan domain.TaskEvent, error) {
+	const op = "TaskUsecase.WatchTask"
+
+	// subscribe before reading the task, so nothing published in between is lost
+	events, cancel := t.subscriber.SubscribeEvents(watchBuffer)
+
+	task, err := getTask(ctx, t.taskRepo, id)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	out := make(chan domain.TaskEvent, 1)
+	out <- domain.NewTaskEvent(domain.EventTaskSnapshot, task)
+	if task.TaskState.Status.IsTerminal() {
+		cancel()
+		close(out)
+		return out, nil
 	}
-	return result, nil
+
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		// events of versions already sent are part of the snapshot or were published again by the outbox relay, maybe out of order.
+		// A purge doesn't change the version of the task, it is never a duplicate since it ends the stream
+		last := task.Version
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if event.Task.ID != id || (event.Type != domain.EventTaskPurged && event.Task.Version <= last) {
+					continue
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+				last = event.Task.Version
+				if event.Task.TaskState.Status.IsTerminal() || event.Type == domain.EventTaskDeleted || event.Type == domain.EventTaskPurged {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
 }
 
-func (t *TaskUsecase) DeleteTask(id uuid.UUID) error {
+func (t *TaskUsecase) GetTaskDeliveries(ctx context.Context, id uuid.UUID) ([]domain.Delivery, error) {
+	const op = "TaskUsecase.GetTaskDeliveries"
+
+	// check that task exists so unknown ids are reported as not found instead of an empty list
+	if _, err := getTask(ctx, t.taskRepo, id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return t.deliveryRepo.GetDeliveriesByTaskID(id), nil
+}
+
+// DeleteTask moves the task to the trash, unless version is domain.AnyVersion only if the task still has the version.
+// The task is hidden from reads until it is restored or purged
+func (t *TaskUsecase) DeleteTask(ctx context.Context, id uuid.UUID, version int64) error {
 	const op = "TaskUsecase.DeleteTask"
 
-	err := t.taskRepo.DeleteTask(id)
+	events := newEvents(ctx, domain.EventTaskDeleted)
+	task, err := t.taskRepo.UpdateTask(ctx, id, func(task *domain.Task) error {
+		if task.IsDeleted() {
+			return domain.ErrTaskNotFound
+		}
+		if !task.MatchesVersion(version) {
+			return domain.ErrVersionMismatch
+		}
+		now := time.Now()
+		task.DeletedAt = &now
+		return nil
+	}, events...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.publish(ctx, domain.EventsWithTask(events, task))
+	return nil
+}
+
+// PurgeTask deletes the task for good whether it is in the trash or not, unless version is domain.AnyVersion only if the task still has the version
+func (t *TaskUsecase) PurgeTask(ctx context.Context, id uuid.UUID, version int64) error {
+	const op = "TaskUsecase.PurgeTask"
+
+	// read as stored, tasks in the trash are purged as well
+	task, err := t.taskRepo.GetTaskByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	events := domain.EventsWithTask(newEvents(ctx, domain.EventTaskPurged), task)
+	if err := t.taskRepo.DeleteTask(ctx, id, version, events...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.publish(ctx, events)
 	return nil
 }
+
+// ListDeletedTasks returns tasks in the trash, earliest deleted first
+func (t *TaskUsecase) ListDeletedTasks(ctx context.Context) ([]domain.Task, error) {
+	const op = "TaskUsecase.ListDeletedTasks"
+
+	tasks, err := t.taskRepo.ListDeletedTasks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tasks, nil
+}
+
+// ListTasks returns a page of tasks, a zero limit means domain.DefaultTaskPageSize and an empty sort the latest created first.
+// NextCursor of the page is set when more tasks follow it
+func (t *TaskUsecase) ListTasks(ctx context.Context, query domain.TaskListQuery) (domain.TaskPage, error) {
+	const op = "TaskUsecase.ListTasks"
+
+	if query.Sort == "" {
+		query.Sort = domain.SortCreatedDesc
+	}
+	if !query.Sort.IsValid() {
+		return domain.TaskPage{}, fmt.Errorf("%s: %w: %s", op, domain.ErrInvalidSort, query.Sort)
+	}
+	if query.Limit == 0 {
+		query.Limit = domain.DefaultTaskPageSize
+	}
+	if query.Limit < 0 || query.Limit > domain.MaxTaskPageSize {
+		return domain.TaskPage{}, fmt.Errorf("%s: %w: %d, must be between 1 and %d", op, domain.ErrInvalidPageSize, query.Limit, domain.MaxTaskPageSize)
+	}
+	if query.After != nil && query.After.Sort != query.Sort {
+		return domain.TaskPage{}, fmt.Errorf("%s: %w: cursor was made for sort %s", op, domain.ErrInvalidCursor, query.After.Sort)
+	}
+	if err := query.Filter.Validate(); err != nil {
+		return domain.TaskPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// one task more tells whether there is a next page
+	limit := query.Limit
+	query.Limit++
+	tasks, err := t.taskRepo.ListTasks(ctx, query)
+	if err != nil {
+		return domain.TaskPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	page := domain.TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.NextCursor = domain.NewTaskCursor(query.Sort, page.Tasks[limit-1]).Encode()
+	}
+	if page.Tasks == nil {
+		page.Tasks = []domain.Task{}
+	}
+	return page, nil
+}
+
+// RetryTask starts the next attempt of the failed or cancelled task and publishes the retried event, the result of the last attempt
+// is dropped. Unless version is domain.AnyVersion the task is retried only if it still has the version
+func (t *TaskUsecase) RetryTask(ctx context.Context, id uuid.UUID, version int64) (domain.Task, error) {
+	const op = "TaskUsecase.RetryTask"
+
+	events := newEvents(ctx, domain.EventTaskRetried)
+	task, err := t.taskRepo.UpdateTask(ctx, id, func(task *domain.Task) error {
+		if task.IsDeleted() {
+			return domain.ErrTaskNotFound
+		}
+		if !task.MatchesVersion(version) {
+			return domain.ErrVersionMismatch
+		}
+		if !task.TaskState.Status.IsRetryable() {
+			return domain.ErrTaskNotRetryable
+		}
+		task.TaskState = domain.TaskState{
+			Status:       domain.StatusInProgress,
+			WorkDuration: time.Since(task.CreatedAt),
+		}
+		task.Attempt++
+		task.CompletedAt = nil
+		task.Result = nil
+		task.ResultContentType = ""
+		task.ResultBlob = nil
+		return nil
+	}, events...)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.publish(ctx, domain.EventsWithTask(events, task))
+	return task, nil
+}
+
+// RestoreTask takes the task out of the trash, unless version is domain.AnyVersion only if the task still has the version
+func (t *TaskUsecase) RestoreTask(ctx context.Context, id uuid.UUID, version int64) (domain.Task, error) {
+	const op = "TaskUsecase.RestoreTask"
+
+	events := newEvents(ctx, domain.EventTaskRestored)
+	task, err := t.taskRepo.UpdateTask(ctx, id, func(task *domain.Task) error {
+		if !task.IsDeleted() {
+			return domain.ErrTaskNotInTrash
+		}
+		if !task.MatchesVersion(version) {
+			return domain.ErrVersionMismatch
+		}
+		task.DeletedAt = nil
+		return nil
+	}, events...)
+	if err != nil {
+		return domain.Task{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.publish(ctx, domain.EventsWithTask(events, task))
+	return task, nil
+}
+
+// PurgeDeletedTasks deletes for good the tasks that were moved to the trash before deletedBefore and returns how many were purged.
+// A task restored or changed while the trash is purged is kept
+func (t *TaskUsecase) PurgeDeletedTasks(ctx context.Context, deletedBefore time.Time) (int, error) {
+	const op = "TaskUsecase.PurgeDeletedTasks"
+
+	tasks, err := t.taskRepo.ListDeletedTasks(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	purged := 0
+	for _, task := range tasks {
+		if !task.DeletedAt.Before(deletedBefore) {
+			break // the trash is ordered by deletion time
+		}
+		events := domain.EventsWithTask(newEvents(ctx, domain.EventTaskPurged), task)
+		err := t.taskRepo.DeleteTask(ctx, task.ID, task.Version, events...)
+		if errors.Is(err, domain.ErrVersionMismatch) || errors.Is(err, domain.ErrTaskNotFound) {
+			continue
+		}
+		if err != nil {
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+		t.publish(ctx, events)
+		purged++
+	}
+	return purged, nil
+}
+
+// getTask reads the task, tasks in the trash are reported as not found
+func getTask(ctx context.Context, repo TaskRepository, id uuid.UUID) (domain.Task, error) {
+	task, err := repo.GetTaskByID(ctx, id)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if task.IsDeleted() {
+		return domain.Task{}, domain.ErrTaskNotFound
+	}
+	return task, nil
+}
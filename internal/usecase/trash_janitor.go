@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Util787/task-manager/pkg/logger/sl"
+)
+
+// maxTrashPurgeInterval bounds how long a task may outstay the grace period when the period is long,
+// minTrashPurgeInterval keeps short periods from purging in a busy loop
+const (
+	maxTrashPurgeInterval = time.Hour
+	minTrashPurgeInterval = time.Second
+)
+
+// TrashJanitor purges tasks that stayed in the trash for longer than the grace period
+type TrashJanitor struct {
+	log         *slog.Logger
+	tasks       *TaskUsecase
+	gracePeriod time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewTrashJanitor(log *slog.Logger, tasks *TaskUsecase, gracePeriod time.Duration) *TrashJanitor {
+	return &TrashJanitor{
+		log:         log,
+		tasks:       tasks,
+		gracePeriod: gracePeriod,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start runs the purge every tenth of the grace period, but at least once an hour and at most once a second
+func (j *TrashJanitor) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(min(max(j.gracePeriod/10, minTrashPurgeInterval), maxTrashPurgeInterval))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				j.purge()
+			}
+		}
+	}()
+}
+
+func (j *TrashJanitor) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+	j.wg.Wait()
+}
+
+func (j *TrashJanitor) purge() {
+	// a purge in progress is finished on stop, it only deletes tasks that are already hidden
+	purged, err := j.tasks.PurgeDeletedTasks(context.Background(), time.Now().Add(-j.gracePeriod))
+	if err != nil {
+		j.log.Error("failed to purge trash", slog.Int("purged", purged), sl.Err(err))
+		return
+	}
+	if purged > 0 {
+		j.log.Info("trash purged", slog.Int("purged", purged))
+	}
+}
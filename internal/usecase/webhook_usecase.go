@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/Util787/task-manager/internal/domain"
+	"github.com/google/uuid"
+)
+
+type WebhookUsecase struct {
+	subRepo      SubscriptionRepository
+	deliveryRepo WebhookDeliveryRepository
+	replayer     WebhookReplayer
+}
+
+type SubscriptionRepository interface {
+	CreateSubscription(sub *domain.WebhookSubscription) uuid.UUID
+	GetSubscriptionByID(id uuid.UUID) (domain.WebhookSubscription, error)
+	ListSubscriptions() []domain.WebhookSubscription
+	UpdateSubscription(id uuid.UUID, update func(sub *domain.WebhookSubscription) error) (domain.WebhookSubscription, error)
+	DeleteSubscription(id uuid.UUID) error
+}
+
+type WebhookDeliveryRepository interface {
+	GetWebhookDeliveryByID(id uuid.UUID) (domain.WebhookDelivery, error)
+	GetWebhookDeliveriesBySubscriptionID(subID uuid.UUID) []domain.WebhookDelivery
+}
+
+type WebhookReplayer interface {
+	Replay(delivery domain.WebhookDelivery)
+}
+
+func NewWebhookUsecase(subRepo SubscriptionRepository, deliveryRepo WebhookDeliveryRepository, replayer WebhookReplayer) *WebhookUsecase {
+	return &WebhookUsecase{subRepo: subRepo, deliveryRepo: deliveryRepo, replayer: replayer}
+}
+
+func (w *WebhookUsecase) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	const op = "WebhookUsecase.CreateSubscription"
+
+	if err := validateSubscription(sub); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sub.Active = true
+	w.subRepo.CreateSubscription(sub)
+	return *sub, nil
+}
+
+func validateSubscription(sub *domain.WebhookSubscription) error {
+	if !isHTTPURL(sub.URL) {
+		return fmt.Errorf("%w, must be an absolute http(s) url", domain.ErrInvalidWebhookURL)
+	}
+	for _, eventType := range sub.Events {
+		if !eventType.IsValid() {
+			return fmt.Errorf("%w: %s, must be one of %v", domain.ErrInvalidEventType, eventType, domain.TaskEventTypes)
+		}
+	}
+	return nil
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func (w *WebhookUsecase) GetSubscriptionByID(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	const op = "WebhookUsecase.GetSubscriptionByID"
+
+	sub, err := w.subRepo.GetSubscriptionByID(id)
+	if err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return sub, nil
+}
+
+func (w *WebhookUsecase) ListSubscriptions(ctx context.Context) []domain.WebhookSubscription {
+	return w.subRepo.ListSubscriptions()
+}
+
+// UpdateSubscription replaces url, filters and active flag of the subscription, enabling it again resets its failure counter
+func (w *WebhookUsecase) UpdateSubscription(ctx context.Context, id uuid.UUID, changes domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	const op = "WebhookUsecase.UpdateSubscription"
+
+	if err := validateSubscription(&changes); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sub, err := w.subRepo.UpdateSubscription(id, func(sub *domain.WebhookSubscription) error {
+		sub.URL = changes.URL
+		sub.Events = changes.Events
+		sub.Types = changes.Types
+		sub.Labels = changes.Labels
+		if changes.Active && !sub.Active {
+			sub.ConsecutiveFailures = 0
+			sub.DisabledReason = ""
+		}
+		sub.Active = changes.Active
+		return nil
+	})
+	if err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return sub, nil
+}
+
+func (w *WebhookUsecase) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	const op = "WebhookUsecase.DeleteSubscription"
+
+	if err := w.subRepo.DeleteSubscription(id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (w *WebhookUsecase) GetSubscriptionDeliveries(ctx context.Context, id uuid.UUID) ([]domain.WebhookDelivery, error) {
+	const op = "WebhookUsecase.GetSubscriptionDeliveries"
+
+	if _, err := w.subRepo.GetSubscriptionByID(id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return w.deliveryRepo.GetWebhookDeliveriesBySubscriptionID(id), nil
+}
+
+// ReplayDelivery sends the event of the failed delivery to the subscription again
+func (w *WebhookUsecase) ReplayDelivery(ctx context.Context, subID, deliveryID uuid.UUID) error {
+	const op = "WebhookUsecase.ReplayDelivery"
+
+	sub, err := w.subRepo.GetSubscriptionByID(subID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	delivery, err := w.deliveryRepo.GetWebhookDeliveryByID(deliveryID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if delivery.SubscriptionID != sub.ID {
+		return fmt.Errorf("%s: %w", op, domain.ErrWebhookDeliveryNotFound)
+	}
+	if delivery.Success {
+		return fmt.Errorf("%s: %w", op, domain.ErrWebhookDeliverySucceeded)
+	}
+	if !sub.Active {
+		return fmt.Errorf("%s: %w", op, domain.ErrSubscriptionDisabled)
+	}
+
+	w.replayer.Replay(delivery)
+	return nil
+}